@@ -0,0 +1,224 @@
+package history
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+)
+
+// healthFile is the file health results are stored in.
+const healthFile = "health.jsonl"
+
+// Result is the outcome of running a rule once.
+type Result struct {
+	Package string    `json:"package"`
+	Time    time.Time `json:"time"`
+	// Run identifies the run which produced the result (e.g. the updater or the
+	// reachability test, and when it was started).
+	Run      string          `json:"run,omitempty"`
+	OK       bool            `json:"ok"`
+	Latency  time.Duration   `json:"latency"` // nanoseconds
+	Category c.ErrorCategory `json:"category,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// NewResult returns a result for the outcome of a rule.
+func NewResult(run, pkg string, t time.Time, latency time.Duration, err error) Result {
+	r := Result{
+		Package: pkg,
+		Time:    t,
+		Run:     run,
+		OK:      err == nil,
+		Latency: latency,
+	}
+	if err != nil {
+		r.Category = c.Category(err)
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// RunID returns an identifier for a run.
+func RunID(source string, start time.Time) string {
+	return source + "@" + start.UTC().Format(time.RFC3339)
+}
+
+// MaxAge is how long results should be kept for.
+const MaxAge = time.Hour * 24 * 90
+
+// AppendResults appends results to the store.
+func (s *Store) AppendResults(results ...Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+	records := make([]interface{}, len(results))
+	for i, r := range results {
+		records[i] = r
+	}
+	return s.appendRecords(healthFile, records)
+}
+
+// Results returns all stored results, ordered by time.
+func (s *Store) Results() ([]Result, error) {
+	results := []Result{}
+	err := s.readRecords(healthFile, func(buf []byte) error {
+		var r Result
+		if json.Unmarshal(buf, &r) == nil && r.Package != "" {
+			results = append(results, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Time.Before(results[j].Time)
+	})
+	return results, nil
+}
+
+// PruneResults removes results older than a time.
+func (s *Store) PruneResults(before time.Time) error {
+	results, err := s.Results()
+	if err != nil {
+		return err
+	}
+	records := []interface{}{}
+	for _, r := range results {
+		if !r.Time.Before(before) {
+			records = append(records, r)
+		}
+	}
+	if len(records) == len(results) {
+		return nil
+	}
+	return s.rewriteRecords(healthFile, records)
+}
+
+// Status is the overall health of a rule.
+type Status string
+
+// Statuses, from best to worst.
+const (
+	StatusOK      Status = "ok"      // the last run succeeded, and it is not flaky
+	StatusFlaky   Status = "flaky"   // it alternated between succeeding and failing
+	StatusFailing Status = "failing" // the last run failed
+	StatusBroken  Status = "broken"  // at least BrokenStreak runs in a row failed
+)
+
+// BrokenStreak is the number of consecutive failures after which a rule is
+// considered broken rather than flaky or failing.
+const BrokenStreak = 3
+
+// Stats is a summary of the results for a rule.
+type Stats struct {
+	Package string
+	// Runs, Failures, SuccessRate, MeanLatency, Transitions and Status only
+	// consider the results in the window.
+	Runs        int
+	Failures    int
+	SuccessRate float64
+	MeanLatency time.Duration
+	// Transitions is the number of times the outcome changed between runs.
+	Transitions int
+	// Streak is the number of most recent runs which failed in a row.
+	Streak int
+	// LastSuccess and LastFailure consider all results.
+	LastSuccess  time.Time
+	LastFailure  time.Time
+	LastCategory c.ErrorCategory
+	LastError    string
+	Status       Status
+}
+
+// Flaky checks if a rule alternated between succeeding and failing (rather than
+// breaking once, or failing once).
+func (s *Stats) Flaky() bool {
+	return s.Failures > 0 && s.Failures < s.Runs && s.Transitions >= 2
+}
+
+// ComputeStats summarizes the results for each rule, only considering the last
+// window results of each for everything other than the last success and failure
+// (all of them if window is zero).
+func ComputeStats(results []Result, window int) map[string]*Stats {
+	byPkg := map[string][]Result{}
+	for _, r := range results {
+		byPkg[r.Package] = append(byPkg[r.Package], r)
+	}
+
+	stats := map[string]*Stats{}
+	for pkg, rs := range byPkg {
+		sort.SliceStable(rs, func(i, j int) bool {
+			return rs[i].Time.Before(rs[j].Time)
+		})
+
+		st := &Stats{Package: pkg}
+		for _, r := range rs {
+			if r.OK {
+				st.LastSuccess = r.Time
+			} else {
+				st.LastFailure = r.Time
+				st.LastCategory = r.Category
+				st.LastError = r.Error
+			}
+		}
+
+		if window > 0 && len(rs) > window {
+			rs = rs[len(rs)-window:]
+		}
+
+		var latency time.Duration
+		for i, r := range rs {
+			st.Runs++
+			latency += r.Latency
+			if r.OK {
+				st.Streak = 0
+			} else {
+				st.Failures++
+				st.Streak++
+			}
+			if i > 0 && r.OK != rs[i-1].OK {
+				st.Transitions++
+			}
+		}
+		st.SuccessRate = float64(st.Runs-st.Failures) / float64(st.Runs)
+		st.MeanLatency = latency / time.Duration(st.Runs)
+
+		switch {
+		case st.Streak >= BrokenStreak:
+			st.Status = StatusBroken
+		case st.Flaky():
+			st.Status = StatusFlaky
+		case st.Streak > 0:
+			st.Status = StatusFailing
+		default:
+			st.Status = StatusOK
+		}
+		stats[pkg] = st
+	}
+	return stats
+}
+
+// Worst returns the stats for rules which are not ok, ordered from the worst (the
+// lowest success rate, then the longest failure streak), or all of them if all is true.
+func Worst(stats map[string]*Stats, all bool) []*Stats {
+	worst := []*Stats{}
+	for _, st := range stats {
+		if all || st.Status != StatusOK {
+			worst = append(worst, st)
+		}
+	}
+	sort.Slice(worst, func(i, j int) bool {
+		a, b := worst[i], worst[j]
+		if a.SuccessRate != b.SuccessRate {
+			return a.SuccessRate < b.SuccessRate
+		}
+		if a.Streak != b.Streak {
+			return a.Streak > b.Streak
+		}
+		return a.Package < b.Package
+	})
+	return worst
+}
@@ -0,0 +1,121 @@
+package history
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreResults(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jiup-history")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(filepath.Join(dir, "state"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	rs, err := s.Results()
+	assert.NoError(t, err)
+	assert.Empty(t, rs, "missing file should have no results")
+
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := RunID("test", t0)
+	assert.Equal(t, "test@2020-01-01T00:00:00Z", run)
+	assert.NoError(t, s.AppendResults(
+		NewResult(run, "a", t0.Add(time.Hour), time.Second, nil),
+		NewResult(run, "b", t0, time.Second, c.Errorf(c.CategoryRegexp, "", "could not find match")),
+	))
+
+	// partially written lines should be skipped
+	f, _ := os.OpenFile(filepath.Join(s.Dir, healthFile), os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString("{\"package\":\"c\",\"ti\n")
+	f.Close()
+
+	assert.NoError(t, s.AppendResults(NewResult(run, "a", t0.Add(time.Hour*24), time.Second, errors.New("test"))))
+
+	rs, err = s.Results()
+	if assert.NoError(t, err) && assert.Len(t, rs, 3) {
+		assert.Equal(t, "b", rs[0].Package, "results should be ordered by time")
+		assert.False(t, rs[0].OK)
+		assert.Equal(t, c.CategoryRegexp, rs[0].Category)
+		assert.Equal(t, "could not find match", rs[0].Error)
+		assert.True(t, rs[1].OK)
+		assert.Equal(t, time.Second, rs[1].Latency)
+		assert.Equal(t, c.CategoryOther, rs[2].Category)
+	}
+
+	assert.NoError(t, s.PruneResults(t0.Add(time.Minute)))
+	rs, err = s.Results()
+	if assert.NoError(t, err) && assert.Len(t, rs, 2) {
+		assert.Equal(t, "a", rs[0].Package)
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	results := []Result{}
+	add := func(pkg, outcomes string) {
+		for i, o := range outcomes {
+			r := Result{Package: pkg, Time: t0.Add(time.Hour * time.Duration(i)), OK: o == '+', Latency: time.Second * time.Duration(i+1)}
+			if !r.OK {
+				r.Category = c.CategoryNetwork
+			}
+			results = append(results, r)
+		}
+	}
+	add("ok", "++++")
+	add("flaky", "++-++-+")
+	add("failing", "+++-")
+	add("broken", "++---")
+	add("recovered", "---++")
+	add("old", "-+++++")
+
+	stats := ComputeStats(results, 5)
+
+	for pkg, status := range map[string]Status{
+		"ok":        StatusOK,
+		"flaky":     StatusFlaky,
+		"failing":   StatusFailing,
+		"broken":    StatusBroken,
+		"recovered": StatusOK,
+		"old":       StatusOK,
+	} {
+		if assert.Contains(t, stats, pkg) {
+			assert.Equal(t, status, stats[pkg].Status, pkg)
+		}
+	}
+
+	st := stats["flaky"]
+	assert.Equal(t, 5, st.Runs, "should only consider the window")
+	assert.Equal(t, 2, st.Failures)
+	assert.Equal(t, 0.6, st.SuccessRate)
+	assert.Equal(t, 3, st.Transitions)
+	assert.Equal(t, t0.Add(time.Hour*6), st.LastSuccess)
+	assert.Equal(t, t0.Add(time.Hour*5), st.LastFailure)
+	assert.Equal(t, c.CategoryNetwork, st.LastCategory)
+	assert.Equal(t, time.Second*5, st.MeanLatency)
+
+	st = stats["broken"]
+	assert.Equal(t, 3, st.Streak)
+	assert.Equal(t, t0.Add(time.Hour), st.LastSuccess)
+
+	assert.Equal(t, 0, stats["old"].Failures, "should not count failures outside the window")
+	assert.False(t, stats["old"].LastFailure.IsZero(), "last failure should consider all results")
+
+	worst := []string{}
+	for _, st := range Worst(stats, false) {
+		worst = append(worst, st.Package)
+	}
+	assert.Equal(t, []string{"broken", "flaky", "failing"}, worst)
+	assert.Len(t, Worst(stats, true), 6)
+}
@@ -0,0 +1,124 @@
+// Package history stores the results of past runs in a state directory, with one
+// JSON object per line in a file for each kind of record, so records can be appended
+// cheaply and a partially written line only loses that record.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultDir returns the default state directory ($XDG_STATE_HOME/just-install-updater,
+// or ~/.local/state/just-install-updater), or an empty string if it can't be determined.
+func DefaultDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "just-install-updater")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".local", "state", "just-install-updater")
+}
+
+// Store is a directory containing the history files.
+type Store struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// Open opens a store, creating the directory if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{Dir: dir}, nil
+}
+
+// appendRecords appends records to a file.
+func (s *Store) appendRecords(name string, records []interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(s.Dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readRecords calls fn with each line of a file. Lines which can't be parsed are
+// skipped. A missing file has no records.
+func (s *Store) readRecords(name string, fn func(buf []byte) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(filepath.Join(s.Dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1024*1024)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(sc.Bytes()); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// rewriteRecords replaces the records in a file.
+func (s *Store) rewriteRecords(name string, records []interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn := filepath.Join(s.Dir, name)
+	f, err := os.Create(fn + ".tmp")
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(fn+".tmp", fn)
+}
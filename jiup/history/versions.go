@@ -0,0 +1,188 @@
+package history
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+)
+
+// versionsFile is the file observed versions are stored in.
+const versionsFile = "versions.jsonl"
+
+// Observation is a version and set of download links found for a package.
+type Observation struct {
+	Package string    `json:"package"`
+	Time    time.Time `json:"time"`
+	Run     string    `json:"run,omitempty"`
+	// Rule is where the rule which found the version is defined (see rules.R.Source).
+	Rule    string            `json:"rule,omitempty"`
+	Version string            `json:"version"`
+	URLs    map[c.Arch]string `json:"urls,omitempty"`
+}
+
+// NewObservation returns an observation for the result of a rule.
+func NewObservation(run, pkg, rule string, t time.Time, version string, dls c.Downloads) Observation {
+	o := Observation{
+		Package: pkg,
+		Time:    t,
+		Run:     run,
+		Rule:    rule,
+		Version: version,
+		URLs:    map[c.Arch]string{},
+	}
+	for arch, dl := range dls {
+		if dl != nil {
+			o.URLs[arch] = dl.URL
+		}
+	}
+	return o
+}
+
+// Same checks if two observations have the same version and links.
+func (o Observation) Same(other Observation) bool {
+	if o.Version != other.Version || len(o.URLs) != len(other.URLs) {
+		return false
+	}
+	for arch, u := range o.URLs {
+		if other.URLs[arch] != u {
+			return false
+		}
+	}
+	return true
+}
+
+// AppendObservations appends observations to the store, skipping ones which are the
+// same as the latest stored observation for the package. The store only grows when a
+// package changes, so each observation is when the version or links were first seen.
+func (s *Store) AppendObservations(observations ...Observation) error {
+	if len(observations) == 0 {
+		return nil
+	}
+	existing, err := s.Observations()
+	if err != nil {
+		return err
+	}
+	latest := Latest(existing)
+
+	sort.SliceStable(observations, func(i, j int) bool {
+		return observations[i].Time.Before(observations[j].Time)
+	})
+
+	records := []interface{}{}
+	for _, o := range observations {
+		if l, ok := latest[o.Package]; ok && l.Same(o) {
+			continue
+		}
+		latest[o.Package] = o
+		records = append(records, o)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return s.appendRecords(versionsFile, records)
+}
+
+// Observations returns all stored observations, ordered by time.
+func (s *Store) Observations() ([]Observation, error) {
+	observations := []Observation{}
+	err := s.readRecords(versionsFile, func(buf []byte) error {
+		var o Observation
+		if json.Unmarshal(buf, &o) == nil && o.Package != "" {
+			observations = append(observations, o)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(observations, func(i, j int) bool {
+		return observations[i].Time.Before(observations[j].Time)
+	})
+	return observations, nil
+}
+
+// Latest returns the latest observation for each package.
+func Latest(observations []Observation) map[string]Observation {
+	latest := map[string]Observation{}
+	for _, o := range observations {
+		if l, ok := latest[o.Package]; !ok || !o.Time.Before(l.Time) {
+			latest[o.Package] = o
+		}
+	}
+	return latest
+}
+
+// PackageHistory returns the observations for a package, ordered by time.
+func PackageHistory(observations []Observation, pkg string) []Observation {
+	res := []Observation{}
+	for _, o := range observations {
+		if o.Package == pkg {
+			res = append(res, o)
+		}
+	}
+	return res
+}
+
+// FirstSeen returns the first observation of a version of a package (i.e. when the
+// package was updated to it).
+func FirstSeen(observations []Observation, pkg, version string) (Observation, bool) {
+	for _, o := range observations {
+		if o.Package == pkg && o.Version == version {
+			return o, true
+		}
+	}
+	return Observation{}, false
+}
+
+// Between returns the observations in a time range (i.e. the versions and links which
+// were first seen in it). A zero time leaves that end of the range open.
+func Between(observations []Observation, from, to time.Time) []Observation {
+	res := []Observation{}
+	for _, o := range observations {
+		if (from.IsZero() || !o.Time.Before(from)) && (to.IsZero() || o.Time.Before(to)) {
+			res = append(res, o)
+		}
+	}
+	return res
+}
+
+// Cadence returns the number of version changes of a package, and the mean time
+// between them (zero if there were less than two).
+func Cadence(observations []Observation, pkg string) (releases int, interval time.Duration) {
+	var first, last time.Time
+	var prev string
+	for _, o := range PackageHistory(observations, pkg) {
+		if releases > 0 && o.Version == prev {
+			continue // only the links changed
+		}
+		if releases == 0 {
+			first = o.Time
+		}
+		last, prev = o.Time, o.Version
+		releases++
+	}
+	if releases < 2 {
+		return releases, 0
+	}
+	return releases, last.Sub(first) / time.Duration(releases-1)
+}
+
+// Stale returns the latest observation of the packages which have not changed since
+// before a time, ordered from the oldest.
+func Stale(observations []Observation, before time.Time) []Observation {
+	res := []Observation{}
+	for _, o := range Latest(observations) {
+		if o.Time.Before(before) {
+			res = append(res, o)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].Time.Equal(res[j].Time) {
+			return res[i].Time.Before(res[j].Time)
+		}
+		return res[i].Package < res[j].Package
+	})
+	return res
+}
@@ -0,0 +1,82 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreObservations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jiup-history")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	day := time.Hour * 24
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	dl := func(u string) *c.Download { return &c.Download{URL: u} }
+	obs := func(pkg string, d int, version string, x86 string) Observation {
+		return NewObservation("test", pkg, pkg, t0.Add(day*time.Duration(d)), version, c.Downloads{c.ArchX86: dl(x86)})
+	}
+
+	assert.NoError(t, s.AppendObservations(
+		obs("7zip", 0, "18.06", "https://example.com/7z1806.exe"),
+		obs("other", 0, "1.0", "https://example.com/other.exe"),
+	))
+	assert.NoError(t, s.AppendObservations(
+		obs("7zip", 1, "18.06", "https://example.com/7z1806.exe"),
+		obs("other", 1, "1.0", "https://example.com/other.exe"),
+	))
+	assert.NoError(t, s.AppendObservations(
+		obs("7zip", 10, "18.06", "https://mirror.example.com/7z1806.exe"),
+		obs("7zip", 30, "19.00", "https://example.com/7z1900.exe"),
+		obs("7zip", 20, "18.06", "https://mirror.example.com/7z1806.exe"),
+	))
+
+	all, err := s.Observations()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, all, 4, "unchanged observations should not be stored")
+
+	h := PackageHistory(all, "7zip")
+	if assert.Len(t, h, 3) {
+		assert.Equal(t, t0, h[0].Time)
+		assert.Equal(t, "https://mirror.example.com/7z1806.exe", h[1].URLs[c.ArchX86])
+		assert.Equal(t, "7zip", h[2].Rule)
+	}
+
+	o, ok := FirstSeen(all, "7zip", "19.00")
+	if assert.True(t, ok) {
+		assert.Equal(t, t0.Add(day*30), o.Time)
+	}
+	_, ok = FirstSeen(all, "7zip", "20.00")
+	assert.False(t, ok)
+
+	assert.Len(t, Between(all, t0.Add(day), t0.Add(day*30)), 1, "end of range should be exclusive")
+	assert.Len(t, Between(all, t0.Add(day), time.Time{}), 2)
+	assert.Len(t, Between(all, time.Time{}, time.Time{}), 4)
+
+	n, interval := Cadence(all, "7zip")
+	assert.Equal(t, 2, n, "link changes should not be counted as releases")
+	assert.Equal(t, day*30, interval)
+	n, interval = Cadence(all, "other")
+	assert.Equal(t, 1, n)
+	assert.Zero(t, interval)
+
+	stale := Stale(all, t0.Add(day*7))
+	if assert.Len(t, stale, 1) {
+		assert.Equal(t, "other", stale[0].Package)
+	}
+	assert.Equal(t, "19.00", Latest(all)["7zip"].Version)
+}
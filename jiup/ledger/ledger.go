@@ -0,0 +1,189 @@
+// Package ledger keeps track of broken rules across runs.
+//
+// Entries are either added automatically when a rule fails, or manually (by editing
+// the file) for rules which are known to be broken. Only manual entries which have
+// not expired are skipped, so automatic entries are always re-tested, and an entry is
+// removed once the rule works again.
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+)
+
+// Entry is a broken rule.
+type Entry struct {
+	Package   string          `json:"package"`
+	Category  c.ErrorCategory `json:"category,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	FirstSeen time.Time       `json:"firstSeen"`
+	LastSeen  time.Time       `json:"lastSeen"`
+	// Manual is true for entries added manually, which are skipped until they expire.
+	Manual bool `json:"manual"`
+	// Expires is when a manual entry should be re-tested (nil for never).
+	Expires *time.Time `json:"expires,omitempty"`
+	Note    string     `json:"note,omitempty"`
+}
+
+// Expired checks if a manual entry has expired.
+func (e *Entry) Expired(now time.Time) bool {
+	return e.Expires != nil && !now.Before(*e.Expires)
+}
+
+// Err returns an error describing the entry.
+func (e *Entry) Err() error {
+	msg := e.Error
+	if e.Note != "" {
+		if msg != "" {
+			msg += " "
+		}
+		msg += "(" + e.Note + ")"
+	}
+	if msg == "" {
+		msg = "manually marked as broken"
+	}
+	cat := e.Category
+	if cat == "" {
+		cat = c.CategoryOther
+	}
+	return c.NewError(cat, "", errors.New("broken: "+msg))
+}
+
+// Ledger is a set of broken rules.
+type Ledger struct {
+	entries map[string]*Entry
+}
+
+// New creates an empty ledger.
+func New() *Ledger {
+	return &Ledger{entries: map[string]*Entry{}}
+}
+
+// Load loads a ledger from a file. If the file doesn't exist, an empty ledger is returned.
+func Load(fn string) (*Ledger, error) {
+	buf, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return New(), nil
+	} else if err != nil {
+		return nil, err
+	}
+	return Parse(buf)
+}
+
+// Parse parses a ledger.
+func Parse(buf []byte) (*Ledger, error) {
+	var obj struct {
+		Entries []*Entry `json:"entries"`
+	}
+	if err := json.Unmarshal(buf, &obj); err != nil {
+		return nil, fmt.Errorf("could not parse ledger: %v", err)
+	}
+	l := New()
+	for _, e := range obj.Entries {
+		if e.Package == "" {
+			return nil, errors.New("could not parse ledger: entry without a package")
+		}
+		l.entries[e.Package] = e
+	}
+	return l, nil
+}
+
+// JSON returns the ledger as JSON, with the entries sorted by package.
+func (l *Ledger) JSON() ([]byte, error) {
+	buf, err := json.MarshalIndent(struct {
+		Entries []*Entry `json:"entries"`
+	}{l.Entries()}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '\n'), nil
+}
+
+// Save saves the ledger to a file.
+func (l *Ledger) Save(fn string) error {
+	buf, err := l.JSON()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fn, buf, 0644)
+}
+
+// Entries returns the entries sorted by package.
+func (l *Ledger) Entries() []*Entry {
+	es := []*Entry{}
+	for _, e := range l.entries {
+		es = append(es, e)
+	}
+	sort.Slice(es, func(i, j int) bool {
+		return es[i].Package < es[j].Package
+	})
+	return es
+}
+
+// Get gets the entry for a package.
+func (l *Ledger) Get(pkg string) (*Entry, bool) {
+	e, ok := l.entries[pkg]
+	return e, ok
+}
+
+// Skip returns the entry for a package if it should not be tested (i.e. it was
+// manually marked as broken and hasn't expired).
+func (l *Ledger) Skip(pkg string, now time.Time) (*Entry, bool) {
+	e, ok := l.entries[pkg]
+	if !ok || !e.Manual || e.Expired(now) {
+		return nil, false
+	}
+	return e, true
+}
+
+// Skipped returns the errors (see Entry.Err) for the packages which should not be
+// tested (see Skip).
+func (l *Ledger) Skipped(now time.Time) map[string]error {
+	skipped := map[string]error{}
+	for pkg := range l.entries {
+		if e, ok := l.Skip(pkg, now); ok {
+			skipped[pkg] = e.Err()
+		}
+	}
+	return skipped
+}
+
+// Record records the result of testing a package. If it failed, the entry is added
+// or updated (keeping the manual flag, expiry and note). If it succeeded, the entry
+// is removed.
+func (l *Ledger) Record(pkg string, err error, now time.Time) {
+	if err == nil {
+		delete(l.entries, pkg)
+		return
+	}
+	e, ok := l.entries[pkg]
+	if !ok {
+		e = &Entry{Package: pkg, FirstSeen: now}
+		l.entries[pkg] = e
+	}
+	e.Category = c.Category(err)
+	e.Error = err.Error()
+	e.LastSeen = now
+}
+
+// Mark manually marks a package as broken until it expires (or forever if zero).
+func (l *Ledger) Mark(pkg, note string, expires time.Time, now time.Time) {
+	e, ok := l.entries[pkg]
+	if !ok {
+		e = &Entry{Package: pkg, FirstSeen: now, LastSeen: now}
+		l.entries[pkg] = e
+	}
+	e.Manual = true
+	e.Note = note
+	e.Expires = nil
+	if !expires.IsZero() {
+		e.Expires = &expires
+	}
+}
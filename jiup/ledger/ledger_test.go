@@ -0,0 +1,79 @@
+package ledger
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedger(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1, t2 := t0.Add(time.Hour*24), t0.Add(time.Hour*24*30)
+
+	l := New()
+	l.Record("auto", c.Errorf(c.CategoryRegexp, "", "could not find match"), t0)
+	l.Record("auto", c.Errorf(c.CategoryRegexp, "", "could not find match"), t1)
+	l.Mark("manual", "upstream is down", time.Time{}, t0)
+	l.Mark("expiring", "", t1, t0)
+	l.Record("expiring", errors.New("test"), t0)
+
+	if e, ok := l.Get("auto"); assert.True(t, ok) {
+		assert.Equal(t, c.CategoryRegexp, e.Category)
+		assert.Equal(t, t0, e.FirstSeen)
+		assert.Equal(t, t1, e.LastSeen)
+		assert.False(t, e.Manual)
+	}
+	if e, ok := l.Get("expiring"); assert.True(t, ok) {
+		assert.True(t, e.Manual, "should keep the manual flag when recording an error")
+		assert.Equal(t, c.CategoryOther, e.Category)
+	}
+
+	_, ok := l.Skip("auto", t0)
+	assert.False(t, ok, "should not skip automatic entries")
+	_, ok = l.Skip("manual", t2)
+	assert.True(t, ok, "should skip manual entries without an expiry")
+	_, ok = l.Skip("expiring", t0)
+	assert.True(t, ok, "should skip manual entries which haven't expired")
+	_, ok = l.Skip("expiring", t1)
+	assert.False(t, ok, "should not skip expired entries")
+	_, ok = l.Skip("none", t0)
+	assert.False(t, ok)
+
+	skipped := l.Skipped(t0)
+	assert.Len(t, skipped, 2)
+	assert.EqualError(t, skipped["manual"], "broken: (upstream is down)")
+	assert.EqualError(t, skipped["expiring"], "broken: test")
+
+	dir, err := ioutil.TempDir("", "jiup-ledger")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "broken.json")
+
+	if !assert.NoError(t, l.Save(fn)) {
+		return
+	}
+	l2, err := Load(fn)
+	if assert.NoError(t, err) {
+		assert.Equal(t, l.Entries(), l2.Entries())
+	}
+
+	l2.Record("expiring", nil, t1)
+	_, ok = l2.Get("expiring")
+	assert.False(t, ok, "should remove entries which work again")
+
+	l3, err := Load(filepath.Join(dir, "nonexistent.json"))
+	if assert.NoError(t, err) {
+		assert.Empty(t, l3.Entries())
+	}
+
+	_, err = Parse([]byte(`{"entries": [{"error": "test"}]}`))
+	assert.Error(t, err)
+}
@@ -19,15 +19,17 @@ type Package struct {
 		Interactive *bool         `json:"interactive,omitempty"` // optional: default to false
 		Kind        InstallerKind `json:"kind"`
 		// Options will either have: (base options set) or (x86 set)
-		// or (x86 and x86_64 set). Check the x86 for nil to
+		// or (x86 and x86_64 and/or arm64 set). Check the x86 for nil to
 		// determine which one.
 		Options *struct {
 			*Options `json:",omitempty"` // maybe optional
 			X86      *Options            `json:"x86,omitempty"`    // maybe optional
 			X86_64   *Options            `json:"x86_64,omitempty"` // optional
+			ARM64    *Options            `json:"arm64,omitempty"`  // optional
 		} `json:"options,omitempty"` // optional
-		X86    *string `json:"x86,omitempty"` // optional, but at least either x86 or x86_64 must be defined
+		X86    *string `json:"x86,omitempty"` // optional, but at least one of x86, x86_64 or arm64 must be defined
 		X86_64 *string `json:"x86_64,omitempty"`
+		ARM64  *string `json:"arm64,omitempty"`
 	} `json:"installer"`
 	Version string `json:"version"`
 }
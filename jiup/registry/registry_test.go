@@ -34,3 +34,42 @@ func TestRegistry(t *testing.T) {
 
 	assert.Equal(t, string(outA), string(outB), "normalized JSON should be the same")
 }
+
+func TestRegistryARM64(t *testing.T) {
+	buf := []byte(`{
+		"$schema": "./just-install-schema.json",
+		"version": 4,
+		"packages": {
+			"test": {
+				"installer": {
+					"kind": "zip",
+					"options": {
+						"x86": {"destination": "{{.PROGRAMFILES_X86}}\\test"},
+						"arm64": {"destination": "{{.PROGRAMFILES}}\\test", "shims": ["{{.PROGRAMFILES}}\\test\\test.exe"]}
+					},
+					"x86": "https://example.com/test-1.0-x86.zip",
+					"arm64": "https://example.com/test-1.0-arm64.zip"
+				},
+				"version": "1.0"
+			}
+		}
+	}`)
+
+	r, err := NewFromJSON(buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+	pkg := r.Packages["test"]
+	if assert.NotNil(t, pkg.Installer.ARM64) {
+		assert.Equal(t, "https://example.com/test-1.0-arm64.zip", *pkg.Installer.ARM64)
+	}
+	assert.Nil(t, pkg.Installer.X86_64)
+	if assert.NotNil(t, pkg.Installer.Options) && assert.NotNil(t, pkg.Installer.Options.ARM64) && assert.NotNil(t, pkg.Installer.Options.ARM64.Shims) {
+		assert.Equal(t, []string{"{{.PROGRAMFILES}}\\test\\test.exe"}, *pkg.Installer.Options.ARM64.Shims)
+	}
+
+	bufn, err := r.GetJSON()
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, string(buf), string(bufn), "arm64 fields should round-trip")
+	}
+}
@@ -1,77 +1,216 @@
 package rules
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/just-install/just-install-updater-go/jiup/rules/lint"
 )
 
 // R represents a rule for an application.
 type R struct {
-	V c.VersionExtractorFunc
-	D c.DownloadExtractorFunc
+	V c.VersionInfoExtractorFunc
+	D c.DownloadsExtractorFunc
+	// VDesc and DDesc describe the extractors (see c.Descriptor).
+	VDesc *c.Descriptor
+	DDesc *c.Descriptor
+	// Source is where the rule is defined (SourceBuiltin, or the file a rule was
+	// loaded from).
+	Source string
 }
 
-var rules = map[string]R{}
+// SourceBuiltin is the source of the rules registered in Go code (see Register).
+const SourceBuiltin = "builtin"
 
-// Rule registers a rule.
-func Rule(pkg string, versionExtractor c.VersionExtractorFunc, downloadExtractor c.DownloadExtractorFunc) {
-	if _, ok := rules[pkg]; ok {
-		panic("rule for " + pkg + " already registered")
+// RuleSet is a set of rules, along with the packages whose version check is disabled
+// (see NoVersionCheck). The zero value is not usable; use NewRuleSet.
+type RuleSet struct {
+	rules          map[string]R
+	noVersionCheck map[string]bool
+}
+
+// NewRuleSet returns an empty rule set.
+func NewRuleSet() *RuleSet {
+	return &RuleSet{
+		rules:          map[string]R{},
+		noVersionCheck: map[string]bool{},
+	}
+}
+
+// Default is the rule set containing the built-in rules (see rules.go), which the
+// package-level functions operate on.
+var Default = NewRuleSet()
+
+// Register adds a rule defined in Go code to the set (see Rule). It returns an error if
+// there is already a rule for the package, or if the rule has any lint errors (see
+// lint.Rule).
+func (s *RuleSet) Register(pkg string, versionExtractor c.VersionExtractor, downloadExtractor c.DownloadExtractor) error {
+	if _, ok := s.rules[pkg]; ok {
+		return fmt.Errorf("rule for %s already registered", pkg)
+	}
+	r := newR(pkg, SourceBuiltin, versionExtractor, downloadExtractor)
+	if errs := lint.Errors(lint.Rule(pkg, r.VDesc, r.DDesc)); len(errs) != 0 {
+		return fmt.Errorf("rule for %s is invalid: %v", pkg, errs)
+	}
+	s.rules[pkg] = r
+	return nil
+}
+
+// Override adds a rule to the set, replacing any existing rule for the package (e.g.
+// with a rule loaded from a file, which is the source). NoVersionCheck is reset for the
+// package. The rule is not linted.
+func (s *RuleSet) Override(pkg, source string, versionExtractor c.VersionExtractor, downloadExtractor c.DownloadExtractor) {
+	s.rules[pkg] = newR(pkg, source, versionExtractor, downloadExtractor)
+	delete(s.noVersionCheck, pkg)
+}
+
+// Get gets the rule for a package if it exists.
+func (s *RuleSet) Get(pkg string) (R, bool) {
+	r, ok := s.rules[pkg]
+	return r, ok
+}
+
+// List returns the sorted packages which have a rule.
+func (s *RuleSet) List() []string {
+	pkgs := make([]string, 0, len(s.rules))
+	for pkg := range s.rules {
+		pkgs = append(pkgs, pkg)
 	}
-	rules[pkg] = R{wrapV(versionExtractor), wrapD(downloadExtractor)}
+	sort.Strings(pkgs)
+	return pkgs
 }
 
-// GetRule gets a rule if it exists.
-func GetRule(pkg string) (c.VersionExtractorFunc, c.DownloadExtractorFunc, bool) {
-	if rule, ok := rules[pkg]; ok {
+// Merge adds the rules from another set, replacing the existing rules for the same
+// packages along with whether their version check is disabled.
+func (s *RuleSet) Merge(o *RuleSet) {
+	for pkg, r := range o.rules {
+		s.rules[pkg] = r
+		if o.noVersionCheck[pkg] {
+			s.noVersionCheck[pkg] = true
+		} else {
+			delete(s.noVersionCheck, pkg)
+		}
+	}
+}
+
+// Subset returns a new set with the rules for the specified packages. Packages without
+// a rule are ignored.
+func (s *RuleSet) Subset(pkgs ...string) *RuleSet {
+	n := NewRuleSet()
+	for _, pkg := range pkgs {
+		if r, ok := s.rules[pkg]; ok {
+			n.rules[pkg] = r
+			if s.noVersionCheck[pkg] {
+				n.noVersionCheck[pkg] = true
+			}
+		}
+	}
+	return n
+}
+
+// NoVersionCheck disables checking that the download links contain the version (see
+// CheckVersion) for packages whose links never do (e.g. CI artifacts).
+func (s *RuleSet) NoVersionCheck(pkgs ...string) {
+	for _, pkg := range pkgs {
+		s.noVersionCheck[pkg] = true
+	}
+}
+
+// CheckVersion checks that the download links for a package contain the version (see
+// c.Downloads.CheckVersion) unless it was disabled with NoVersionCheck. It returns a
+// *c.VersionMismatchError if they don't.
+func (s *RuleSet) CheckVersion(pkg string, version c.VersionInfo, dls c.Downloads) error {
+	if s.noVersionCheck[pkg] {
+		return nil
+	}
+	return dls.CheckVersion(version.Version)
+}
+
+// Rule registers a rule in the default set. The version extractor can be either a
+// c.VersionExtractorFunc or a c.VersionInfoExtractorFunc, and the download extractor
+// can be either a c.DownloadExtractorFunc or a c.DownloadsExtractorFunc. It panics if
+// the rule can't be registered (see RuleSet.Register).
+func Rule(pkg string, versionExtractor c.VersionExtractor, downloadExtractor c.DownloadExtractor) {
+	if err := Default.Register(pkg, versionExtractor, downloadExtractor); err != nil {
+		panic(err)
+	}
+}
+
+func newR(pkg, source string, versionExtractor c.VersionExtractor, downloadExtractor c.DownloadExtractor) R {
+	return R{
+		V:      wrapV(pkg, versionExtractor.Structured()),
+		D:      wrapD(pkg, downloadExtractor.Structured()),
+		VDesc:  c.DescribeVersion(versionExtractor),
+		DDesc:  c.DescribeDownloads(downloadExtractor),
+		Source: source,
+	}
+}
+
+// GetRule gets a rule from the default set if it exists.
+func GetRule(pkg string) (c.VersionInfoExtractorFunc, c.DownloadsExtractorFunc, bool) {
+	if rule, ok := Default.Get(pkg); ok {
 		return rule.V, rule.D, true
 	}
 	return nil, nil, false
 }
 
-// GetRules gets all rules.
+// NoVersionCheck disables the version check for packages in the default set (see
+// RuleSet.NoVersionCheck).
+func NoVersionCheck(pkgs ...string) {
+	Default.NoVersionCheck(pkgs...)
+}
+
+// CheckVersion checks the download links for a package in the default set (see
+// RuleSet.CheckVersion).
+func CheckVersion(pkg string, version c.VersionInfo, dls c.Downloads) error {
+	return Default.CheckVersion(pkg, version, dls)
+}
+
+// GetRules gets all rules in the default set. The map should not be modified.
 func GetRules() map[string]R {
-	return rules
+	return Default.rules
 }
 
-func wrapV(f c.VersionExtractorFunc) c.VersionExtractorFunc {
-	return func() (version string, err error) {
-		version, err = f()
+// wrapV validates the result of a version extractor, and sets the rule of errors
+// (see c.WithRule).
+func wrapV(pkg string, f c.VersionInfoExtractorFunc) c.VersionInfoExtractorFunc {
+	return func(ctx context.Context) (version c.VersionInfo, err error) {
+		version, err = f(ctx)
 		if err != nil {
-			return "", err
+			return c.VersionInfo{}, c.WithRule(pkg, err)
 		}
-		if strings.TrimSpace(version) == "" {
-			return "", errors.New("version is empty")
+		if strings.TrimSpace(version.Version) == "" {
+			return c.VersionInfo{}, c.WithRule(pkg, c.Errorf(c.CategoryValidation, "", "version is empty"))
 		}
 		return version, nil
 	}
 }
 
-func wrapD(f c.DownloadExtractorFunc) c.DownloadExtractorFunc {
-	return func(version string) (x86 *string, x86_64 *string, err error) {
-		x86, x86_64, err = f(version)
-		if x86 != nil {
-			if err != nil {
-				return nil, nil, err
-			}
-			if strings.TrimSpace(*x86) == "" {
-				return nil, nil, errors.New("x86 link is empty")
+// wrapD validates the result of a download extractor, and sets the rule of errors
+// (see c.WithRule).
+func wrapD(pkg string, f c.DownloadsExtractorFunc) c.DownloadsExtractorFunc {
+	return func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+		dls, err := f(ctx, version)
+		if err != nil {
+			return nil, c.WithRule(pkg, err)
+		}
+		for arch, dl := range dls {
+			if !arch.Valid() {
+				return nil, c.WithRule(pkg, c.Errorf(c.CategoryValidation, "", "unknown architecture %q (expected one of %s)", arch, c.Archs).WithArch(arch))
 			}
-			if !strings.HasPrefix(*x86, "http") {
-				return nil, nil, fmt.Errorf("x86 link (%s) does not start with http", *x86)
+			if dl == nil {
+				return nil, c.WithRule(pkg, c.Errorf(c.CategoryValidation, "", "%s download is nil (remove the architecture instead)", arch).WithArch(arch))
 			}
-		}
-		if x86_64 != nil {
-			if strings.TrimSpace(*x86_64) == "" {
-				return nil, nil, errors.New("x86_64 link is empty")
+			if strings.TrimSpace(dl.URL) == "" {
+				return nil, c.WithRule(pkg, c.Errorf(c.CategoryValidation, "", "%s link is empty", arch).WithArch(arch))
 			}
-			if !strings.HasPrefix(*x86_64, "http") {
-				return nil, nil, fmt.Errorf("x86_64 link (%s) does not start with http", *x86_64)
+			if !strings.HasPrefix(dl.URL, "http") {
+				return nil, c.WithRule(pkg, c.Errorf(c.CategoryValidation, dl.URL, "%s link does not start with http", arch).WithArch(arch))
 			}
 		}
-		return x86, x86_64, nil
+		return dls, nil
 	}
 }
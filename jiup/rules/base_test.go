@@ -0,0 +1,88 @@
+package rules
+
+import (
+	"context"
+	"testing"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	d "github.com/just-install/just-install-updater-go/jiup/rules/download"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+	v "github.com/just-install/just-install-updater-go/jiup/rules/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func fixedVersion(version string) c.VersionInfoExtractorFunc {
+	return func(context.Context) (c.VersionInfo, error) {
+		return c.VersionInfo{Version: version}, nil
+	}
+}
+
+func TestRuleSet(t *testing.T) {
+	s := NewRuleSet()
+	assert.NoError(t, s.Register("b", fixedVersion("1.0"), d.Template("https://example.com/b-{{.Version}}.exe", "")))
+	assert.NoError(t, s.Register("a", fixedVersion("2.0"), d.Template("https://example.com/a-{{.Version}}.exe", "")))
+	assert.EqualError(t, s.Register("a", fixedVersion("2.0"), d.Template("https://example.com/a-{{.Version}}.exe", "")), "rule for a already registered")
+	assert.EqualError(t, s.Register("c", v.Regexp("https://example.com", h.Re("[0-9.]+")), d.Template("https://example.com/{{.Version}}", "")), `rule for c is invalid: [c: version > v.Regexp: error: regexp "[0-9.]+" has no capture group]`)
+	assert.Equal(t, []string{"a", "b"}, s.List())
+
+	r, ok := s.Get("b")
+	if assert.True(t, ok) {
+		assert.Equal(t, "d.Template", r.DDesc.Kind)
+		assert.Equal(t, SourceBuiltin, r.Source)
+		vi, err := r.V(context.Background())
+		assert.NoError(t, err)
+		dls, err := r.D(context.Background(), vi)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "https://example.com/b-1.0.exe", dls[c.ArchX86].URL)
+		}
+	}
+	_, ok = s.Get("c")
+	assert.False(t, ok, "invalid rule should not be registered")
+
+	dls := c.Downloads{c.ArchX86: {URL: "https://example.com/a.exe"}}
+	assert.Error(t, s.CheckVersion("a", c.VersionInfo{Version: "2.0"}, dls))
+	s.NoVersionCheck("a")
+	assert.NoError(t, s.CheckVersion("a", c.VersionInfo{Version: "2.0"}, dls))
+
+	sub := s.Subset("a", "x")
+	assert.Equal(t, []string{"a"}, sub.List())
+	assert.NoError(t, sub.CheckVersion("a", c.VersionInfo{Version: "2.0"}, dls), "subset should keep the version check setting")
+
+	o := NewRuleSet()
+	o.Override("a", "a.json", fixedVersion("3.0"), d.Template("https://example.com/a-{{.Version}}.exe", ""))
+	o.Override("d", "d.json", fixedVersion("1.0"), d.Template("https://example.com/d-{{.Version}}.exe", ""))
+	s.Merge(o)
+	assert.Equal(t, []string{"a", "b", "d"}, s.List())
+	if r, ok := s.Get("a"); assert.True(t, ok) {
+		vi, err := r.V(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "3.0", vi.Version, "merged rule should replace the existing one")
+		assert.Equal(t, "a.json", r.Source)
+	}
+	assert.Error(t, s.CheckVersion("a", c.VersionInfo{Version: "2.0"}, dls), "merged rule should reset the version check setting")
+	assert.Equal(t, []string{"a"}, sub.List(), "subset should not be changed by merging into the original")
+}
+
+func TestRuleSetInvalidDownloads(t *testing.T) {
+	for _, tc := range []struct {
+		dls c.Downloads
+		err string
+	}{
+		{c.Downloads{"amd64": {URL: "https://example.com/a.exe"}}, `unknown architecture "amd64" (expected one of [x86 x86_64 arm64])`},
+		{c.Downloads{c.ArchX86: nil}, "x86 download is nil (remove the architecture instead)"},
+		{c.Downloads{c.ArchX86: {URL: " "}}, "x86 link is empty"},
+		{c.Downloads{c.ArchX86_64: {URL: "ftp://example.com/a.exe"}}, "x86_64 link does not start with http (ftp://example.com/a.exe)"},
+	} {
+		dls := tc.dls
+		s := NewRuleSet()
+		assert.NoError(t, s.Register("a", fixedVersion("1.0"), c.DownloadsExtractorFunc(func(context.Context, c.VersionInfo) (c.Downloads, error) {
+			return dls, nil
+		})))
+		r, _ := s.Get("a")
+		_, err := r.D(context.Background(), c.VersionInfo{Version: "1.0"})
+		if assert.EqualError(t, err, tc.err) {
+			assert.Equal(t, c.CategoryValidation, c.Category(err), tc.err)
+			assert.Equal(t, "a", err.(*c.ExtractError).Rule, tc.err)
+		}
+	}
+}
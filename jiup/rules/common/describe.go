@@ -0,0 +1,239 @@
+package c
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Descriptor describes an extractor (e.g. for linting or finding the rules which use a
+// site). It is created by the constructor of the extractor along with it (see
+// DescribedVersion and DescribedDownloads).
+type Descriptor struct {
+	// Kind is the constructor of the extractor (e.g. v.Regexp), or custom if the
+	// extractor doesn't describe itself.
+	Kind string `json:"kind"`
+	// Role is the parameter of the parent extractor this is for (e.g. of, all or x86).
+	Role string `json:"role,omitempty"`
+	// Params contains the non-empty parameters (e.g. url, regexp or x86Selector).
+	Params   map[string]string `json:"params,omitempty"`
+	Children []*Descriptor     `json:"children,omitempty"`
+}
+
+// KindCustom is the kind of extractors which don't describe themselves.
+const KindCustom = "custom"
+
+// Described is implemented by extractors which describe themselves.
+type Described interface {
+	Descriptor() *Descriptor
+}
+
+// DescribedVersion is a version extractor along with its descriptor, which is what the
+// version extractor constructors return.
+type DescribedVersion struct {
+	Desc *Descriptor
+	F    VersionInfoExtractorFunc
+}
+
+// Structured returns the extractor.
+func (v DescribedVersion) Structured() VersionInfoExtractorFunc {
+	return v.F
+}
+
+// Descriptor returns the descriptor.
+func (v DescribedVersion) Descriptor() *Descriptor {
+	return v.Desc
+}
+
+// DescribedDownloads is a download extractor along with its descriptor, which is what
+// the download extractor constructors return.
+type DescribedDownloads struct {
+	Desc *Descriptor
+	F    DownloadsExtractorFunc
+}
+
+// Structured returns the extractor.
+func (d DescribedDownloads) Structured() DownloadsExtractorFunc {
+	return d.F
+}
+
+// Descriptor returns the descriptor.
+func (d DescribedDownloads) Descriptor() *Descriptor {
+	return d.Desc
+}
+
+// DescribeVersion returns the descriptor for a version extractor, which is custom if
+// it doesn't describe itself.
+func DescribeVersion(f VersionExtractor) *Descriptor {
+	return describe(f)
+}
+
+// DescribeDownloads returns the descriptor for a download extractor, which is custom if
+// it doesn't describe itself.
+func DescribeDownloads(f DownloadExtractor) *Descriptor {
+	return describe(f)
+}
+
+func describe(f interface{}) *Descriptor {
+	if d, ok := f.(Described); ok && d.Descriptor() != nil {
+		c := *d.Descriptor() // the role depends on the parent
+		return &c
+	}
+	return &Descriptor{Kind: KindCustom}
+}
+
+// NewDescriptor returns the descriptor for an extractor. The params are pairs of names
+// and values, where the values can be strings, regexps, ints, bools, durations, string
+// slices, or extractors (or slices of them) and other described values (e.g. version
+// transforms) which are described as children. Empty values and nil extractors are
+// left out.
+func NewDescriptor(kind string, params ...interface{}) *Descriptor {
+	d := &Descriptor{Kind: kind, Params: map[string]string{}}
+	child := func(role string, c *Descriptor) {
+		c.Role = role
+		d.Children = append(d.Children, c)
+	}
+	for i := 0; i+1 < len(params); i += 2 {
+		name := params[i].(string)
+		switch val := params[i+1].(type) {
+		case string:
+			if val != "" {
+				d.Params[name] = val
+			}
+		case *regexp.Regexp:
+			if val != nil {
+				d.Params[name] = val.String()
+			}
+		case int:
+			d.Params[name] = strconv.Itoa(val)
+		case bool:
+			if val {
+				d.Params[name] = "true"
+			}
+		case time.Duration:
+			if val != 0 {
+				d.Params[name] = val.String()
+			}
+		case []string:
+			if len(val) != 0 {
+				d.Params[name] = strings.Join(val, ",")
+			}
+		case VersionExtractor:
+			if !isNil(val) {
+				child(name, DescribeVersion(val))
+			}
+		case DownloadExtractor:
+			if !isNil(val) {
+				child(name, DescribeDownloads(val))
+			}
+		case []VersionExtractor:
+			for _, f := range val {
+				if !isNil(f) {
+					child(name, DescribeVersion(f))
+				}
+			}
+		case []DownloadExtractor:
+			for _, f := range val {
+				if !isNil(f) {
+					child(name, DescribeDownloads(f))
+				}
+			}
+		case []Described:
+			for _, x := range val {
+				child(name, describe(x))
+			}
+		case nil:
+		default:
+			panic(fmt.Sprintf("unsupported descriptor param %s of type %T", name, val))
+		}
+	}
+	if len(d.Params) == 0 {
+		d.Params = nil
+	}
+	return d
+}
+
+// isNil checks if an extractor is a nil function.
+func isNil(f interface{}) bool {
+	switch f := f.(type) {
+	case VersionInfoExtractorFunc:
+		return f == nil
+	case VersionExtractorFunc:
+		return f == nil
+	case DownloadsExtractorFunc:
+		return f == nil
+	case DownloadExtractorFunc:
+		return f == nil
+	case DescribedVersion:
+		return f.F == nil
+	case DescribedDownloads:
+		return f.F == nil
+	}
+	return f == nil
+}
+
+// Walk calls fn for the descriptor and all of its children, depth-first.
+func (d *Descriptor) Walk(fn func(d *Descriptor)) {
+	fn(d)
+	for _, c := range d.Children {
+		c.Walk(fn)
+	}
+}
+
+// URLs returns the urls the extractor and its children request or link to (i.e. the
+// url and template params). They may be templates.
+func (d *Descriptor) URLs() []string {
+	urls := []string{}
+	seen := map[string]bool{}
+	d.Walk(func(d *Descriptor) {
+		names := []string{}
+		for name := range d.Params {
+			if name == "url" || strings.HasSuffix(name, "Template") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if u := d.Params[name]; !seen[u] {
+				seen[u] = true
+				urls = append(urls, u)
+			}
+		}
+	})
+	return urls
+}
+
+// Hosts returns the sorted hosts of URLs.
+func (d *Descriptor) Hosts() []string {
+	hosts := []string{}
+	seen := map[string]bool{}
+	for _, s := range d.URLs() {
+		if u, err := url.Parse(s); err == nil && u.Host != "" && !seen[u.Host] {
+			seen[u.Host] = true
+			hosts = append(hosts, u.Host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// String formats the descriptor on one line (e.g. w.Transform(of: v.Regexp(url=...))).
+func (d *Descriptor) String() string {
+	parts := []string{}
+	names := []string{}
+	for name := range d.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, name+"="+strconv.Quote(d.Params[name]))
+	}
+	for _, c := range d.Children {
+		parts = append(parts, c.Role+": "+c.String())
+	}
+	return d.Kind + "(" + strings.Join(parts, ", ") + ")"
+}
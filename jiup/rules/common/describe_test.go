@@ -0,0 +1,64 @@
+package c
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func describedVersion(url string) DescribedVersion {
+	return DescribedVersion{
+		Desc: NewDescriptor("v.Test", "url", url, "regexp", regexp.MustCompile("([0-9.]+)"), "format", ""),
+		F: func(context.Context) (VersionInfo, error) {
+			return VersionInfo{Version: "1.0"}, nil
+		},
+	}
+}
+
+func describedDownloads(tmpl string, fs ...DownloadExtractor) DescribedDownloads {
+	return DescribedDownloads{
+		Desc: NewDescriptor("d.Test", "x86Template", tmpl, "n", 2, "insecure", false, "timeout", time.Second, "all", fs),
+		F: func(context.Context, VersionInfo) (Downloads, error) {
+			return NewDownloads(&tmpl, nil, nil), nil
+		},
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	vd := DescribeVersion(describedVersion("https://example.com/download"))
+	assert.Equal(t, &Descriptor{Kind: "v.Test", Params: map[string]string{"url": "https://example.com/download", "regexp": "([0-9.]+)"}}, vd)
+
+	dd := DescribeDownloads(describedDownloads("https://dl.example.org/{{.Version}}.exe", describedDownloads("https://example.net/a.exe"), DownloadsExtractorFunc(nil), DescribedDownloads{}))
+	assert.Equal(t, "d.Test", dd.Kind)
+	assert.Equal(t, map[string]string{"x86Template": "https://dl.example.org/{{.Version}}.exe", "n": "2", "timeout": "1s"}, dd.Params)
+	if assert.Len(t, dd.Children, 1, "nil extractors should be left out") {
+		assert.Equal(t, "all", dd.Children[0].Role)
+		assert.Equal(t, "https://example.net/a.exe", dd.Children[0].Params["x86Template"])
+	}
+	assert.Equal(t, []string{"https://dl.example.org/{{.Version}}.exe", "https://example.net/a.exe"}, dd.URLs())
+	assert.Equal(t, []string{"dl.example.org", "example.net"}, dd.Hosts())
+	assert.Equal(t, `d.Test(n="2", timeout="1s", x86Template="https://dl.example.org/{{.Version}}.exe", all: d.Test(n="2", timeout="1s", x86Template="https://example.net/a.exe"))`, dd.String())
+
+	child := describedDownloads("https://example.net/a.exe")
+	NewDescriptor("d.Test", "x86", child)
+	assert.Empty(t, DescribeDownloads(child).Role, "describing a child should not change the descriptor of the extractor")
+}
+
+func TestDescribeCustom(t *testing.T) {
+	assert.Equal(t, &Descriptor{Kind: KindCustom}, DescribeVersion(VersionExtractorFunc(func() (string, error) {
+		return "1.0", nil
+	})), "old-style extractors can't describe themselves")
+
+	assert.Equal(t, &Descriptor{Kind: KindCustom}, DescribeVersion(VersionInfoExtractorFunc(func(ctx context.Context) (VersionInfo, error) {
+		version, err := describedVersion("https://example.com").Structured()(ctx)
+		if err != nil {
+			return VersionInfo{}, err
+		}
+		return VersionInfo{Version: version.Version + ".0"}, nil
+	})), "custom extractors should not be described as the ones they call")
+
+	assert.Equal(t, &Descriptor{Kind: KindCustom}, DescribeDownloads(DescribedDownloads{F: describedDownloads("https://example.com").F}), "extractors without a descriptor are custom")
+}
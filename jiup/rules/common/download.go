@@ -0,0 +1,171 @@
+package c
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Arch represents an architecture.
+type Arch string
+
+// Architectures.
+const (
+	ArchX86    Arch = "x86"
+	ArchX86_64 Arch = "x86_64"
+	ArchARM64  Arch = "arm64"
+)
+
+// Archs contains all architectures in the order they should be shown.
+var Archs = []Arch{ArchX86, ArchX86_64, ArchARM64}
+
+// Valid checks if the architecture is one of Archs.
+func (a Arch) Valid() bool {
+	for _, arch := range Archs {
+		if a == arch {
+			return true
+		}
+	}
+	return false
+}
+
+// Download represents a download link and optional metadata about it.
+type Download struct {
+	URL         string
+	FileName    string    // optional
+	Size        int64     // optional: 0 if unknown
+	Checksum    string    // optional: algorithm:hex (e.g. sha256:abcd...)
+	ContentType string    // optional
+	ReleaseDate time.Time // optional: zero if unknown
+}
+
+// Downloads contains the download for each available architecture.
+type Downloads map[Arch]*Download
+
+// NewDownloads creates Downloads from the links for each architecture (which can be nil).
+func NewDownloads(x86, x86_64, arm64 *string) Downloads {
+	d := Downloads{}
+	for arch, link := range map[Arch]*string{
+		ArchX86:    x86,
+		ArchX86_64: x86_64,
+		ArchARM64:  arm64,
+	} {
+		if link != nil {
+			d[arch] = &Download{URL: *link}
+		}
+	}
+	return d
+}
+
+// URL returns the link for an architecture, or nil if not available.
+func (d Downloads) URL(arch Arch) *string {
+	if dl, ok := d[arch]; ok && dl != nil {
+		u := dl.URL
+		return &u
+	}
+	return nil
+}
+
+// Links returns the links for each architecture (which can be nil).
+func (d Downloads) Links() (x86 *string, x86_64 *string, arm64 *string) {
+	return d.URL(ArchX86), d.URL(ArchX86_64), d.URL(ArchARM64)
+}
+
+// CheckVersion checks that the link or file name of each download contains the
+// version, or a normalized form of it (see VersionForms). It returns a
+// *VersionMismatchError for the first one which doesn't. Versions which are
+// "latest" are not checked.
+func (d Downloads) CheckVersion(version string) error {
+	if version == "latest" {
+		return nil
+	}
+	forms := VersionForms(version)
+	for _, arch := range Archs {
+		dl, ok := d[arch]
+		if !ok || dl == nil {
+			continue
+		}
+		haystack := strings.ToLower(dl.URL + "\n" + dl.FileName)
+		if u, err := url.PathUnescape(dl.URL); err == nil {
+			haystack += "\n" + strings.ToLower(u)
+		}
+		found := false
+		for _, form := range forms {
+			if strings.Contains(haystack, form) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return &VersionMismatchError{Arch: arch, Version: version, URL: dl.URL}
+		}
+	}
+	return nil
+}
+
+// VersionForms returns the lowercase forms of a version which are expected in a download
+// link: the version itself, with the dots replaced by underscores, dashes, or nothing,
+// and each of those with trailing zero components removed (e.g. 1.2.0 -> 1.2, but not
+// 1.0 -> 1).
+func VersionForms(version string) []string {
+	version = strings.ToLower(version)
+	bases := []string{version}
+	for t := version; strings.HasSuffix(t, ".0") && strings.Count(t, ".") > 1; {
+		t = strings.TrimSuffix(t, ".0")
+		bases = append(bases, t)
+	}
+
+	forms := []string{}
+	for _, b := range bases {
+		for _, sep := range []string{".", "_", "-", ""} {
+			forms = append(forms, strings.Replace(b, ".", sep, -1))
+		}
+	}
+	return forms
+}
+
+// DownloadsExtractorFunc represents a function which extracts the downloads for a version.
+// See DownloadExtractorFunc for the meaning of version (the fields can also be used for
+// string substitution). Architectures which are not available should not be in the map.
+// It should stop and return an error when the context is done.
+type DownloadsExtractorFunc func(ctx context.Context, version VersionInfo) (Downloads, error)
+
+// DownloadExtractor is implemented by all kinds of download extractor functions.
+type DownloadExtractor interface {
+	// Structured returns the extractor as a DownloadsExtractorFunc.
+	Structured() DownloadsExtractorFunc
+}
+
+// Structured adapts a DownloadExtractorFunc into a DownloadsExtractorFunc. The context
+// is only checked before calling f.
+func (f DownloadExtractorFunc) Structured() DownloadsExtractorFunc {
+	return func(ctx context.Context, version VersionInfo) (Downloads, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		x86, x86_64, arm64, err := f(version.Version)
+		if err != nil {
+			return nil, err
+		}
+		return NewDownloads(x86, x86_64, arm64), nil
+	}
+}
+
+// Structured returns f.
+func (f DownloadsExtractorFunc) Structured() DownloadsExtractorFunc {
+	return f
+}
+
+// Links adapts a DownloadsExtractorFunc into a DownloadExtractorFunc. The metadata is discarded,
+// the version will not have any fields, and it is called with a background context.
+func (f DownloadsExtractorFunc) Links() DownloadExtractorFunc {
+	return func(version string) (*string, *string, *string, error) {
+		d, err := f(context.Background(), VersionInfo{Version: version})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		x86, x86_64, arm64 := d.Links()
+		return x86, x86_64, arm64, nil
+	}
+}
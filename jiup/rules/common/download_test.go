@@ -0,0 +1,89 @@
+package c
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadsAdapters(t *testing.T) {
+	x86, arm64 := "https://example.com/x86.exe", "https://example.com/arm64.exe"
+
+	var f DownloadExtractorFunc = func(version string) (*string, *string, *string, error) {
+		if version == "error" {
+			return nil, nil, nil, errors.New("error")
+		}
+		return &x86, nil, &arm64, nil
+	}
+
+	dls, err := f.Structured()(context.Background(), VersionInfo{Version: "1.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, Downloads{
+		ArchX86:   {URL: x86},
+		ArchARM64: {URL: arm64},
+	}, dls)
+	assert.Nil(t, dls.URL(ArchX86_64))
+
+	_, err = f.Structured()(context.Background(), VersionInfo{Version: "error"})
+	assert.Error(t, err)
+
+	rx86, rx86_64, rarm64, err := f.Structured().Links()("1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, &x86, rx86)
+	assert.Nil(t, rx86_64)
+	assert.Equal(t, &arm64, rarm64)
+
+	_, _, _, err = f.Structured().Links()("error")
+	assert.Error(t, err)
+}
+
+func TestCheckVersion(t *testing.T) {
+	for _, tc := range []struct {
+		Version string
+		Dls     Downloads
+		OK      bool
+	}{
+		{"1.2.3", Downloads{ArchX86_64: {URL: "https://example.com/app-1.2.3.msi"}}, true},
+		{"1.2.3", Downloads{ArchX86_64: {URL: "https://example.com/app-1_2_3.msi"}}, true},
+		{"1.2.3", Downloads{ArchX86_64: {URL: "https://example.com/app-1-2-3.msi"}}, true},
+		{"1.2.3", Downloads{ArchX86_64: {URL: "https://example.com/app123.msi"}}, true},
+		{"1.2.0", Downloads{ArchX86_64: {URL: "https://example.com/app-1.2.msi"}}, true},
+		{"1.0", Downloads{ArchX86_64: {URL: "https://example.com/app-1.msi"}}, false},
+		{"1.0-RC1", Downloads{ArchX86_64: {URL: "https://example.com/app-1.0-rc1.msi"}}, true},
+		{"1.2 beta", Downloads{ArchX86_64: {URL: "https://example.com/app-1.2%20beta.msi"}}, true},
+		{"1.2.3", Downloads{ArchX86_64: {URL: "https://example.com/download?id=5", FileName: "app-1.2.3.msi"}}, true},
+		{"1.2.3", Downloads{ArchX86_64: {URL: "https://example.com/app-1.2.2.msi"}}, false},
+		{"1.2.3", Downloads{ArchX86: {URL: "https://example.com/app-1.2.3.msi"}, ArchX86_64: {URL: "https://example.com/app-1.2.2-x64.msi"}}, false},
+		{"latest", Downloads{ArchX86_64: {URL: "https://example.com/app.msi"}}, true},
+		{"1.2.3", Downloads{}, true},
+	} {
+		err := tc.Dls.CheckVersion(tc.Version)
+		if tc.OK {
+			assert.NoError(t, err, tc.Version)
+		} else if assert.IsType(t, &VersionMismatchError{}, err, tc.Version) {
+			assert.Equal(t, tc.Version, err.(*VersionMismatchError).Version)
+		}
+	}
+}
+
+func TestAdaptersContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	_, err := VersionExtractorFunc(func() (string, error) {
+		called = true
+		return "1.0", nil
+	}).Structured()(ctx)
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = DownloadExtractorFunc(func(string) (*string, *string, *string, error) {
+		called = true
+		return nil, nil, nil, nil
+	}).Structured()(ctx, VersionInfo{Version: "1.0"})
+	assert.Equal(t, context.Canceled, err)
+
+	assert.False(t, called, "legacy extractor should not be called if the context is done")
+}
@@ -0,0 +1,194 @@
+package c
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// ErrorCategory is the category of an extraction error.
+type ErrorCategory string
+
+// Error categories.
+const (
+	// CategoryNetwork is for errors making a request (including timeouts).
+	CategoryNetwork ErrorCategory = "network"
+	// CategoryTLS is for certificate and handshake errors.
+	CategoryTLS ErrorCategory = "tls"
+	// CategoryHTTPStatus is for unexpected response statuses.
+	CategoryHTTPStatus ErrorCategory = "http status"
+	// CategoryParse is for responses which could not be parsed.
+	CategoryParse ErrorCategory = "parse"
+	// CategorySelector is for selectors which did not match.
+	CategorySelector ErrorCategory = "selector"
+	// CategoryRegexp is for regexps which did not match.
+	CategoryRegexp ErrorCategory = "regexp"
+	// CategoryValidation is for invalid rules and invalid results.
+	CategoryValidation ErrorCategory = "validation"
+	// CategoryConsistency is for results which don't agree with each other (e.g.
+	// a link without the version, or sources which return different versions).
+	CategoryConsistency ErrorCategory = "consistency"
+	// CategoryOther is for errors which don't fit in the other categories.
+	CategoryOther ErrorCategory = "other"
+)
+
+// ErrorCategories contains all error categories in the order they should be shown.
+var ErrorCategories = []ErrorCategory{CategoryNetwork, CategoryTLS, CategoryHTTPStatus, CategoryParse, CategorySelector, CategoryRegexp, CategoryValidation, CategoryConsistency, CategoryOther}
+
+// ExtractError is an error from an extractor. The rule, url, and architecture are
+// set if known.
+type ExtractError struct {
+	Category ErrorCategory
+	Rule     string
+	URL      string
+	Arch     Arch
+	Err      error
+}
+
+// NewError creates an ExtractError for an error.
+func NewError(category ErrorCategory, url string, err error) *ExtractError {
+	return &ExtractError{Category: category, URL: url, Err: err}
+}
+
+// Errorf creates an ExtractError with a formatted message (see fmt.Errorf).
+func Errorf(category ErrorCategory, url string, format string, a ...interface{}) *ExtractError {
+	return NewError(category, url, fmt.Errorf(format, a...))
+}
+
+// WithArch returns a copy of the error for an architecture.
+func (e *ExtractError) WithArch(arch Arch) *ExtractError {
+	ee := *e
+	ee.Arch = arch
+	return &ee
+}
+
+func (e *ExtractError) Error() string {
+	if e.URL != "" {
+		return fmt.Sprintf("%v (%s)", e.Err, e.URL)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExtractError) Unwrap() error {
+	return e.Err
+}
+
+// WithRule sets the rule of an error, wrapping it in an ExtractError if needed.
+func WithRule(rule string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*ExtractError); ok {
+		ee := *e
+		ee.Rule = rule
+		return &ee
+	}
+	return &ExtractError{Category: Category(err), Rule: rule, Err: err}
+}
+
+// Category returns the category of an error. The first ExtractError or error with
+// a method ErrorCategory() ErrorCategory in the chain is used, otherwise the
+// category is guessed from the standard library error types.
+func Category(err error) ErrorCategory {
+	if err == nil {
+		return ""
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		switch e := e.(type) {
+		case *ExtractError:
+			if e.Category != "" {
+				return e.Category
+			}
+		case *VersionMismatchError:
+			return CategoryConsistency
+		case interface{ ErrorCategory() ErrorCategory }:
+			return e.ErrorCategory()
+		}
+	}
+	return RequestCategory(err)
+}
+
+// RequestCategory guesses the category of an error making a request.
+func RequestCategory(err error) ErrorCategory {
+	// the x509 errors are wrapped by tls.CertificateVerificationError in newer
+	// versions of Go, and alerts are only returned as a net.OpError with an
+	// unexported type before tls.AlertError
+	var (
+		uae x509.UnknownAuthorityError
+		cie x509.CertificateInvalidError
+		hne x509.HostnameError
+		rhe tls.RecordHeaderError
+		oe  *net.OpError
+	)
+	switch {
+	case errors.As(err, &uae), errors.As(err, &cie), errors.As(err, &hne), errors.As(err, &rhe):
+		return CategoryTLS
+	case errors.As(err, &oe) && oe.Op == "remote error":
+		return CategoryTLS
+	}
+
+	var (
+		se  *json.SyntaxError
+		ute *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &se), errors.As(err, &ute):
+		return CategoryParse
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return CategoryNetwork
+	}
+	return CategoryOther
+}
+
+// IsTimeout checks if an error is because of a timeout.
+func IsTimeout(err error) bool {
+	var ne net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout())
+}
+
+// VersionMismatchError is returned if a download link does not contain the version
+// it was extracted for (e.g. if the page was being updated when it was fetched).
+type VersionMismatchError struct {
+	Arch    Arch
+	Version string
+	URL     string
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("%s link (%s) does not contain version %s", e.Arch, e.URL, e.Version)
+}
+
+// GroupByCategory groups errors (e.g. by package) by their category (see Category).
+// The keys in each group are sorted.
+func GroupByCategory(errs map[string]error) map[ErrorCategory][]string {
+	groups := map[ErrorCategory][]string{}
+	for k, err := range errs {
+		cat := Category(err)
+		groups[cat] = append(groups[cat], k)
+	}
+	for _, ks := range groups {
+		sort.Strings(ks)
+	}
+	return groups
+}
+
+// FormatCategoryCounts formats the number of errors in each category of a grouping
+// (see GroupByCategory), e.g. "2 network, 1 regexp".
+func FormatCategoryCounts(groups map[ErrorCategory][]string) string {
+	s := []string{}
+	for _, cat := range ErrorCategories {
+		if n := len(groups[cat]); n > 0 {
+			s = append(s, fmt.Sprintf("%d %s", n, cat))
+		}
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,80 @@
+package c
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+type categoryError struct{}
+
+func (categoryError) Error() string                { return "custom" }
+func (categoryError) ErrorCategory() ErrorCategory { return CategoryConsistency }
+
+func TestCategory(t *testing.T) {
+	var syntaxErr error = json.Unmarshal([]byte("{"), &struct{}{})
+	for _, tc := range []struct {
+		Err      error
+		Category ErrorCategory
+	}{
+		{nil, ""},
+		{errors.New("test"), CategoryOther},
+		{Errorf(CategoryRegexp, "https://example.com", "test"), CategoryRegexp},
+		{fmt.Errorf("wrapped: %w", Errorf(CategorySelector, "", "test")), CategorySelector},
+		{Errorf(CategorySelector, "", "wrapped: %w", Errorf(CategoryRegexp, "", "test")), CategorySelector},
+		{&VersionMismatchError{ArchX86, "1.0", "https://example.com"}, CategoryConsistency},
+		{categoryError{}, CategoryConsistency},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: timeoutError{}}, CategoryNetwork},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: x509.UnknownAuthorityError{}}, CategoryTLS},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: &net.OpError{Op: "remote error", Err: errors.New("tls: handshake failure")}}, CategoryTLS},
+		{fmt.Errorf("%w", context.DeadlineExceeded), CategoryNetwork},
+		{syntaxErr, CategoryParse},
+		{WithRule("test", errors.New("test")), CategoryOther},
+		{WithRule("test", Errorf(CategoryHTTPStatus, "", "test")), CategoryHTTPStatus},
+	} {
+		assert.Equal(t, tc.Category, Category(tc.Err), "%v", tc.Err)
+	}
+}
+
+func TestExtractError(t *testing.T) {
+	err := Errorf(CategoryRegexp, "https://example.com", "could not find %s", "x86").WithArch(ArchX86)
+	assert.EqualError(t, err, "could not find x86 (https://example.com)")
+	assert.Equal(t, ArchX86, err.Arch)
+
+	rerr := WithRule("test", err)
+	assert.EqualError(t, rerr, err.Error())
+	var ee *ExtractError
+	if assert.True(t, errors.As(rerr, &ee)) {
+		assert.Equal(t, "test", ee.Rule)
+		assert.Equal(t, ArchX86, ee.Arch)
+		assert.Equal(t, "https://example.com", ee.URL)
+	}
+	assert.Empty(t, err.Rule, "should not modify the original error")
+
+	assert.True(t, IsTimeout(fmt.Errorf("%w", context.DeadlineExceeded)))
+	assert.True(t, IsTimeout(&url.Error{Op: "Get", URL: "https://example.com", Err: timeoutError{}}))
+	assert.False(t, IsTimeout(errors.New("test")))
+}
+
+func TestGroupByCategory(t *testing.T) {
+	groups := GroupByCategory(map[string]error{
+		"b": Errorf(CategoryNetwork, "", "test"),
+		"a": Errorf(CategoryNetwork, "", "test"),
+		"c": Errorf(CategoryRegexp, "", "test"),
+	})
+	assert.Equal(t, map[ErrorCategory][]string{CategoryNetwork: {"a", "b"}, CategoryRegexp: {"c"}}, groups)
+	assert.Equal(t, "2 network, 1 regexp", FormatCategoryCounts(groups))
+}
@@ -0,0 +1,58 @@
+package c
+
+import "context"
+
+// VersionInfo represents an extracted version. Fields contains additional named
+// values which are not part of the displayed version (e.g. a build number), and can
+// be referenced by download extractors.
+type VersionInfo struct {
+	Version string
+	Fields  map[string]string // optional
+}
+
+// String returns the displayed version.
+func (v VersionInfo) String() string {
+	return v.Version
+}
+
+// VersionInfoExtractorFunc represents a function which extracts the version and any
+// additional fields. It should stop and return an error when the context is done.
+type VersionInfoExtractorFunc func(ctx context.Context) (version VersionInfo, err error)
+
+// VersionExtractor is implemented by all kinds of version extractor functions.
+type VersionExtractor interface {
+	// Structured returns the extractor as a VersionInfoExtractorFunc.
+	Structured() VersionInfoExtractorFunc
+}
+
+// Structured adapts a VersionExtractorFunc into a VersionInfoExtractorFunc. The context
+// is only checked before calling f.
+func (f VersionExtractorFunc) Structured() VersionInfoExtractorFunc {
+	return func(ctx context.Context) (VersionInfo, error) {
+		if err := ctx.Err(); err != nil {
+			return VersionInfo{}, err
+		}
+		version, err := f()
+		if err != nil {
+			return VersionInfo{}, err
+		}
+		return VersionInfo{Version: version}, nil
+	}
+}
+
+// Structured returns f.
+func (f VersionInfoExtractorFunc) Structured() VersionInfoExtractorFunc {
+	return f
+}
+
+// Simple adapts a VersionInfoExtractorFunc into a VersionExtractorFunc. The fields are discarded,
+// and it is called with a background context.
+func (f VersionInfoExtractorFunc) Simple() VersionExtractorFunc {
+	return func() (string, error) {
+		version, err := f(context.Background())
+		if err != nil {
+			return "", err
+		}
+		return version.Version, nil
+	}
+}
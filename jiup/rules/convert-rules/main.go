@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/just-install/just-install-updater-go/jiup/rules/declarative"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	source := pflag.String("source", filepath.Join("jiup", "rules", "rules.go"), "The Go file containing the rules")
+	out := pflag.StringP("out", "o", "rules", "The directory to write the rule definitions to (as package.json)")
+	force := pflag.BoolP("force", "f", false, "Overwrite existing rule definitions")
+	help := pflag.Bool("help", false, "Show this help text")
+	pflag.Parse()
+
+	if *help {
+		helpExit()
+	}
+
+	src, err := ioutil.ReadFile(*source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	converted, skipped, err := declarative.Convert(*source, src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if pflag.NArg() > 0 {
+		filtered := []*declarative.Rule{}
+		for _, p := range pflag.Args() {
+			found := false
+			for _, r := range converted {
+				if r.Package == p {
+					filtered = append(filtered, r)
+					found = true
+				}
+			}
+			if _, ok := skipped[p]; !ok && !found {
+				fmt.Fprintf(os.Stderr, "Error: no rule for %s\n", p)
+				os.Exit(1)
+			}
+		}
+		converted = filtered
+	}
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	written, invalid := 0, 0
+	for _, r := range converted {
+		if err := r.Validate(); err != nil {
+			fmt.Printf(" ✗  %s: %v\n", r.Package, err)
+			invalid++
+			continue
+		}
+		fn := filepath.Join(*out, r.Package+".json")
+		if _, err := os.Stat(fn); err == nil && !*force {
+			fmt.Printf(" -  %s: %s already exists\n", r.Package, fn)
+			continue
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := ioutil.WriteFile(fn, buf.Bytes(), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		written++
+	}
+
+	pkgs := []string{}
+	for p := range skipped {
+		if pflag.NArg() == 0 || includes(pflag.Args(), p) {
+			pkgs = append(pkgs, p)
+		}
+	}
+	sort.Strings(pkgs)
+	for _, p := range pkgs {
+		fmt.Printf(" ✗  %s: %v\n", p, skipped[p])
+	}
+
+	fmt.Printf("\nSummary: %d written, %d not converted\n", written, len(pkgs)+invalid)
+	os.Exit(0)
+}
+
+func helpExit() {
+	fmt.Fprintf(os.Stderr, "Usage: convert-rules [options] [packages...]\n\n")
+	pflag.PrintDefaults()
+	os.Exit(1)
+}
+
+func includes(arr []string, s string) bool {
+	for _, x := range arr {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,415 @@
+package declarative
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	d "github.com/just-install/just-install-updater-go/jiup/rules/download"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+	"github.com/just-install/just-install-updater-go/jiup/rules/lint"
+	v "github.com/just-install/just-install-updater-go/jiup/rules/version"
+	w "github.com/just-install/just-install-updater-go/jiup/rules/wrapper"
+)
+
+// VersionTypes contains the fields which can be used by each type of version
+// extractor (other than type).
+var VersionTypes = map[string][]string{
+	"regexp":            {"url", "regexp", "format"},
+	"regexp-max":        {"url", "regexp", "include", "exclude"},
+	"html":              {"url", "selector", "attr", "regexp", "format"},
+	"html-max":          {"url", "selector", "attr", "regexp", "include", "exclude"},
+	"github-release":    {"repo", "regexp", "format"},
+	"github-tag":        {"repo", "regexp", "format"},
+	"appveyor-branch":   {"repo", "branch"},
+	"latest":            {"suffix"},
+	"client-options":    {"client", "of"},
+	"first-of":          {"all"},
+	"consensus":         {"n", "all"},
+	"underscore-to-dot": {"of"},
+	"timeout":           {"timeout", "of"},
+	"transform":         {"of", "transforms"},
+}
+
+// DownloadTypes contains the fields which can be used by each type of download
+// extractor (other than type), with the fields which can be used for each
+// architecture after the colon.
+var DownloadTypes = map[string][]string{
+	"regexp":             {"url", "format", "x86", "x86_64", "arm64", ":regexp"},
+	"html":               {"url", "format", "x86", "x86_64", "arm64", ":selector", ":attr", ":regexp"},
+	"html-a":             {"url", "x86", "x86_64", "arm64", ":selector"},
+	"template":           {"x86", "x86_64", "arm64", ":template"},
+	"github-release":     {"repo", "x86", "x86_64", "arm64", ":regexp"},
+	"appveyor-artifacts": {"repo", "x86", "x86_64", "arm64", ":regexp"},
+	"client-options":     {"client", "of"},
+	"first-of":           {"all"},
+	"consensus":          {"n", "all"},
+	"append-to-url":      {"suffix", "of"},
+	"split":              {"x86", "x86_64", "arm64", ":extractor"},
+	"timeout":            {"timeout", "of"},
+}
+
+// TransformTypes contains the fields which can be used by each type of version
+// transform (other than type).
+var TransformTypes = map[string][]string{
+	"replace":     {"old", "new"},
+	"replace-re":  {"regexp", "new"},
+	"trim-prefix": {"value"},
+	"trim-suffix": {"value"},
+	"first-n":     {"n"},
+	"pad-n":       {"n"},
+	"lower":       {},
+	"map":         {"map"},
+}
+
+// builder builds the extractors for a rule, collecting all errors.
+type builder struct {
+	rule *Rule
+	errs Errors
+	v    c.VersionExtractor
+	d    c.DownloadExtractor
+}
+
+func (b *builder) errorf(path, format string, a ...interface{}) {
+	b.errs = append(b.errs, &Error{File: b.rule.File, Package: b.rule.Package, Path: path, Msg: fmt.Sprintf(format, a...)})
+}
+
+func (b *builder) build() {
+	if b.rule.Package == "" {
+		b.errorf("package", "required")
+	}
+	b.v = b.version(b.rule.Version, "version")
+	b.d = b.download(b.rule.Download, "download")
+	if len(b.errs) == 0 && b.v != nil && b.d != nil {
+		for _, p := range lint.Errors(lint.Rule(b.rule.Package, c.DescribeVersion(b.v), c.DescribeDownloads(b.d))) {
+			b.errorf(p.Extractor, "%s", p.Message)
+		}
+	}
+}
+
+// fields checks the type and fields of an extractor or transform, and returns
+// false if the type is invalid.
+func (b *builder) fields(path, kind string, obj interface{}, types map[string][]string) bool {
+	typ := reflect.ValueOf(obj).Elem().FieldByName("Type").String()
+	allowed, ok := types[typ]
+	if !ok {
+		names := []string{}
+		for name := range types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if typ == "" {
+			b.errorf(path+".type", "required (one of %s)", strings.Join(names, ", "))
+		} else {
+			b.errorf(path+".type", "unknown %s type %q (expected one of %s)", kind, typ, strings.Join(names, ", "))
+		}
+		return false
+	}
+
+	fields, archFields := []string{"type"}, []string{}
+	for _, f := range allowed {
+		if strings.HasPrefix(f, ":") {
+			archFields = append(archFields, f[1:])
+		} else {
+			fields = append(fields, f)
+		}
+	}
+	for _, f := range setFields(obj) {
+		if !includes(fields, f) {
+			b.errorf(path+"."+f, "not used by %s %s", typ, kind)
+		}
+	}
+	if e, ok := obj.(*Extractor); ok {
+		for _, a := range []struct {
+			name string
+			arch *Arch
+		}{{"x86", e.X86}, {"x86_64", e.X86_64}, {"arm64", e.ARM64}} {
+			if a.arch == nil {
+				continue
+			}
+			for _, f := range setFields(a.arch) {
+				if !includes(archFields, f) {
+					b.errorf(path+"."+a.name+"."+f, "not used by %s %s", typ, kind)
+				}
+			}
+		}
+	}
+	return true
+}
+
+// setFields returns the JSON names of the non-zero fields of a struct pointer.
+func setFields(obj interface{}) []string {
+	val := reflect.ValueOf(obj).Elem()
+	fields := []string{}
+	for i := 0; i < val.NumField(); i++ {
+		tag := strings.Split(val.Type().Field(i).Tag.Get("json"), ",")[0]
+		if tag == "" || tag == "-" || tag == "type" {
+			continue
+		}
+		if !val.Field(i).IsZero() {
+			fields = append(fields, tag)
+		}
+	}
+	return fields
+}
+
+func includes(arr []string, s string) bool {
+	for _, x := range arr {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *builder) required(path, val string) string {
+	if val == "" {
+		b.errorf(path, "required")
+	}
+	return val
+}
+
+// re compiles a regexp, returning nil if it is empty.
+func (b *builder) re(path, str string) *regexp.Regexp {
+	if str == "" {
+		return nil
+	}
+	re, err := regexp.Compile(str)
+	if err != nil {
+		b.errorf(path, "invalid regexp: %v", err)
+		return nil
+	}
+	return re
+}
+
+// requiredRe compiles a regexp which must be set.
+func (b *builder) requiredRe(path, str string) *regexp.Regexp {
+	if str == "" {
+		b.errorf(path, "required")
+		return nil
+	}
+	return b.re(path, str)
+}
+
+func (b *builder) duration(path, str string) time.Duration {
+	if str == "" {
+		return 0
+	}
+	dur, err := time.ParseDuration(str)
+	if err != nil || dur < 0 {
+		b.errorf(path, "invalid duration %q (e.g. 30s)", str)
+	}
+	return dur
+}
+
+func (b *builder) client(path string, o *ClientOptions) h.ClientOptions {
+	if o == nil {
+		b.errorf(path, "required")
+		return h.ClientOptions{}
+	}
+	opts := h.ClientOptions{
+		Timeout:   b.duration(path+".timeout", o.Timeout),
+		Insecure:  o.Insecure,
+		Pins:      o.Pins,
+		UserAgent: o.UserAgent,
+	}
+	if o.RootCAs != "" {
+		opts.RootCAs = []byte(o.RootCAs)
+	}
+	return opts
+}
+
+func (b *builder) version(e *Extractor, path string) c.VersionExtractor {
+	if e == nil {
+		b.errorf(path, "required")
+		return nil
+	}
+	if !b.fields(path, "version extractor", e, VersionTypes) {
+		return nil
+	}
+
+	switch e.Type {
+	case "regexp":
+		return v.RegexpF(b.required(path+".url", e.URL), b.requiredRe(path+".regexp", e.Regexp), e.Format)
+	case "regexp-max":
+		return v.RegexpMax(b.required(path+".url", e.URL), b.requiredRe(path+".regexp", e.Regexp), b.re(path+".include", e.Include), b.re(path+".exclude", e.Exclude))
+	case "html":
+		return v.HTMLF(b.required(path+".url", e.URL), b.required(path+".selector", e.Selector), b.required(path+".attr", e.Attr), b.re(path+".regexp", e.Regexp), e.Format)
+	case "html-max":
+		return v.HTMLMax(b.required(path+".url", e.URL), b.required(path+".selector", e.Selector), b.required(path+".attr", e.Attr), b.re(path+".regexp", e.Regexp), b.re(path+".include", e.Include), b.re(path+".exclude", e.Exclude))
+	case "github-release":
+		return v.GitHubReleaseF(b.required(path+".repo", e.Repo), b.re(path+".regexp", e.Regexp), e.Format)
+	case "github-tag":
+		return v.GitHubTagF(b.required(path+".repo", e.Repo), b.re(path+".regexp", e.Regexp), e.Format)
+	case "appveyor-branch":
+		return v.AppVeyorBranch(b.required(path+".repo", e.Repo), b.required(path+".branch", e.Branch))
+	case "latest":
+		return v.LatestS(e.Suffix)
+	case "client-options":
+		return w.ClientOptions(b.client(path+".client", e.Client), b.version(e.Of, path+".of"))
+	case "first-of":
+		return w.FirstOf(b.versions(e.All, path+".all")...)
+	case "consensus":
+		if e.N < 1 || e.N > len(e.All) {
+			b.errorf(path+".n", "must be between 1 and the number of extractors (%d)", len(e.All))
+		}
+		return w.Consensus(e.N, b.versions(e.All, path+".all")...)
+	case "underscore-to-dot":
+		return w.UnderscoreToDot(b.version(e.Of, path+".of"))
+	case "timeout":
+		return w.Timeout(b.duration(path+".timeout", b.required(path+".timeout", e.Timeout)), b.version(e.Of, path+".of"))
+	case "transform":
+		if len(e.Transforms) == 0 {
+			b.errorf(path+".transforms", "required")
+		}
+		ts := []w.VersionTransform{}
+		for i, t := range e.Transforms {
+			ts = append(ts, b.transform(t, fmt.Sprintf("%s.transforms[%d]", path, i)))
+		}
+		return w.Transform(b.version(e.Of, path+".of"), ts...)
+	}
+	panic("unhandled version extractor type " + e.Type)
+}
+
+func (b *builder) versions(es []*Extractor, path string) []c.VersionExtractor {
+	if len(es) == 0 {
+		b.errorf(path, "required")
+	}
+	fs := []c.VersionExtractor{}
+	for i, e := range es {
+		fs = append(fs, b.version(e, fmt.Sprintf("%s[%d]", path, i)))
+	}
+	return fs
+}
+
+func (b *builder) transform(t *Transform, path string) w.VersionTransform {
+	if t == nil {
+		b.errorf(path, "required")
+		return w.VersionTransform{}
+	}
+	if !b.fields(path, "transform", t, TransformTypes) {
+		return w.VersionTransform{}
+	}
+
+	switch t.Type {
+	case "replace":
+		return w.Replace(b.required(path+".old", t.Old), t.New)
+	case "replace-re":
+		return w.ReplaceRe(b.requiredRe(path+".regexp", t.Regexp), t.New)
+	case "trim-prefix":
+		return w.TrimPrefix(b.required(path+".value", t.Value))
+	case "trim-suffix":
+		return w.TrimSuffix(b.required(path+".value", t.Value))
+	case "first-n", "pad-n":
+		if t.N < 1 {
+			b.errorf(path+".n", "must be at least 1")
+		}
+		if t.Type == "first-n" {
+			return w.FirstN(t.N)
+		}
+		return w.PadN(t.N)
+	case "lower":
+		return w.Lower()
+	case "map":
+		if len(t.Map) == 0 {
+			b.errorf(path+".map", "required")
+		}
+		return w.Map(t.Map)
+	}
+	panic("unhandled transform type " + t.Type)
+}
+
+// arch returns the parameters for an architecture, or an empty Arch if not set.
+func arch(a *Arch) *Arch {
+	if a == nil {
+		return &Arch{}
+	}
+	return a
+}
+
+func (b *builder) download(e *Extractor, path string) c.DownloadExtractor {
+	if e == nil {
+		b.errorf(path, "required")
+		return nil
+	}
+	if !b.fields(path, "download extractor", e, DownloadTypes) {
+		return nil
+	}
+
+	x86, x64, arm64 := arch(e.X86), arch(e.X86_64), arch(e.ARM64)
+	if _, ok := map[string]bool{"client-options": true, "first-of": true, "consensus": true, "append-to-url": true, "timeout": true}[e.Type]; !ok {
+		if e.X86 == nil && e.X86_64 == nil && e.ARM64 == nil {
+			b.errorf(path, "at least one of x86, x86_64 and arm64 must be set")
+		}
+	}
+
+	switch e.Type {
+	case "regexp":
+		return d.RegexpF(b.required(path+".url", e.URL), b.re(path+".x86.regexp", x86.Regexp), b.re(path+".x86_64.regexp", x64.Regexp), e.Format, d.ARM64{FileRe: b.re(path+".arm64.regexp", arm64.Regexp)})
+	case "html":
+		b.archAttrs(path, e)
+		return d.HTMLF(b.required(path+".url", e.URL), x86.Selector, x64.Selector, x86.Attr, x64.Attr, b.re(path+".x86.regexp", x86.Regexp), b.re(path+".x86_64.regexp", x64.Regexp), e.Format, d.ARM64{Selector: arm64.Selector, Attr: arm64.Attr, FileRe: b.re(path+".arm64.regexp", arm64.Regexp)})
+	case "html-a":
+		return d.HTMLA(b.required(path+".url", e.URL), x86.Selector, x64.Selector, d.ARM64{Selector: arm64.Selector})
+	case "template":
+		return d.Template(x86.Template, x64.Template, d.ARM64{Template: arm64.Template})
+	case "github-release":
+		return d.GitHubRelease(b.required(path+".repo", e.Repo), b.re(path+".x86.regexp", x86.Regexp), b.re(path+".x86_64.regexp", x64.Regexp), d.ARM64{FileRe: b.re(path+".arm64.regexp", arm64.Regexp)})
+	case "appveyor-artifacts":
+		return d.AppVeyorArtifacts(b.required(path+".repo", e.Repo), b.re(path+".x86.regexp", x86.Regexp), b.re(path+".x86_64.regexp", x64.Regexp), d.ARM64{FileRe: b.re(path+".arm64.regexp", arm64.Regexp)})
+	case "client-options":
+		return w.ClientOptionsDownloads(b.client(path+".client", e.Client), b.download(e.Of, path+".of"))
+	case "first-of":
+		return w.FirstOfDownloads(b.downloads(e.All, path+".all")...)
+	case "consensus":
+		if e.N < 1 || e.N > len(e.All) {
+			b.errorf(path+".n", "must be between 1 and the number of extractors (%d)", len(e.All))
+		}
+		return w.ConsensusDownloads(e.N, b.downloads(e.All, path+".all")...)
+	case "append-to-url":
+		return w.AppendToURL(b.required(path+".suffix", e.Suffix), b.download(e.Of, path+".of"))
+	case "split":
+		// a nil function in a non-nil interface would not be skipped
+		fs := []c.DownloadExtractor{nil, nil, nil}
+		for i, a := range []struct {
+			name string
+			arch *Arch
+		}{{"x86", e.X86}, {"x86_64", e.X86_64}, {"arm64", e.ARM64}} {
+			if a.arch != nil {
+				fs[i] = b.download(a.arch.Extractor, path+"."+a.name+".extractor")
+			}
+		}
+		return w.SplitDownload(fs[0], fs[1], fs[2])
+	case "timeout":
+		return w.TimeoutDownloads(b.duration(path+".timeout", b.required(path+".timeout", e.Timeout)), b.download(e.Of, path+".of"))
+	}
+	panic("unhandled download extractor type " + e.Type)
+}
+
+// archAttrs checks that architectures with a selector have an attribute.
+func (b *builder) archAttrs(path string, e *Extractor) {
+	for _, a := range []struct {
+		name string
+		arch *Arch
+	}{{"x86", e.X86}, {"x86_64", e.X86_64}, {"arm64", e.ARM64}} {
+		if a.arch != nil {
+			b.required(path+"."+a.name+".selector", a.arch.Selector)
+			b.required(path+"."+a.name+".attr", a.arch.Attr)
+		}
+	}
+}
+
+func (b *builder) downloads(es []*Extractor, path string) []c.DownloadExtractor {
+	if len(es) == 0 {
+		b.errorf(path, "required")
+	}
+	fs := []c.DownloadExtractor{}
+	for i, e := range es {
+		fs = append(fs, b.download(e, fmt.Sprintf("%s[%d]", path, i)))
+	}
+	return fs
+}
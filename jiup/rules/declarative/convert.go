@@ -0,0 +1,722 @@
+package declarative
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strconv"
+	"time"
+)
+
+// Convert converts the rules registered with rules.Rule in a Go source file (e.g.
+// rules.go) into rule definitions. Rules which can't be represented (e.g. ones which
+// use a custom function) are returned in skipped with the reason.
+func Convert(fn string, src []byte) (converted []*Rule, skipped map[string]error, err error) {
+	cv := &converter{fset: token.NewFileSet()}
+	f, err := parser.ParseFile(cv.fset, fn, src, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	converted, skipped = []*Rule{}, map[string]error{}
+	noVersionCheck := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		fn, ok := call.Fun.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		switch fn.Name {
+		case "Rule":
+			if len(call.Args) != 3 {
+				return true
+			}
+			pkg, err := cv.str(call.Args[0])
+			if err != nil {
+				// e.g. rules registered in a loop
+				skipped[types.ExprString(call.Args[0])] = cv.errorf(call.Args[0], "package name is not a constant string")
+				return false
+			}
+			r := &Rule{Package: pkg}
+			if r.Version, err = cv.version(call.Args[1]); err == nil {
+				r.Download, err = cv.download(call.Args[2])
+			}
+			if err != nil {
+				skipped[pkg] = err
+			} else {
+				converted = append(converted, r)
+			}
+			return false
+		case "NoVersionCheck":
+			for _, arg := range call.Args {
+				if pkg, err := cv.str(arg); err == nil {
+					noVersionCheck[pkg] = true
+				}
+			}
+			return false
+		}
+		return true
+	})
+	for _, r := range converted {
+		r.NoVersionCheck = noVersionCheck[r.Package]
+	}
+	return converted, skipped, nil
+}
+
+type converter struct {
+	fset *token.FileSet
+}
+
+func (cv *converter) errorf(expr ast.Expr, format string, a ...interface{}) error {
+	return fmt.Errorf("line %d: %s", cv.fset.Position(expr.Pos()).Line, fmt.Sprintf(format, a...))
+}
+
+func (cv *converter) unsupported(expr ast.Expr) error {
+	custom := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FuncLit); ok {
+			custom = true
+		}
+		return !custom
+	})
+	if custom {
+		return cv.errorf(expr, "custom functions can't be converted")
+	}
+	return cv.errorf(expr, "unsupported expression %s", types.ExprString(expr))
+}
+
+// call returns the name (e.g. v.Regexp) and arguments of a function call.
+func (cv *converter) call(expr ast.Expr) (string, []ast.Expr, error) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return "", nil, cv.unsupported(expr)
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", nil, cv.unsupported(expr)
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", nil, cv.unsupported(expr)
+	}
+	return pkg.Name + "." + sel.Sel.Name, call.Args, nil
+}
+
+// args checks the number of arguments to a function.
+func (cv *converter) args(expr ast.Expr, name string, args []ast.Expr, n int) error {
+	if len(args) != n {
+		return cv.errorf(expr, "%s: expected %d arguments, got %d", name, n, len(args))
+	}
+	return nil
+}
+
+func (cv *converter) str(expr ast.Expr) (string, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind == token.STRING {
+			return strconv.Unquote(e.Value)
+		}
+	case *ast.ParenExpr:
+		return cv.str(e.X)
+	case *ast.BinaryExpr:
+		if e.Op == token.ADD {
+			a, err := cv.str(e.X)
+			if err != nil {
+				return "", err
+			}
+			b, err := cv.str(e.Y)
+			if err != nil {
+				return "", err
+			}
+			return a + b, nil
+		}
+	}
+	return "", cv.unsupported(expr)
+}
+
+func (cv *converter) strs(exprs []ast.Expr) ([]string, error) {
+	res := make([]string, len(exprs))
+	for i, expr := range exprs {
+		s, err := cv.str(expr)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = s
+	}
+	return res, nil
+}
+
+// re converts a regexp (h.Re or regexp.MustCompile), or nil to an empty string.
+func (cv *converter) re(expr ast.Expr) (string, error) {
+	if id, ok := expr.(*ast.Ident); ok && id.Name == "nil" {
+		return "", nil
+	}
+	name, args, err := cv.call(expr)
+	if err != nil {
+		return "", err
+	}
+	if (name != "h.Re" && name != "regexp.MustCompile") || len(args) != 1 {
+		return "", cv.unsupported(expr)
+	}
+	return cv.str(args[0])
+}
+
+func (cv *converter) int(expr ast.Expr) (int, error) {
+	if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.INT {
+		return strconv.Atoi(lit.Value)
+	}
+	return 0, cv.unsupported(expr)
+}
+
+func (cv *converter) duration(expr ast.Expr) (time.Duration, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		n, err := cv.int(e)
+		return time.Duration(n), err
+	case *ast.ParenExpr:
+		return cv.duration(e.X)
+	case *ast.SelectorExpr:
+		if pkg, ok := e.X.(*ast.Ident); ok && pkg.Name == "time" {
+			if unit, ok := map[string]time.Duration{
+				"Millisecond": time.Millisecond,
+				"Second":      time.Second,
+				"Minute":      time.Minute,
+				"Hour":        time.Hour,
+			}[e.Sel.Name]; ok {
+				return unit, nil
+			}
+		}
+	case *ast.BinaryExpr:
+		if e.Op == token.MUL {
+			a, err := cv.duration(e.X)
+			if err != nil {
+				return 0, err
+			}
+			b, err := cv.duration(e.Y)
+			if err != nil {
+				return 0, err
+			}
+			return a * b, nil
+		}
+	}
+	return 0, cv.unsupported(expr)
+}
+
+func (cv *converter) bool(expr ast.Expr) (bool, error) {
+	if id, ok := expr.(*ast.Ident); ok && (id.Name == "true" || id.Name == "false") {
+		return id.Name == "true", nil
+	}
+	return false, cv.unsupported(expr)
+}
+
+func (cv *converter) client(expr ast.Expr) (*ClientOptions, error) {
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok || types.ExprString(lit.Type) != "h.ClientOptions" {
+		return nil, cv.unsupported(expr)
+	}
+	o := &ClientOptions{}
+	for _, el := range lit.Elts {
+		kv, ok := el.(*ast.KeyValueExpr)
+		if !ok {
+			return nil, cv.unsupported(el)
+		}
+		var err error
+		switch types.ExprString(kv.Key) {
+		case "Timeout":
+			var dur time.Duration
+			dur, err = cv.duration(kv.Value)
+			o.Timeout = dur.String()
+		case "Insecure":
+			o.Insecure, err = cv.bool(kv.Value)
+		case "UserAgent":
+			o.UserAgent, err = cv.str(kv.Value)
+		case "Pins":
+			pins, ok := kv.Value.(*ast.CompositeLit)
+			if !ok {
+				return nil, cv.unsupported(kv.Value)
+			}
+			o.Pins, err = cv.strs(pins.Elts)
+		case "RootCAs":
+			conv, ok := kv.Value.(*ast.CallExpr)
+			if !ok || types.ExprString(conv.Fun) != "[]byte" || len(conv.Args) != 1 {
+				return nil, cv.unsupported(kv.Value)
+			}
+			o.RootCAs, err = cv.str(conv.Args[0])
+		default:
+			return nil, cv.unsupported(el)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// archs converts a value for each architecture (with an empty value for none).
+func (cv *converter) archs(args []ast.Expr, fn func(ast.Expr) (string, error), set func(a *Arch, val string)) (x86, x64, arm64 *Arch, err error) {
+	res := make([]*Arch, 3)
+	for i, arg := range args {
+		val, err := fn(arg)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if val != "" {
+			res[i] = &Arch{}
+			set(res[i], val)
+		}
+	}
+	return res[0], res[1], res[2], nil
+}
+
+// arm64 checks that there are n arguments, or n and the arm64 parameters (a d.ARM64
+// literal) which are converted and removed from the arguments.
+func (cv *converter) arm64(expr ast.Expr, name string, args []ast.Expr, n int) ([]ast.Expr, *Arch, error) {
+	if len(args) != n+1 {
+		return args, nil, cv.args(expr, name, args, n)
+	}
+	lit, ok := args[n].(*ast.CompositeLit)
+	if !ok || types.ExprString(lit.Type) != "d.ARM64" {
+		return nil, nil, cv.unsupported(args[n])
+	}
+	a := &Arch{}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return nil, nil, cv.unsupported(elt)
+		}
+		var err error
+		switch types.ExprString(kv.Key) {
+		case "Selector":
+			a.Selector, err = cv.str(kv.Value)
+		case "Attr":
+			a.Attr, err = cv.str(kv.Value)
+		case "FileRe":
+			a.Regexp, err = cv.re(kv.Value)
+		case "Template":
+			a.Template, err = cv.str(kv.Value)
+		default:
+			err = cv.unsupported(kv.Key)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if *a == (Arch{}) {
+		a = nil
+	}
+	return args[:n], a, nil
+}
+
+// mergeArchs merges the fields of the architectures from multiple calls to archs.
+func mergeArchs(dst **Arch, src *Arch) {
+	if src == nil {
+		return
+	}
+	if *dst == nil {
+		*dst = &Arch{}
+	}
+	if src.Selector != "" {
+		(*dst).Selector = src.Selector
+	}
+	if src.Attr != "" {
+		(*dst).Attr = src.Attr
+	}
+	if src.Regexp != "" {
+		(*dst).Regexp = src.Regexp
+	}
+	if src.Template != "" {
+		(*dst).Template = src.Template
+	}
+}
+
+func (cv *converter) version(expr ast.Expr) (*Extractor, error) {
+	name, args, err := cv.call(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Extractor{}
+	switch name {
+	case "v.Regexp", "v.RegexpF":
+		e.Type = "regexp"
+		if name == "v.RegexpF" {
+			if err := cv.args(expr, name, args, 3); err != nil {
+				return nil, err
+			}
+			if e.Format, err = cv.str(args[2]); err != nil {
+				return nil, err
+			}
+		} else if err := cv.args(expr, name, args, 2); err != nil {
+			return nil, err
+		}
+		if e.URL, err = cv.str(args[0]); err != nil {
+			return nil, err
+		}
+		e.Regexp, err = cv.re(args[1])
+	case "v.RegexpMax":
+		e.Type = "regexp-max"
+		if err := cv.args(expr, name, args, 4); err != nil {
+			return nil, err
+		}
+		if e.URL, err = cv.str(args[0]); err != nil {
+			return nil, err
+		}
+		err = cv.all(func() (err error) { e.Regexp, err = cv.re(args[1]); return },
+			func() (err error) { e.Include, err = cv.re(args[2]); return },
+			func() (err error) { e.Exclude, err = cv.re(args[3]); return })
+	case "v.HTML", "v.HTMLF", "v.HTMLMax":
+		e.Type = "html"
+		n := 4
+		if name != "v.HTML" {
+			n = map[string]int{"v.HTMLF": 5, "v.HTMLMax": 6}[name]
+		}
+		if err := cv.args(expr, name, args, n); err != nil {
+			return nil, err
+		}
+		err = cv.all(func() (err error) { e.URL, err = cv.str(args[0]); return },
+			func() (err error) { e.Selector, err = cv.str(args[1]); return },
+			func() (err error) { e.Attr, err = cv.str(args[2]); return },
+			func() (err error) { e.Regexp, err = cv.re(args[3]); return })
+		if err == nil && name == "v.HTMLF" {
+			e.Format, err = cv.str(args[4])
+		}
+		if err == nil && name == "v.HTMLMax" {
+			e.Type = "html-max"
+			err = cv.all(func() (err error) { e.Include, err = cv.re(args[4]); return },
+				func() (err error) { e.Exclude, err = cv.re(args[5]); return })
+		}
+	case "v.GitHubRelease", "v.GitHubReleaseF", "v.GitHubTag", "v.GitHubTagF":
+		e.Type = map[string]string{"v.GitHubRelease": "github-release", "v.GitHubReleaseF": "github-release", "v.GitHubTag": "github-tag", "v.GitHubTagF": "github-tag"}[name]
+		n := 2
+		if name == "v.GitHubReleaseF" || name == "v.GitHubTagF" {
+			n = 3
+		}
+		if err := cv.args(expr, name, args, n); err != nil {
+			return nil, err
+		}
+		err = cv.all(func() (err error) { e.Repo, err = cv.str(args[0]); return },
+			func() (err error) { e.Regexp, err = cv.re(args[1]); return })
+		if err == nil && n == 3 {
+			e.Format, err = cv.str(args[2])
+		}
+	case "v.AppVeyorBranch":
+		e.Type = "appveyor-branch"
+		if err := cv.args(expr, name, args, 2); err != nil {
+			return nil, err
+		}
+		err = cv.all(func() (err error) { e.Repo, err = cv.str(args[0]); return },
+			func() (err error) { e.Branch, err = cv.str(args[1]); return })
+	case "v.Latest":
+		e.Type = "latest"
+		err = cv.args(expr, name, args, 0)
+	case "v.LatestS":
+		e.Type = "latest"
+		if err := cv.args(expr, name, args, 1); err != nil {
+			return nil, err
+		}
+		e.Suffix, err = cv.str(args[0])
+	case "w.ClientOptions":
+		e.Type = "client-options"
+		if err := cv.args(expr, name, args, 2); err != nil {
+			return nil, err
+		}
+		err = cv.all(func() (err error) { e.Client, err = cv.client(args[0]); return },
+			func() (err error) { e.Of, err = cv.version(args[1]); return })
+	case "w.FirstOf", "w.Consensus":
+		e.Type = "first-of"
+		if name == "w.Consensus" {
+			e.Type = "consensus"
+			if len(args) == 0 {
+				return nil, cv.errorf(expr, "%s: missing n", name)
+			}
+			if e.N, err = cv.int(args[0]); err != nil {
+				return nil, err
+			}
+			args = args[1:]
+		}
+		for _, arg := range args {
+			f, err := cv.version(arg)
+			if err != nil {
+				return nil, err
+			}
+			e.All = append(e.All, f)
+		}
+	case "w.UnderscoreToDot":
+		e.Type = "underscore-to-dot"
+		if err := cv.args(expr, name, args, 1); err != nil {
+			return nil, err
+		}
+		e.Of, err = cv.version(args[0])
+	case "w.Timeout":
+		e.Type = "timeout"
+		if err := cv.args(expr, name, args, 2); err != nil {
+			return nil, err
+		}
+		var dur time.Duration
+		if dur, err = cv.duration(args[0]); err != nil {
+			return nil, err
+		}
+		e.Timeout = dur.String()
+		e.Of, err = cv.version(args[1])
+	case "w.Transform":
+		e.Type = "transform"
+		if len(args) == 0 {
+			return nil, cv.errorf(expr, "%s: missing extractor", name)
+		}
+		if e.Of, err = cv.version(args[0]); err != nil {
+			return nil, err
+		}
+		for _, arg := range args[1:] {
+			t, err := cv.transform(arg)
+			if err != nil {
+				return nil, err
+			}
+			e.Transforms = append(e.Transforms, t)
+		}
+	default:
+		return nil, cv.unsupported(expr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (cv *converter) transform(expr ast.Expr) (*Transform, error) {
+	name, args, err := cv.call(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Transform{}
+	switch name {
+	case "w.Replace":
+		t.Type = "replace"
+		if err := cv.args(expr, name, args, 2); err != nil {
+			return nil, err
+		}
+		err = cv.all(func() (err error) { t.Old, err = cv.str(args[0]); return },
+			func() (err error) { t.New, err = cv.str(args[1]); return })
+	case "w.ReplaceRe":
+		t.Type = "replace-re"
+		if err := cv.args(expr, name, args, 2); err != nil {
+			return nil, err
+		}
+		err = cv.all(func() (err error) { t.Regexp, err = cv.re(args[0]); return },
+			func() (err error) { t.New, err = cv.str(args[1]); return })
+	case "w.TrimPrefix", "w.TrimSuffix":
+		t.Type = map[string]string{"w.TrimPrefix": "trim-prefix", "w.TrimSuffix": "trim-suffix"}[name]
+		if err := cv.args(expr, name, args, 1); err != nil {
+			return nil, err
+		}
+		t.Value, err = cv.str(args[0])
+	case "w.FirstN", "w.PadN":
+		t.Type = map[string]string{"w.FirstN": "first-n", "w.PadN": "pad-n"}[name]
+		if err := cv.args(expr, name, args, 1); err != nil {
+			return nil, err
+		}
+		t.N, err = cv.int(args[0])
+	case "w.Lower":
+		t.Type = "lower"
+		err = cv.args(expr, name, args, 0)
+	case "w.Map":
+		t.Type = "map"
+		if err := cv.args(expr, name, args, 1); err != nil {
+			return nil, err
+		}
+		lit, ok := args[0].(*ast.CompositeLit)
+		if !ok {
+			return nil, cv.unsupported(args[0])
+		}
+		t.Map = map[string]string{}
+		for _, el := range lit.Elts {
+			kv, ok := el.(*ast.KeyValueExpr)
+			if !ok {
+				return nil, cv.unsupported(el)
+			}
+			k, err := cv.str(kv.Key)
+			if err != nil {
+				return nil, err
+			}
+			if t.Map[k], err = cv.str(kv.Value); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, cv.unsupported(expr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (cv *converter) download(expr ast.Expr) (*Extractor, error) {
+	name, args, err := cv.call(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Extractor{}
+	re := func(a *Arch, val string) { a.Regexp = val }
+	switch name {
+	case "d.Regexp", "d.RegexpF":
+		e.Type = "regexp"
+		n := 3
+		if name == "d.RegexpF" {
+			n = 4
+		}
+		if args, e.ARM64, err = cv.arm64(expr, name, args, n); err != nil {
+			return nil, err
+		}
+		if e.URL, err = cv.str(args[0]); err != nil {
+			return nil, err
+		}
+		if e.X86, e.X86_64, _, err = cv.archs(args[1:3], cv.re, re); err == nil && n == 4 {
+			e.Format, err = cv.str(args[3])
+		}
+	case "d.HTML", "d.HTMLF":
+		e.Type = "html"
+		n := 7
+		if name == "d.HTMLF" {
+			n = 8
+		}
+		if args, e.ARM64, err = cv.arm64(expr, name, args, n); err != nil {
+			return nil, err
+		}
+		if e.URL, err = cv.str(args[0]); err != nil {
+			return nil, err
+		}
+		for _, group := range []struct {
+			args []ast.Expr
+			fn   func(ast.Expr) (string, error)
+			set  func(a *Arch, val string)
+		}{
+			{args[1:3], cv.str, func(a *Arch, val string) { a.Selector = val }},
+			{args[3:5], cv.str, func(a *Arch, val string) { a.Attr = val }},
+			{args[5:7], cv.re, re},
+		} {
+			x86, x64, _, err := cv.archs(group.args, group.fn, group.set)
+			if err != nil {
+				return nil, err
+			}
+			mergeArchs(&e.X86, x86)
+			mergeArchs(&e.X86_64, x64)
+		}
+		// attributes or regexps for architectures without a selector are unused
+		for _, a := range []**Arch{&e.X86, &e.X86_64, &e.ARM64} {
+			if *a != nil && (*a).Selector == "" {
+				*a = nil
+			}
+		}
+		if n == 8 {
+			e.Format, err = cv.str(args[7])
+		}
+	case "d.HTMLA":
+		e.Type = "html-a"
+		if args, e.ARM64, err = cv.arm64(expr, name, args, 3); err != nil {
+			return nil, err
+		}
+		if e.URL, err = cv.str(args[0]); err != nil {
+			return nil, err
+		}
+		e.X86, e.X86_64, _, err = cv.archs(args[1:3], cv.str, func(a *Arch, val string) { a.Selector = val })
+	case "d.Template":
+		e.Type = "template"
+		if args, e.ARM64, err = cv.arm64(expr, name, args, 2); err != nil {
+			return nil, err
+		}
+		e.X86, e.X86_64, _, err = cv.archs(args, cv.str, func(a *Arch, val string) { a.Template = val })
+	case "d.GitHubRelease", "d.AppVeyorArtifacts":
+		e.Type = map[string]string{"d.GitHubRelease": "github-release", "d.AppVeyorArtifacts": "appveyor-artifacts"}[name]
+		if args, e.ARM64, err = cv.arm64(expr, name, args, 3); err != nil {
+			return nil, err
+		}
+		if e.Repo, err = cv.str(args[0]); err != nil {
+			return nil, err
+		}
+		e.X86, e.X86_64, _, err = cv.archs(args[1:3], cv.re, re)
+	case "w.ClientOptionsDownloads":
+		e.Type = "client-options"
+		if err := cv.args(expr, name, args, 2); err != nil {
+			return nil, err
+		}
+		err = cv.all(func() (err error) { e.Client, err = cv.client(args[0]); return },
+			func() (err error) { e.Of, err = cv.download(args[1]); return })
+	case "w.FirstOfDownloads", "w.ConsensusDownloads":
+		e.Type = "first-of"
+		if name == "w.ConsensusDownloads" {
+			e.Type = "consensus"
+			if len(args) == 0 {
+				return nil, cv.errorf(expr, "%s: missing n", name)
+			}
+			if e.N, err = cv.int(args[0]); err != nil {
+				return nil, err
+			}
+			args = args[1:]
+		}
+		for _, arg := range args {
+			f, err := cv.download(arg)
+			if err != nil {
+				return nil, err
+			}
+			e.All = append(e.All, f)
+		}
+	case "w.AppendToURL":
+		e.Type = "append-to-url"
+		if err := cv.args(expr, name, args, 2); err != nil {
+			return nil, err
+		}
+		err = cv.all(func() (err error) { e.Suffix, err = cv.str(args[0]); return },
+			func() (err error) { e.Of, err = cv.download(args[1]); return })
+	case "w.SplitDownload":
+		e.Type = "split"
+		if len(args) != 3 {
+			if err := cv.args(expr, name, args, 2); err != nil {
+				return nil, err
+			}
+		}
+		for i, a := range []**Arch{&e.X86, &e.X86_64, &e.ARM64}[:len(args)] {
+			if id, ok := args[i].(*ast.Ident); ok && id.Name == "nil" {
+				continue
+			}
+			f, err := cv.download(args[i])
+			if err != nil {
+				return nil, err
+			}
+			*a = &Arch{Extractor: f}
+		}
+	case "w.TimeoutDownloads":
+		e.Type = "timeout"
+		if err := cv.args(expr, name, args, 2); err != nil {
+			return nil, err
+		}
+		var dur time.Duration
+		if dur, err = cv.duration(args[0]); err != nil {
+			return nil, err
+		}
+		e.Timeout = dur.String()
+		e.Of, err = cv.download(args[1])
+	default:
+		return nil, cv.unsupported(expr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// all runs functions until one returns an error.
+func (cv *converter) all(fns ...func() error) error {
+	for _, fn := range fns {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package declarative
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/just-install/just-install-updater-go/jiup/rules/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert(t *testing.T) {
+	src := `package rules
+
+func init() {
+	Rule("split",
+		w.Transform(
+			w.ClientOptions(h.ClientOptions{Insecure: true, Timeout: time.Second * 30}, v.GitHubTagF("a/b", h.Re("v(?P<v>.+)"), "{{.v}}")),
+			w.TrimPrefix("v"),
+			w.Map(map[string]string{"x": "1"}),
+		),
+		w.SplitDownload(
+			d.HTMLA("https://example.com/"+"x86", "a.x86", ""),
+			nil,
+			d.HTML("https://example.com", "", "", "href", "href", nil, nil, d.ARM64{Selector: "a", Attr: "data-href", FileRe: h.Re("(arm64)")}),
+		),
+	)
+	Rule("custom",
+		v.Latest(),
+		c.DownloadsExtractorFunc(func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+			return nil, nil
+		}),
+	)
+	Rule("unknown", v.Latest(), d.Unknown())
+	NoVersionCheck("split")
+	for _, e := range []string{"a", "b"} {
+		Rule("loop-"+e, v.Latest(), d.Unknown())
+	}
+}
+`
+	converted, skipped, err := Convert("rules.go", []byte(src))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.EqualError(t, skipped["custom"], "line 18: custom functions can't be converted")
+	assert.EqualError(t, skipped["unknown"], "line 22: unsupported expression d.Unknown()")
+	assert.EqualError(t, skipped[`"loop-" + e`], "line 25: package name is not a constant string")
+
+	if assert.Len(t, converted, 1) {
+		buf, err := json.Marshal(converted[0])
+		if assert.NoError(t, err) {
+			assert.JSONEq(t, `{
+				"package": "split",
+				"version": {
+					"type": "transform",
+					"of": {
+						"type": "client-options",
+						"client": {"timeout": "30s", "insecure": true},
+						"of": {"type": "github-tag", "repo": "a/b", "regexp": "v(?P<v>.+)", "format": "{{.v}}"}
+					},
+					"transforms": [
+						{"type": "trim-prefix", "value": "v"},
+						{"type": "map", "map": {"x": "1"}}
+					]
+				},
+				"download": {
+					"type": "split",
+					"x86": {"extractor": {"type": "html-a", "url": "https://example.com/x86", "x86": {"selector": "a.x86"}}},
+					"arm64": {"extractor": {"type": "html", "url": "https://example.com", "arm64": {"selector": "a", "attr": "data-href", "regexp": "(arm64)"}}}
+				},
+				"noVersionCheck": true
+			}`, string(buf))
+		}
+		assert.NoError(t, converted[0].Validate())
+	}
+}
+
+func TestConvertRules(t *testing.T) {
+	src, err := ioutil.ReadFile(filepath.Join("..", "rules.go"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	converted, skipped, err := Convert("rules.go", src)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, len(converted) > len(skipped)*10, "most rules should be converted (%d converted, %d skipped)", len(converted), len(skipped))
+
+	for _, r := range converted {
+		buf, err := json.Marshal(r)
+		if !assert.NoError(t, err, r.Package) {
+			continue
+		}
+		rs, err := Parse(r.Package+".json", buf)
+		if !assert.NoError(t, err, r.Package) {
+			continue
+		}
+		rs[0].File = ""
+		assert.Equal(t, r, rs[0], "rule should round-trip through json")
+
+		v, d, err := r.Build()
+		if !assert.NoError(t, err, r.Package) {
+			continue
+		}
+
+		// the converted rules should behave the same as the go rules
+		dir := filepath.Join("..", "testdata", "fixtures", r.Package)
+		if fixture.Exists(dir) {
+			assert.NoError(t, fixture.Test(context.Background(), dir, v, d), r.Package)
+		}
+	}
+}
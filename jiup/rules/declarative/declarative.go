@@ -0,0 +1,289 @@
+// Package declarative loads rules defined in JSON files, so rules can be added or
+// fixed without rebuilding. Each file contains a rule or an array of rules, where the
+// extractors mirror the v, d and w functions (see the README for the types):
+//
+//	{
+//	  "package": "7zip",
+//	  "version": {
+//	    "type": "regexp",
+//	    "url": "https://7-zip.org/download.html",
+//	    "regexp": "Download 7-Zip ([0-9][0-9].[0-9][0-9])"
+//	  },
+//	  "download": {
+//	    "type": "template",
+//	    "x86": {"template": "https://www.7-zip.org/a/7z{{.VersionN}}.msi"},
+//	    "x86_64": {"template": "https://www.7-zip.org/a/7z{{.VersionN}}-x64.msi"}
+//	  }
+//	}
+//
+// Rules loaded from files replace Go rules for the same package.
+package declarative
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/just-install/just-install-updater-go/jiup/rules"
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+)
+
+// Rule is a rule definition.
+type Rule struct {
+	Package  string     `json:"package"`
+	Version  *Extractor `json:"version"`
+	Download *Extractor `json:"download"`
+	// NoVersionCheck disables checking that the links contain the version (see
+	// rules.RuleSet.NoVersionCheck).
+	NoVersionCheck bool `json:"noVersionCheck,omitempty"`
+
+	// File is the file the rule was loaded from, if any.
+	File string `json:"-"`
+}
+
+// Extractor is a version or download extractor. The fields which can be used depend
+// on the type.
+type Extractor struct {
+	Type     string `json:"type"`
+	URL      string `json:"url,omitempty"`
+	Repo     string `json:"repo,omitempty"`
+	Branch   string `json:"branch,omitempty"`
+	Selector string `json:"selector,omitempty"`
+	Attr     string `json:"attr,omitempty"`
+	Regexp   string `json:"regexp,omitempty"`
+	Include  string `json:"include,omitempty"`
+	Exclude  string `json:"exclude,omitempty"`
+	Format   string `json:"format,omitempty"`
+	Suffix   string `json:"suffix,omitempty"`
+	N        int    `json:"n,omitempty"`
+	// Timeout is a duration (e.g. 30s).
+	Timeout    string         `json:"timeout,omitempty"`
+	Client     *ClientOptions `json:"client,omitempty"`
+	X86        *Arch          `json:"x86,omitempty"`
+	X86_64     *Arch          `json:"x86_64,omitempty"`
+	ARM64      *Arch          `json:"arm64,omitempty"`
+	Of         *Extractor     `json:"of,omitempty"`
+	All        []*Extractor   `json:"all,omitempty"`
+	Transforms []*Transform   `json:"transforms,omitempty"`
+}
+
+// Arch contains the parameters of a download extractor for an architecture.
+type Arch struct {
+	Selector  string     `json:"selector,omitempty"`
+	Attr      string     `json:"attr,omitempty"`
+	Regexp    string     `json:"regexp,omitempty"`
+	Template  string     `json:"template,omitempty"`
+	Extractor *Extractor `json:"extractor,omitempty"`
+}
+
+// ClientOptions are the options for the HTTP client (see h.ClientOptions).
+type ClientOptions struct {
+	Timeout   string   `json:"timeout,omitempty"`
+	Insecure  bool     `json:"insecure,omitempty"`
+	RootCAs   string   `json:"rootCAs,omitempty"`
+	Pins      []string `json:"pins,omitempty"`
+	UserAgent string   `json:"userAgent,omitempty"`
+}
+
+// Transform is a version transform (see w.Transform).
+type Transform struct {
+	Type   string            `json:"type"`
+	Old    string            `json:"old,omitempty"`
+	New    string            `json:"new,omitempty"`
+	Regexp string            `json:"regexp,omitempty"`
+	Value  string            `json:"value,omitempty"`
+	N      int               `json:"n,omitempty"`
+	Map    map[string]string `json:"map,omitempty"`
+}
+
+// Error is an error in a rule definition.
+type Error struct {
+	File    string
+	Package string
+	// Path is the path to the invalid field (e.g. version.all[1].regexp).
+	Path string
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File + ": ")
+	}
+	if e.Package != "" {
+		b.WriteString(e.Package + ": ")
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path + ": ")
+	}
+	b.WriteString(e.Msg)
+	return b.String()
+}
+
+// Errors contains all of the errors in a set of rule definitions.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Parse parses a file containing a rule or an array of rules. The rules are not
+// validated (see Validate).
+func Parse(fn string, buf []byte) ([]*Rule, error) {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.DisallowUnknownFields()
+
+	var rs []*Rule
+	var err error
+	if trimmed := bytes.TrimSpace(buf); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = dec.Decode(&rs)
+	} else {
+		var r Rule
+		err = dec.Decode(&r)
+		rs = []*Rule{&r}
+	}
+	if err != nil {
+		return nil, Errors{{File: fn, Msg: jsonError(buf, err)}}
+	}
+	var errs Errors
+	for i, r := range rs {
+		if r == nil {
+			errs = append(errs, &Error{File: fn, Path: fmt.Sprintf("[%d]", i), Msg: "rule must be an object, not null"})
+			continue
+		}
+		r.File = fn
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return rs, nil
+}
+
+// jsonError formats a JSON error with the line and column.
+func jsonError(buf []byte, err error) string {
+	var offset int64
+	switch err := err.(type) {
+	case *json.SyntaxError:
+		offset = err.Offset
+	case *json.UnmarshalTypeError:
+		offset = err.Offset
+		if err.Field != "" {
+			return fmt.Sprintf("line %d: %s must be a %s, not a %s", lineOf(buf, offset), err.Field, err.Type, err.Value)
+		}
+	default:
+		return err.Error()
+	}
+	return fmt.Sprintf("line %d: %v", lineOf(buf, offset), err)
+}
+
+func lineOf(buf []byte, offset int64) int {
+	if offset > int64(len(buf)) {
+		offset = int64(len(buf))
+	}
+	return bytes.Count(buf[:offset], []byte("\n")) + 1
+}
+
+// LoadDir parses and validates the rules in all of the .json files in a directory.
+// All errors are returned as Errors.
+func LoadDir(dir string) ([]*Rule, error) {
+	fns, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(fns)
+
+	var errs Errors
+	all := []*Rule{}
+	seen := map[string]string{}
+	for _, fn := range fns {
+		buf, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return nil, err
+		}
+		rs, err := Parse(fn, buf)
+		if err != nil {
+			errs = append(errs, err.(Errors)...)
+			continue
+		}
+		for _, r := range rs {
+			if prev, ok := seen[r.Package]; ok && r.Package != "" {
+				errs = append(errs, &Error{File: fn, Package: r.Package, Msg: "already defined in " + prev})
+				continue
+			}
+			seen[r.Package] = fn
+			all = append(all, r)
+		}
+	}
+
+	for _, r := range all {
+		if err := r.Validate(); err != nil {
+			errs = append(errs, err.(Errors)...)
+		}
+	}
+	if len(errs) != 0 {
+		return nil, errs
+	}
+	return all, nil
+}
+
+// Register adds rules to a set, replacing any existing rules for the same packages
+// (see rules.RuleSet.Override). Nothing is added if any rule is invalid.
+func Register(set *rules.RuleSet, rs []*Rule) error {
+	var errs Errors
+	built := make([]*builder, len(rs))
+	for i, r := range rs {
+		built[i] = &builder{rule: r}
+		built[i].build()
+		errs = append(errs, built[i].errs...)
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	for _, b := range built {
+		set.Override(b.rule.Package, b.rule.File, b.v, b.d)
+		if b.rule.NoVersionCheck {
+			set.NoVersionCheck(b.rule.Package)
+		}
+	}
+	return nil
+}
+
+// Load loads the rules in a directory (see LoadDir) into a new set, which can be merged
+// into another one to replace its rules for the same packages (e.g.
+// rules.Default.Merge).
+func Load(dir string) (*rules.RuleSet, error) {
+	rs, err := LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	set := rules.NewRuleSet()
+	if err := Register(set, rs); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// Build validates a rule and returns the extractors without registering it. All
+// errors are returned as Errors.
+func (r *Rule) Build() (c.VersionExtractor, c.DownloadExtractor, error) {
+	b := &builder{rule: r}
+	b.build()
+	if len(b.errs) != 0 {
+		return nil, nil, b.errs
+	}
+	return b.v, b.d, nil
+}
+
+// Validate checks that a rule is valid. All errors are returned as Errors.
+func (r *Rule) Validate() error {
+	_, _, err := r.Build()
+	return err
+}
@@ -0,0 +1,155 @@
+package declarative
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/just-install/just-install-updater-go/jiup/rules"
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		json string
+		errs []string
+	}{
+		{"syntax", "{\n\"package\": \"test\",\n}", []string{"test.json: line 3: invalid character '}' looking for beginning of object key string"}},
+		{"field type", `{"package": 1}`, []string{"test.json: line 1: package must be a string, not a number"}},
+		{"unknown field", `{"package": "test", "versoin": {}}`, []string{`test.json: json: unknown field "versoin"`}},
+		{"null", `[null]`, []string{"test.json: [0]: rule must be an object, not null"}},
+		{"missing", `{}`, []string{
+			"test.json: package: required",
+			"test.json: version: required",
+			"test.json: download: required",
+		}},
+		{"unknown type", `{"package": "test", "version": {"type": "regex"}, "download": {}}`, []string{
+			`test.json: test: version.type: unknown version extractor type "regex" (expected one of appveyor-branch, client-options, consensus, first-of, github-release, github-tag, html, html-max, latest, regexp, regexp-max, timeout, transform, underscore-to-dot)`,
+			"test.json: test: download.type: required (one of append-to-url, appveyor-artifacts, client-options, consensus, first-of, github-release, html, html-a, regexp, split, template, timeout)",
+		}},
+		{"fields", `{
+			"package": "test",
+			"version": {"type": "first-of", "all": [
+				{"type": "regexp", "url": "https://example.com", "regexp": "([0-9"},
+				{"type": "regexp", "selector": "a", "regexp": "x"},
+				{"type": "transform", "of": {"type": "latest"}, "transforms": [{"type": "first-n"}]}
+			]},
+			"download": {"type": "html", "url": "https://example.com", "x86": {"selector": "a", "template": "x"}, "arm64": {"extractor": {"type": "template"}}}
+		}`, []string{
+			"test.json: test: version.all[0].regexp: invalid regexp: error parsing regexp: missing closing ]: `[0-9`",
+			"test.json: test: version.all[1].selector: not used by regexp version extractor",
+			"test.json: test: version.all[1].url: required",
+			"test.json: test: version.all[2].transforms[0].n: must be at least 1",
+			"test.json: test: download.x86.template: not used by html download extractor",
+			"test.json: test: download.arm64.extractor: not used by html download extractor",
+			"test.json: test: download.x86.attr: required",
+			"test.json: test: download.arm64.selector: required",
+			"test.json: test: download.arm64.attr: required",
+		}},
+		{"split", `{
+			"package": "test",
+			"version": {"type": "consensus", "n": 3, "all": [{"type": "latest"}]},
+			"download": {"type": "split", "x86": {"extractor": {"type": "template"}}}
+		}`, []string{
+			"test.json: test: version.n: must be between 1 and the number of extractors (1)",
+			"test.json: test: download.x86.extractor: at least one of x86, x86_64 and arm64 must be set",
+		}},
+		{"lint", `{
+			"package": "test",
+			"version": {"type": "html", "url": "https://example.com", "selector": "a", "attr": "href", "regexp": "[0-9.]+"},
+			"download": {"type": "template", "x86": {"template": "https://example.com/setup.exe"}}
+		}`, []string{
+			`test.json: test: version > v.HTML: regexp "[0-9.]+" has no capture group`,
+			`test.json: test: download > d.Template: x86Template "https://example.com/setup.exe" doesn't use the version`,
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rs, err := Parse("test.json", []byte(tc.json))
+			if err == nil {
+				assert.Len(t, rs, 1)
+				err = rs[0].Validate()
+			}
+			if assert.Error(t, err) && assert.IsType(t, Errors{}, err) {
+				msgs := []string{}
+				for _, e := range err.(Errors) {
+					msgs = append(msgs, e.Error())
+				}
+				assert.Equal(t, tc.errs, msgs)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jiup-rules")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, s string) {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(s), 0644))
+	}
+	write("a.json", `{
+		"package": "declarative-test",
+		"version": {"type": "transform", "of": {"type": "latest", "suffix": "-1_2"}, "transforms": [{"type": "replace", "old": "_", "new": "."}, {"type": "trim-prefix", "value": "latest-"}]},
+		"download": {"type": "template", "x86": {"template": "https://example.com/{{.Version}}/setup.exe"}},
+		"noVersionCheck": true
+	}`)
+	write("b.json", `[{
+		"package": "7zip",
+		"version": {"type": "latest"},
+		"download": {"type": "append-to-url", "suffix": "?x", "of": {"type": "template", "x86_64": {"template": "https://example.com/7zip.msi"}}}
+	}]`)
+	write("ignored.txt", "not a rule")
+
+	set, err := Load(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"7zip", "declarative-test"}, set.List())
+
+	r, ok := set.Get("declarative-test")
+	if assert.True(t, ok) {
+		assert.Equal(t, filepath.Join(dir, "a.json"), r.Source)
+		vi, err := r.V(context.Background())
+		if assert.NoError(t, err) {
+			assert.Equal(t, "1.2", vi.Version)
+			dls, err := r.D(context.Background(), vi)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "https://example.com/1.2/setup.exe", dls[c.ArchX86].URL)
+				assert.NoError(t, set.CheckVersion("declarative-test", c.VersionInfo{Version: "2.0"}, dls), "version check should be disabled")
+			}
+		}
+	}
+
+	merged := rules.Default.Subset(rules.Default.List()...)
+	merged.Merge(set)
+	assert.Len(t, merged.List(), len(rules.Default.List())+1)
+	for _, tc := range []struct {
+		set *rules.RuleSet
+		url string
+		msg string
+	}{
+		{rules.Default, "https://www.7-zip.org/a/7zlatest-x64.msi", "default set should not be changed"},
+		{merged, "https://example.com/7zip.msi?x", "go rule should be replaced"},
+	} {
+		r, ok := tc.set.Get("7zip")
+		if assert.True(t, ok) {
+			dls, err := r.D(context.Background(), c.VersionInfo{Version: "latest"})
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.url, dls[c.ArchX86_64].URL, tc.msg)
+			}
+		}
+	}
+
+	write("c.json", `{"package": "7zip", "version": {"type": "latest"}, "download": {"type": "regexp"}}`)
+	_, err = Load(dir)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), filepath.Join(dir, "c.json")+": 7zip: already defined in "+filepath.Join(dir, "b.json"))
+	}
+}
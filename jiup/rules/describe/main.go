@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/just-install/just-install-updater-go/jiup/rules"
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/just-install/just-install-updater-go/jiup/rules/declarative"
+	"github.com/spf13/pflag"
+)
+
+type description struct {
+	Package  string        `json:"package"`
+	Version  *c.Descriptor `json:"version"`
+	Download *c.Descriptor `json:"download"`
+}
+
+func main() {
+	host := pflag.String("host", "", "Only show rules which request or link to a host or its subdomains (e.g. sourceforge.net)")
+	kind := pflag.String("kind", "", "Only show rules which use an extractor (e.g. v.GitHubRelease, or custom)")
+	jsonOut := pflag.Bool("json", false, "Output the descriptors as JSON")
+	rulesDir := pflag.String("rules-dir", "", "If set, rules will also be loaded from the JSON files in the specified directory, replacing the built-in rules for the same packages")
+	help := pflag.Bool("help", false, "Show this help text")
+	pflag.Parse()
+
+	if *help {
+		helpExit()
+	}
+
+	if *rulesDir != "" {
+		set, err := declarative.Load(*rulesDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading rules:\n%v\n", err)
+			os.Exit(1)
+		}
+		rules.Default.Merge(set)
+	}
+
+	pkgs := pflag.Args()
+	if len(pkgs) == 0 {
+		pkgs = rules.Default.List()
+	}
+	sort.Strings(pkgs)
+
+	descs := []description{}
+	for _, p := range pkgs {
+		r, ok := rules.Default.Get(p)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Warning: no rule for %s\n", p)
+			continue
+		}
+		if *host != "" && !hasHost(*host, r.VDesc, r.DDesc) {
+			continue
+		}
+		if *kind != "" && !hasKind(*kind, r.VDesc, r.DDesc) {
+			continue
+		}
+		descs = append(descs, description{p, r.VDesc, r.DDesc})
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(descs); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	for _, desc := range descs {
+		fmt.Printf("%s\n  version:  %s\n  download: %s\n", desc.Package, desc.Version, desc.Download)
+	}
+	fmt.Printf("\n%d rules\n", len(descs))
+	os.Exit(0)
+}
+
+func helpExit() {
+	fmt.Fprintf(os.Stderr, "Usage: describe [options] [packages...]\n\n")
+	pflag.PrintDefaults()
+	os.Exit(1)
+}
+
+func hasHost(host string, descs ...*c.Descriptor) bool {
+	for _, desc := range descs {
+		for _, h := range desc.Hosts() {
+			if h == host || strings.HasSuffix(h, "."+host) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasKind(kind string, descs ...*c.Descriptor) bool {
+	found := false
+	for _, desc := range descs {
+		desc.Walk(func(d *c.Descriptor) {
+			found = found || d.Kind == kind
+		})
+	}
+	return found
+}
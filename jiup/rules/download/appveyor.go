@@ -1,8 +1,10 @@
 package d
 
 import (
+	"context"
 	"net/http"
 	"net/url"
+	"path"
 	"regexp"
 
 	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
@@ -10,63 +12,84 @@ import (
 )
 
 // AppVeyorArtifacts returns a download extractor for a AppVeyor artifacts
-// with a deployment name matching the provided regexps. Either regexp can be nil.
+// with a deployment name matching the provided regexps. Any of the regexps can be nil.
+// The file name and size are included in the result.
 //
 // The version passed to the extractor must be a valid AppVeyor build version.
-func AppVeyorArtifacts(repo string, x86FileRe, x64FileRe *regexp.Regexp) c.DownloadExtractorFunc {
-	return func(version string) (*string, *string, error) {
-		var build struct {
-			Build struct {
-				Jobs []struct {
-					JobID string `json:"jobId"`
+func AppVeyorArtifacts(repo string, x86FileRe, x64FileRe *regexp.Regexp, arm64 ...ARM64) c.DescribedDownloads {
+	arm64FileRe := arm64Params(arm64).FileRe
+	return c.DescribedDownloads{
+		Desc: c.NewDescriptor("d.AppVeyorArtifacts", "repo", repo, "url", h.DefaultBaseURLs.AppVeyor+"/api/projects/"+repo, "x86Regexp", x86FileRe, "x64Regexp", x64FileRe, "arm64Regexp", arm64FileRe),
+		F: func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+			var build struct {
+				Build struct {
+					Jobs []struct {
+						JobID string `json:"jobId"`
+					}
 				}
 			}
-		}
 
-		if err := h.GetJSON(
-			nil,
-			"https://ci.appveyor.com/api/projects/"+repo+"/build/"+url.PathEscape(version),
-			map[string]string{"Accept": "application/json"},
-			[]int{http.StatusOK},
-			&build,
-		); err != nil {
-			return nil, nil, err
-		}
-
-		var x86, x64 *string
-		for _, job := range build.Build.Jobs {
-			var artifacts []struct {
-				FileName string `json:"fileName"`
-				Name     string
-			}
-
-			if job.JobID == "" {
-				continue
-			} else if err := h.GetJSON(
+			if err := h.GetJSON(
+				ctx,
 				nil,
-				"https://ci.appveyor.com/api/buildjobs/"+url.PathEscape(job.JobID)+"/artifacts",
+				h.AppVeyorURL(ctx, "api/projects/"+repo+"/build/"+url.PathEscape(version.Version)),
 				map[string]string{"Accept": "application/json"},
 				[]int{http.StatusOK},
-				&artifacts,
+				&build,
 			); err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 
-			for _, artifact := range artifacts {
-				if x86FileRe != nil && x86 == nil && x86FileRe.MatchString(artifact.Name) {
-					u := "https://ci.appveyor.com/api/buildjobs/" + url.PathEscape(job.JobID) + "/artifacts/" + url.PathEscape(artifact.FileName)
-					x86 = &u
+			res := map[c.Arch]*regexp.Regexp{
+				c.ArchX86:    x86FileRe,
+				c.ArchX86_64: x64FileRe,
+				c.ArchARM64:  arm64FileRe,
+			}
+
+			dls := c.Downloads{}
+			for _, job := range build.Build.Jobs {
+				var artifacts []struct {
+					FileName string `json:"fileName"`
+					Name     string
+					Size     int64 `json:"size"`
 				}
-				if x64FileRe != nil && x64 == nil && x64FileRe.MatchString(artifact.Name) {
-					u := "https://ci.appveyor.com/api/buildjobs/" + url.PathEscape(job.JobID) + "/artifacts/" + url.PathEscape(artifact.FileName)
-					x64 = &u
+
+				if job.JobID == "" {
+					continue
+				} else if err := h.GetJSON(
+					ctx,
+					nil,
+					h.AppVeyorURL(ctx, "api/buildjobs/"+url.PathEscape(job.JobID)+"/artifacts"),
+					map[string]string{"Accept": "application/json"},
+					[]int{http.StatusOK},
+					&artifacts,
+				); err != nil {
+					return nil, err
+				}
+
+				for _, artifact := range artifacts {
+					for arch, re := range res {
+						if re != nil && dls[arch] == nil && re.MatchString(artifact.Name) {
+							dls[arch] = &c.Download{
+								URL:      h.AppVeyorURL(ctx, "api/buildjobs/"+url.PathEscape(job.JobID)+"/artifacts/"+url.PathEscape(artifact.FileName)),
+								FileName: path.Base(artifact.FileName),
+								Size:     artifact.Size,
+							}
+						}
+					}
 				}
-			}
 
-			if (x86 != nil || x86FileRe == nil) && (x64 != nil || x64FileRe == nil) {
-				break
+				done := true
+				for arch, re := range res {
+					if re != nil && dls[arch] == nil {
+						done = false
+					}
+				}
+				if done {
+					break
+				}
 			}
-		}
-		return x86, x64, nil
+			return dls, nil
+		},
 	}
 }
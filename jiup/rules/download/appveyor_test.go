@@ -0,0 +1,53 @@
+package d
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/just-install/just-install-updater-go/jiup/rules/fakes"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppVeyorArtifacts(t *testing.T) {
+	build := fakes.Build{Version: "1.0.45", Jobs: []fakes.Job{
+		{JobID: "job1", Artifacts: []fakes.Artifact{{FileName: "out/app-x86.exe", Name: "app-x86", Size: 10}}},
+		{JobID: "job2", Artifacts: []fakes.Artifact{{FileName: "out/app-x64.exe", Name: "app-x64", Size: 20}}},
+	}}
+	for _, tc := range []struct {
+		Name    string
+		Version string
+		Prefix  string
+		Fault   *fakes.Fault
+		Err     c.ErrorCategory
+	}{
+		{"ok", "1.0.45", "", nil, ""},
+		{"no such build", "1.0.44", "", nil, c.CategoryHTTPStatus},
+		{"build server error", "1.0.45", "/api/projects/", &fakes.Fault{Status: http.StatusInternalServerError}, c.CategoryHTTPStatus},
+		{"artifacts not found", "1.0.45", "/api/buildjobs/job2/", &fakes.Fault{Status: http.StatusNotFound}, c.CategoryHTTPStatus},
+		{"artifacts malformed", "1.0.45", "/api/buildjobs/", &fakes.Fault{Malformed: true}, c.CategoryParse},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			a := fakes.NewAppVeyor()
+			defer a.Close()
+			a.AddBuild("test/app", "master", build)
+			if tc.Fault != nil {
+				a.SetFault(tc.Prefix, *tc.Fault)
+			}
+
+			dls, err := AppVeyorArtifacts("test/app", h.Re("x86"), h.Re("x64")).Structured()(a.Context(context.Background()), c.VersionInfo{Version: tc.Version})
+			if tc.Err != "" {
+				assert.Equal(t, tc.Err, c.Category(err), "%v", err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, c.Downloads{
+					c.ArchX86:    {URL: a.URL + "/api/buildjobs/job1/artifacts/out%2Fapp-x86.exe", FileName: "app-x86.exe", Size: 10},
+					c.ArchX86_64: {URL: a.URL + "/api/buildjobs/job2/artifacts/out%2Fapp-x64.exe", FileName: "app-x64.exe", Size: 20},
+				}, dls)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package d
+
+import "regexp"
+
+// ARM64 contains the parameters for the arm64 link, which can be passed as the last
+// argument of the download extractors (e.g. d.Template(x86, x64, d.ARM64{Template: ...})).
+// Each extractor only uses the fields it has x86 and x64 parameters for.
+type ARM64 struct {
+	Selector string
+	Attr     string
+	FileRe   *regexp.Regexp
+	Template string
+}
+
+// arm64Params returns the arm64 parameters passed to an extractor, or empty ones.
+func arm64Params(arm64 []ARM64) ARM64 {
+	if len(arm64) == 0 {
+		return ARM64{}
+	}
+	return arm64[0]
+}
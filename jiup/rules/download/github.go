@@ -1,93 +1,102 @@
 package d
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
 	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
 )
 
-// GitHubRelease returns a download extractor for a GitHub release. x64Re can be nil.
-func GitHubRelease(repo string, x86FileRe, x64FileRe *regexp.Regexp) c.DownloadExtractorFunc {
-	return func(_ string) (*string, *string, error) {
-		if x86FileRe == nil && x64FileRe == nil {
-			return nil, nil, errors.New("at least one of x86 and x64 regexps must be defined")
-		}
-
-		// scrape to avoid limit
-		doc, err := h.GetDoc(nil, fmt.Sprintf("https://github.com/%s/releases/latest", repo), map[string]string{}, []int{200})
-		if err != nil {
-			return nil, nil, err
-		}
-
-		files := [][]string{}
-		err = nil
-		doc.Find(".release").First().Find(".Details-element:contains('Assets') .Box a[href][href*='download']").EachWithBreak(func(_ int, s *goquery.Selection) bool {
-			href := strings.TrimSpace(s.AttrOr("href", ""))
-			if href == "" {
-				err = errors.New("could not extract href from release asset")
-				return false
+// GitHubRelease returns a download extractor for a GitHub release. Any of the regexps can be nil, but not all of them.
+// The file name and release date are included in the result.
+func GitHubRelease(repo string, x86FileRe, x64FileRe *regexp.Regexp, arm64 ...ARM64) c.DescribedDownloads {
+	arm64FileRe := arm64Params(arm64).FileRe
+	return c.DescribedDownloads{
+		Desc: c.NewDescriptor("d.GitHubRelease", "repo", repo, "url", h.DefaultBaseURLs.GitHub+"/"+repo+"/releases/latest", "x86Regexp", x86FileRe, "x64Regexp", x64FileRe, "arm64Regexp", arm64FileRe),
+		F: func(ctx context.Context, _ c.VersionInfo) (c.Downloads, error) {
+			if x86FileRe == nil && x64FileRe == nil && arm64FileRe == nil {
+				return nil, c.Errorf(c.CategoryValidation, "", "at least one of x86, x64 and arm64 regexps must be defined")
 			}
-			href, err = h.ResolveURL(fmt.Sprintf("https://github.com/%s/releases/latest", repo), href)
+
+			// scrape to avoid limit
+			url := h.GitHubURL(ctx, repo+"/releases/latest")
+			doc, err := h.GetDoc(ctx, nil, url, map[string]string{}, []int{200})
 			if err != nil {
-				return false
+				return nil, err
 			}
-			spl := strings.Split(href, "/")
-			fname := spl[len(spl)-1]
-			if fname == "" {
-				err = errors.New("could not extract filename from release asset")
-				return false
-			}
-			if strings.HasSuffix(fname, ".sig") {
-				// Skip signature files
-				return true
-			}
-			if strings.HasSuffix(fname, ".sha1") || strings.HasSuffix(fname, ".sha256") || strings.HasSuffix(fname, ".md5") {
-				// Skip sha files
-				return true
+
+			release := doc.Find(".release").First()
+
+			var date time.Time
+			if dt, ok := release.Find("relative-time[datetime]").First().Attr("datetime"); ok {
+				date, _ = time.Parse(time.RFC3339, dt)
 			}
-			files = append(files, []string{href, fname})
-			return true
-		})
-		if err != nil {
-			return nil, nil, err
-		}
-		if len(files) == 0 {
-			return nil, nil, errors.New("could not extract list of assets")
-		}
 
-		var x86dl, x64dl *string
-		if x86FileRe != nil {
-			for _, file := range files {
-				if x86FileRe.MatchString(file[1]) {
-					x86dl = h.StrPtr(file[0])
-					break
+			files := [][]string{}
+			err = nil
+			release.Find(".Details-element:contains('Assets') .Box a[href][href*='download']").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+				href := strings.TrimSpace(s.AttrOr("href", ""))
+				if href == "" {
+					err = c.Errorf(c.CategoryParse, url, "could not extract href from release asset")
+					return false
 				}
+				href, err = h.ResolveURL(url, href)
+				if err != nil {
+					err = c.NewError(c.CategoryParse, url, err)
+					return false
+				}
+				spl := strings.Split(href, "/")
+				fname := spl[len(spl)-1]
+				if fname == "" {
+					err = c.Errorf(c.CategoryParse, url, "could not extract filename from release asset")
+					return false
+				}
+				if strings.HasSuffix(fname, ".sig") {
+					// Skip signature files
+					return true
+				}
+				if strings.HasSuffix(fname, ".sha1") || strings.HasSuffix(fname, ".sha256") || strings.HasSuffix(fname, ".md5") {
+					// Skip sha files
+					return true
+				}
+				files = append(files, []string{href, fname})
+				return true
+			})
+			if err != nil {
+				return nil, err
 			}
-			if x86dl == nil {
-				return nil, nil, errors.New("could not find asset filename match for x86")
+			if len(files) == 0 {
+				return nil, c.Errorf(c.CategorySelector, url, "could not extract list of assets")
 			}
-		}
 
-		if x64FileRe != nil {
-			for _, file := range files {
-				if x64FileRe.MatchString(file[1]) {
-					x64dl = h.StrPtr(file[0])
-					break
+			dls := c.Downloads{}
+			for _, l := range []struct {
+				arch c.Arch
+				re   *regexp.Regexp
+			}{{c.ArchX86, x86FileRe}, {c.ArchX86_64, x64FileRe}, {c.ArchARM64, arm64FileRe}} {
+				if l.re == nil {
+					continue
+				}
+				for _, file := range files {
+					if l.re.MatchString(file[1]) {
+						dls[l.arch] = &c.Download{
+							URL:         file[0],
+							FileName:    file[1],
+							ReleaseDate: date,
+						}
+						break
+					}
+				}
+				if dls[l.arch] == nil {
+					return nil, c.Errorf(c.CategoryRegexp, url, "could not find asset filename match for %s", l.arch).WithArch(l.arch)
 				}
 			}
-			if x64dl == nil {
-				return nil, nil, errors.New("could not find asset filename match for x64")
-			}
-		}
 
-		if x86dl == nil && x64dl == nil {
-			return nil, nil, errors.New("could not find match for x86 or x64")
-		}
-		return x86dl, x64dl, nil
+			return dls, nil
+		},
 	}
 }
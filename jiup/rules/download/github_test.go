@@ -0,0 +1,51 @@
+package d
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/just-install/just-install-updater-go/jiup/rules/fakes"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitHubRelease(t *testing.T) {
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tc := range []struct {
+		Name   string
+		Assets []string
+		Fault  *fakes.Fault
+		Files  map[c.Arch]string
+	}{
+		{"ok", []string{"app-1.0-x86.exe", "app-1.0-x86.exe.sha256", "app-1.0-x64.exe"}, nil, map[c.Arch]string{c.ArchX86: "app-1.0-x86.exe", c.ArchX86_64: "app-1.0-x64.exe"}},
+		{"missing asset", []string{"app-1.0-x86.exe"}, nil, nil},
+		{"no assets", nil, nil, nil},
+		{"not found", []string{"app-1.0-x86.exe", "app-1.0-x64.exe"}, &fakes.Fault{Status: http.StatusNotFound}, nil},
+		{"malformed", []string{"app-1.0-x86.exe", "app-1.0-x64.exe"}, &fakes.Fault{Malformed: true}, nil},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			g := fakes.NewGitHub()
+			defer g.Close()
+			g.SetRelease("test/app", fakes.Release{Tag: "v1.0", Date: date, Assets: tc.Assets})
+			if tc.Fault != nil {
+				g.SetFault("/test/app/releases/latest", *tc.Fault)
+			}
+
+			dls, err := GitHubRelease("test/app", h.Re(`x86\.exe$`), h.Re(`x64\.exe$`)).Structured()(g.Context(context.Background()), c.VersionInfo{Version: "1.0"})
+			if tc.Files == nil {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) && assert.Len(t, dls, len(tc.Files)) {
+				for arch, fn := range tc.Files {
+					assert.Equal(t, g.URL+"/test/app/releases/download/v1.0/"+fn, dls[arch].URL)
+					assert.Equal(t, fn, dls[arch].FileName)
+					assert.Equal(t, date, dls[arch].ReleaseDate)
+				}
+			}
+		})
+	}
+}
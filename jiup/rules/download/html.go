@@ -1,8 +1,7 @@
 package d
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"regexp"
 	"strings"
 
@@ -12,50 +11,71 @@ import (
 )
 
 // HTML returns a download extractor for the first match of a css selector, an attribute (or innerText for the text), and an optional regexp on the url (and resolves the url).
-// If a match fails, the next one (if any) is tried. {{.Version}} is replaced with the current version.
-func HTML(url string, x86Selector, x64Selector, x86Attr, x64Attr string, x86FileRe, x64FileRe *regexp.Regexp) c.DownloadExtractorFunc {
-	return func(version string) (*string, *string, error) {
-		url := strings.Replace(url, "{{.Version}}", version, -1)
+// If a match fails, the next one (if any) is tried. The url is a template (see h.ExecTemplate) rendered with the current version and its fields.
+// The link is the capture group named URL if there is one, or the first capture group.
+func HTML(url string, x86Selector, x64Selector, x86Attr, x64Attr string, x86FileRe, x64FileRe *regexp.Regexp, arm64 ...ARM64) c.DescribedDownloads {
+	return HTMLF(url, x86Selector, x64Selector, x86Attr, x64Attr, x86FileRe, x64FileRe, "", arm64...)
+}
 
-		doc, err := h.GetDoc(nil, url, map[string]string{}, []int{200})
-		if err != nil {
-			return nil, nil, err
-		}
+// HTMLF is like HTML, but assembles the link from the named capture groups of the regexps
+// using a format template (e.g. {{.path}}/{{.file}}).
+func HTMLF(url string, x86Selector, x64Selector, x86Attr, x64Attr string, x86FileRe, x64FileRe *regexp.Regexp, format string, arm64 ...ARM64) c.DescribedDownloads {
+	a := arm64Params(arm64)
+	arm64Selector, arm64Attr, arm64FileRe := a.Selector, a.Attr, a.FileRe
+	return c.DescribedDownloads{
+		Desc: c.NewDescriptor("d.HTML", "url", url, "x86Selector", x86Selector, "x64Selector", x64Selector, "arm64Selector", arm64Selector, "x86Attr", x86Attr, "x64Attr", x64Attr, "arm64Attr", arm64Attr, "x86Regexp", x86FileRe, "x64Regexp", x64FileRe, "arm64Regexp", arm64FileRe, "format", format),
+		F: func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+			url, err := h.ExecTemplate(url, h.VersionTemplateData(version.Version, version.Fields))
+			if err != nil {
+				return nil, c.NewError(c.CategoryValidation, "", err)
+			}
 
-		x86dl, err := doSelector(doc, x86Selector, x86Attr, url, x86FileRe)
-		if err != nil {
-			return nil, nil, fmt.Errorf("could not find x86 link: %v", err)
-		}
+			doc, err := h.GetDoc(ctx, nil, url, map[string]string{}, []int{200})
+			if err != nil {
+				return nil, err
+			}
 
-		x64dl, err := doSelector(doc, x64Selector, x64Attr, url, x64FileRe)
-		if err != nil {
-			return nil, nil, fmt.Errorf("could not find x64 link: %v", err)
-		}
+			x86dl, err := doSelector(doc, x86Selector, x86Attr, url, x86FileRe, format)
+			if err != nil {
+				return nil, c.Errorf(c.Category(err), url, "could not find x86 link: %w", err).WithArch(c.ArchX86)
+			}
 
-		if x86dl == nil && x64dl == nil {
-			return nil, nil, errors.New("at least one of x86 and x64 must exist")
-		}
+			x64dl, err := doSelector(doc, x64Selector, x64Attr, url, x64FileRe, format)
+			if err != nil {
+				return nil, c.Errorf(c.Category(err), url, "could not find x64 link: %w", err).WithArch(c.ArchX86_64)
+			}
+
+			arm64dl, err := doSelector(doc, arm64Selector, arm64Attr, url, arm64FileRe, format)
+			if err != nil {
+				return nil, c.Errorf(c.Category(err), url, "could not find arm64 link: %w", err).WithArch(c.ArchARM64)
+			}
+
+			if x86dl == nil && x64dl == nil && arm64dl == nil {
+				return nil, c.Errorf(c.CategoryValidation, url, "at least one of x86, x64 and arm64 must exist")
+			}
 
-		return x86dl, x64dl, nil
+			return c.NewDownloads(x86dl, x64dl, arm64dl), nil
+		},
 	}
 }
 
 // HTMLA is a shorthand version of HTML for selecting a link with a href attribute without a regex. Leave the x64 selector blank if no 64-bit version.
-func HTMLA(url, x86Selector, x64Selector string) c.DownloadExtractorFunc {
-	return HTML(url, x86Selector, x64Selector, "href", "href", nil, nil)
+func HTMLA(url, x86Selector, x64Selector string, arm64 ...ARM64) c.DescribedDownloads {
+	a := arm64Params(arm64)
+	return HTML(url, x86Selector, x64Selector, "href", "href", nil, nil, ARM64{Selector: a.Selector, Attr: "href"})
 }
 
-func doSelector(doc *goquery.Document, sel, attr, baseURL string, fileRe *regexp.Regexp) (*string, error) {
+func doSelector(doc *goquery.Document, sel, attr, baseURL string, fileRe *regexp.Regexp, format string) (*string, error) {
 	if sel == "" {
 		return nil, nil
 	}
 	if attr == "" {
-		return nil, errors.New("attr must not be empty (use innerText for the contents)")
+		return nil, c.Errorf(c.CategoryValidation, "", "attr must not be empty (use innerText for the contents)")
 	}
 
 	matches := doc.Find(sel)
 	if matches.Length() < 1 {
-		return nil, errors.New("could not find any matches for x86 selector")
+		return nil, c.Errorf(c.CategorySelector, "", "could not find any matches for selector")
 	}
 
 	var a string
@@ -68,22 +88,22 @@ func doSelector(doc *goquery.Document, sel, attr, baseURL string, fileRe *regexp
 		}
 		if a == "" {
 			h, _ := match.Html()
-			err = errors.New("specified attribute for x86 is empty on element: " + h)
+			err = c.Errorf(c.CategorySelector, "", "specified attribute is empty on element: %s", h)
 			return true // see if any other matches don't have an issue
 		}
 		r, erra := h.ResolveURL(baseURL, a)
 		if erra != nil {
-			err = fmt.Errorf("could not resolve url: %v", erra)
+			err = c.Errorf(c.CategoryParse, "", "could not resolve url: %v", erra)
 			return true
 		}
 		a = r
 		if fileRe != nil {
-			m := fileRe.FindStringSubmatch(a)
-			if len(m) != 2 || m[1] == "" {
-				err = errors.New("could not find 2nd match group for x86 link regexp")
+			m, _, ok := h.FindGroups(fileRe, a, "URL", format)
+			if !ok {
+				err = c.Errorf(c.CategoryRegexp, "", "could not find match group for link regexp")
 				return true
 			}
-			a = m[1]
+			a = m
 		}
 		return false
 	})
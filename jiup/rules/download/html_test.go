@@ -0,0 +1,48 @@
+package d
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoSelector(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`
+		<a class="empty" href="">Empty</a>
+		<a class="dl" href="/files/test-1.0-x64.exe?mirror=1">Download</a>
+		<span class="text">https://example.com/dl/test-1.0-x86.exe</span>
+	`))
+	assert.NoError(t, err)
+
+	for _, c := range []struct {
+		Sel    string
+		Attr   string
+		Re     *regexp.Regexp
+		Format string
+		Res    *string
+		Err    bool
+	}{
+		{"", "href", nil, "", nil, false},
+		{"a.dl", "", nil, "", nil, true},
+		{"a.none", "href", nil, "", nil, true},
+		{"a.empty", "href", nil, "", nil, true},
+		{"a.dl", "href", nil, "", h.StrPtr("https://example.com/files/test-1.0-x64.exe?mirror=1"), false},
+		{"span.text", "innerText", nil, "", h.StrPtr("https://example.com/dl/test-1.0-x86.exe"), false},
+		{"a.dl", "href", h.Re("(.+)\\?"), "", h.StrPtr("https://example.com/files/test-1.0-x64.exe"), false},
+		{"a.dl", "href", h.Re("(?P<Base>.+/)(?P<URL>[^/]+)\\?"), "", h.StrPtr("test-1.0-x64.exe"), false},
+		{"a.dl", "href", h.Re("(?P<base>.+)/files/(?P<file>[^/]+)\\?"), "{{.base}}/mirror/{{.file}}", h.StrPtr("https://example.com/mirror/test-1.0-x64.exe"), false},
+		{"a.dl", "href", h.Re("notfound(.+)"), "", nil, true},
+	} {
+		res, err := doSelector(doc, c.Sel, c.Attr, "https://example.com/", c.Re, c.Format)
+		if c.Err {
+			assert.Error(t, err, c.Sel)
+			continue
+		}
+		assert.NoError(t, err, c.Sel)
+		assert.Equal(t, c.Res, res, c.Sel)
+	}
+}
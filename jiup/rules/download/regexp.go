@@ -1,8 +1,7 @@
 package d
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"regexp"
 
 	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
@@ -10,44 +9,50 @@ import (
 )
 
 // Regexp returns a version extractor for the first match of a regex (and resolves the url).
-func Regexp(url string, x86FileRe, x64FileRe *regexp.Regexp) c.DownloadExtractorFunc {
-	return func(_ string) (*string, *string, error) {
-		if x86FileRe == nil && x64FileRe == nil {
-			return nil, nil, errors.New("at least one of x86 and x64 regexps must be defined")
-		}
-
-		buf, code, ok, err := h.GetURL(nil, url, map[string]string{}, []int{200})
-		if err != nil {
-			return nil, nil, err
-		}
-		if !ok {
-			return nil, nil, fmt.Errorf("unexpected response status: %d", code)
-		}
+// The link is the capture group named URL if there is one, or the first capture group.
+func Regexp(url string, x86FileRe, x64FileRe *regexp.Regexp, arm64 ...ARM64) c.DescribedDownloads {
+	return RegexpF(url, x86FileRe, x64FileRe, "", arm64...)
+}
 
-		var x86dl, x64dl *string
-		if x86FileRe != nil {
-			m := x86FileRe.FindStringSubmatch(string(buf))
-			if len(m) != 2 || m[1] == "" {
-				return x86dl, x64dl, errors.New("could not find 2nd match group for x86 download link regexp")
+// RegexpF is like Regexp, but assembles the link from the named capture groups using
+// a format (e.g. {{.path}}/{{.file}}).
+func RegexpF(url string, x86FileRe, x64FileRe *regexp.Regexp, format string, arm64 ...ARM64) c.DescribedDownloads {
+	arm64FileRe := arm64Params(arm64).FileRe
+	return c.DescribedDownloads{
+		Desc: c.NewDescriptor("d.Regexp", "url", url, "x86Regexp", x86FileRe, "x64Regexp", x64FileRe, "arm64Regexp", arm64FileRe, "format", format),
+		F: func(ctx context.Context, _ c.VersionInfo) (c.Downloads, error) {
+			if x86FileRe == nil && x64FileRe == nil && arm64FileRe == nil {
+				return nil, c.Errorf(c.CategoryValidation, "", "at least one of x86, x64 and arm64 regexps must be defined")
 			}
-			x86dls, err := h.ResolveURL(url, m[1])
+
+			buf, code, ok, err := h.GetURL(ctx, nil, url, map[string]string{}, []int{200})
 			if err != nil {
-				return x86dl, x64dl, err
+				return nil, err
 			}
-			x86dl = h.StrPtr(x86dls)
-		}
-		if x64FileRe != nil {
-			m := x64FileRe.FindStringSubmatch(string(buf))
-			if len(m) != 2 || m[1] == "" {
-				return x86dl, x64dl, errors.New("could not find 2nd match group for x86 download link regexp")
+			if !ok {
+				return nil, c.Errorf(c.CategoryHTTPStatus, url, "unexpected response status: %d", code)
 			}
-			x64dls, err := h.ResolveURL(url, m[1])
-			if err != nil {
-				return x86dl, x64dl, err
+
+			dls := c.Downloads{}
+			for _, l := range []struct {
+				arch c.Arch
+				re   *regexp.Regexp
+			}{{c.ArchX86, x86FileRe}, {c.ArchX86_64, x64FileRe}, {c.ArchARM64, arm64FileRe}} {
+				if l.re == nil {
+					continue
+				}
+				m, _, ok := h.FindGroups(l.re, string(buf), "URL", format)
+				if !ok {
+					return nil, c.Errorf(c.CategoryRegexp, url, "could not find match group for %s download link regexp", l.arch).WithArch(l.arch)
+				}
+				dl, err := h.ResolveURL(url, m)
+				if err != nil {
+					return nil, c.NewError(c.CategoryParse, url, err).WithArch(l.arch)
+				}
+				dls[l.arch] = &c.Download{URL: dl}
 			}
-			x64dl = h.StrPtr(x64dls)
-		}
 
-		return x86dl, x64dl, nil
+			return dls, nil
+		},
 	}
 }
@@ -1,37 +1,40 @@
 package d
 
 import (
-	"errors"
-	"strings"
+	"context"
 
 	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
 	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
 )
 
-// Template creates a download link based on substituting {{.Version}}. Leave a template empty if no link for that version.
-func Template(x86Tmpl, x64Tmpl string) c.DownloadExtractorFunc {
-	return func(version string) (*string, *string, error) {
-		if x86Tmpl == "" && x64Tmpl == "" {
-			return nil, nil, errors.New("at least one of x86 and x64 templates must be defined")
-		}
+// Template creates a download link by rendering a template (see h.ExecTemplate) with the version. Leave a template empty
+// if no link for that version. The fields of the version can also be used (e.g. {{.Build}} for a capture group named
+// Build), as can the template functions (e.g. {{major .Version}}.{{minor .Version}}).
+func Template(x86Tmpl, x64Tmpl string, arm64 ...ARM64) c.DescribedDownloads {
+	arm64Tmpl := arm64Params(arm64).Template
+	return c.DescribedDownloads{
+		Desc: c.NewDescriptor("d.Template", "x86Template", x86Tmpl, "x64Template", x64Tmpl, "arm64Template", arm64Tmpl),
+		F: func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+			if x86Tmpl == "" && x64Tmpl == "" && arm64Tmpl == "" {
+				return nil, c.Errorf(c.CategoryValidation, "", "at least one of x86, x64 and arm64 templates must be defined")
+			}
 
-		r := func(i string) string {
-			o := i
-			o = strings.Replace(o, "{{.Version}}", version, -1)
-			o = strings.Replace(o, "{{.VersionU}}", strings.Replace(version, ".", "_", -1), -1)
-			o = strings.Replace(o, "{{.VersionD}}", strings.Replace(version, ".", "-", -1), -1)
-			o = strings.Replace(o, "{{.Version0}}", strings.Split(version, ".")[0], -1)
-			o = strings.Replace(o, "{{.VersionN}}", strings.Replace(version, ".", "", -1), -1)
-			return o
-		}
+			data := h.VersionTemplateData(version.Version, version.Fields)
 
-		var x86dl, x64dl *string
-		if x86Tmpl != "" {
-			x86dl = h.StrPtr(r(x86Tmpl))
-		}
-		if x64Tmpl != "" {
-			x64dl = h.StrPtr(r(x64Tmpl))
-		}
-		return x86dl, x64dl, nil
+			dls := c.Downloads{}
+			for _, l := range []struct {
+				arch c.Arch
+				tmpl string
+			}{{c.ArchX86, x86Tmpl}, {c.ArchX86_64, x64Tmpl}, {c.ArchARM64, arm64Tmpl}} {
+				if l.tmpl != "" {
+					u, err := h.ExecTemplate(l.tmpl, data)
+					if err != nil {
+						return nil, c.NewError(c.CategoryValidation, "", err).WithArch(l.arch)
+					}
+					dls[l.arch] = &c.Download{URL: u}
+				}
+			}
+			return dls, nil
+		},
 	}
 }
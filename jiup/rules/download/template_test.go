@@ -0,0 +1,42 @@
+package d
+
+import (
+	"context"
+	"testing"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		Tmpl    string
+		Version c.VersionInfo
+		Res     string
+	}{
+		{"https://example.com/{{.Version}}.exe", c.VersionInfo{Version: "1.2.3"}, "https://example.com/1.2.3.exe"},
+		{"https://example.com/{{.VersionU}}.exe", c.VersionInfo{Version: "1.2.3"}, "https://example.com/1_2_3.exe"},
+		{"https://example.com/{{.VersionD}}.exe", c.VersionInfo{Version: "1.2.3"}, "https://example.com/1-2-3.exe"},
+		{"https://example.com/{{.Version0}}.exe", c.VersionInfo{Version: "1.2.3"}, "https://example.com/1.exe"},
+		{"https://example.com/{{.VersionN}}.exe", c.VersionInfo{Version: "1.2.3"}, "https://example.com/123.exe"},
+		{"https://example.com/{{.Version}}/ide-{{.Build}}.exe", c.VersionInfo{Version: "3.1.1.0", Fields: map[string]string{"Build": "173.4697961"}}, "https://example.com/3.1.1.0/ide-173.4697961.exe"},
+		{"https://example.com/Blender{{major .Version}}.{{minor .Version}}/blender-{{.Version}}.msi", c.VersionInfo{Version: "2.93.1"}, "https://example.com/Blender2.93/blender-2.93.1.msi"},
+	} {
+		dls, err := Template("", tc.Tmpl).Structured()(context.Background(), tc.Version)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Downloads{c.ArchX86_64: {URL: tc.Res}}, dls)
+	}
+
+	dls, err := Template("https://example.com/{{.Version}}.exe", "", ARM64{Template: "https://example.com/{{.Version}}-arm64.exe"}).Structured()(context.Background(), c.VersionInfo{Version: "1.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, c.Downloads{c.ArchX86: {URL: "https://example.com/1.0.exe"}, c.ArchARM64: {URL: "https://example.com/1.0-arm64.exe"}}, dls)
+
+	_, err = Template("", "").Structured()(context.Background(), c.VersionInfo{Version: "1.0"})
+	assert.Error(t, err)
+
+	_, err = Template("", "https://example.com/{{.Build}}.exe").Structured()(context.Background(), c.VersionInfo{Version: "1.0"})
+	assert.Error(t, err, "missing field")
+
+	_, err = Template("", "https://example.com/{{.Version").Structured()(context.Background(), c.VersionInfo{Version: "1.0"})
+	assert.Error(t, err, "invalid template")
+}
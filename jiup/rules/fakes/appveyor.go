@@ -0,0 +1,135 @@
+package fakes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+)
+
+// Build is an AppVeyor build.
+type Build struct {
+	Version string `json:"version"`
+	Jobs    []Job  `json:"jobs"`
+}
+
+// Job is an AppVeyor build job.
+type Job struct {
+	JobID     string     `json:"jobId"`
+	Artifacts []Artifact `json:"-"`
+}
+
+// Artifact is an AppVeyor build artifact.
+type Artifact struct {
+	FileName string `json:"fileName"`
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+}
+
+// AppVeyor is a fake AppVeyor server. It serves the branch, build and artifacts API
+// and the artifacts for the projects it knows about.
+type AppVeyor struct {
+	*server
+
+	mu       sync.Mutex
+	builds   map[string]Build
+	branches map[string]string
+}
+
+// NewAppVeyor starts a fake AppVeyor server. It should be closed after use.
+func NewAppVeyor() *AppVeyor {
+	a := &AppVeyor{builds: map[string]Build{}, branches: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/projects/", a.serveProject)
+	mux.HandleFunc("/api/buildjobs/", a.serveJob)
+	a.server = newServer(mux)
+	return a
+}
+
+// AddBuild adds a build to a project (account/name), and makes it the latest one for
+// a branch if not empty.
+func (a *AppVeyor) AddBuild(repo, branch string, b Build) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.builds[repo+"/"+b.Version] = b
+	if branch != "" {
+		a.branches[repo+"/"+branch] = b.Version
+	}
+}
+
+// Context returns a context which makes the extractors use the server.
+func (a *AppVeyor) Context(ctx context.Context) context.Context {
+	return h.WithBaseURLs(ctx, h.BaseURLs{AppVeyor: a.URL})
+}
+
+func (a *AppVeyor) serveProject(w http.ResponseWriter, r *http.Request) {
+	// /api/projects/{account}/{name}/{branch|build}/{branch|version}
+	spl := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/api/projects/"), "/", 4)
+	if len(spl) != 4 {
+		http.NotFound(w, r)
+		return
+	}
+	repo := spl[0] + "/" + spl[1]
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	version := spl[3]
+	switch spl[2] {
+	case "branch":
+		var ok bool
+		if version, ok = a.branches[repo+"/"+spl[3]]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+	case "build":
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	b, ok := a.builds[repo+"/"+version]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"build": b})
+}
+
+func (a *AppVeyor) serveJob(w http.ResponseWriter, r *http.Request) {
+	// /api/buildjobs/{id}/artifacts[/{filename}]
+	spl := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/api/buildjobs/"), "/", 3)
+	if len(spl) < 2 || spl[1] != "artifacts" {
+		http.NotFound(w, r)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, b := range a.builds {
+		for _, j := range b.Jobs {
+			if j.JobID != spl[0] {
+				continue
+			}
+			if len(spl) == 2 {
+				artifacts := j.Artifacts
+				if artifacts == nil {
+					artifacts = []Artifact{}
+				}
+				writeJSON(w, artifacts)
+				return
+			}
+			for _, af := range j.Artifacts {
+				if af.FileName == spl[2] {
+					w.Header().Set("Content-Type", "application/octet-stream")
+					w.Write([]byte("fake " + af.FileName))
+					return
+				}
+			}
+		}
+	}
+	http.NotFound(w, r)
+}
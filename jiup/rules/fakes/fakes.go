@@ -0,0 +1,129 @@
+// Package fakes contains in-process fake servers for the services used by the
+// extractors, with knobs for injecting errors.
+package fakes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault is an error to inject into the responses for a path.
+type Fault struct {
+	// Status is returned instead of the response if non-zero.
+	Status int
+	// Delay is the time to wait before responding (or until the request is cancelled).
+	Delay time.Duration
+	// Malformed truncates the response body to its first few bytes.
+	Malformed bool
+}
+
+// server is the common part of the fake servers.
+type server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	faults map[string]Fault
+}
+
+func newServer(mux *http.ServeMux) *server {
+	s := &server{faults: map[string]Fault{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.serve(w, r, mux)
+	}))
+	return s
+}
+
+// SetFault injects a fault into the responses for all paths starting with a prefix
+// ("/" for all paths). If more than one prefix matches, the longest one is used.
+func (s *server) SetFault(prefix string, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[prefix] = f
+}
+
+// ClearFaults removes all injected faults.
+func (s *server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = map[string]Fault{}
+}
+
+func (s *server) fault(path string) (Fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var f Fault
+	var m string
+	for prefix, pf := range s.faults {
+		if strings.HasPrefix(path, prefix) && len(prefix) >= len(m) {
+			f, m = pf, prefix
+		}
+	}
+	return f, m != ""
+}
+
+func (s *server) serve(w http.ResponseWriter, r *http.Request, mux *http.ServeMux) {
+	f, ok := s.fault(r.URL.Path)
+	if !ok {
+		mux.ServeHTTP(w, r)
+		return
+	}
+
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if f.Status != 0 {
+		http.Error(w, http.StatusText(f.Status), f.Status)
+		return
+	}
+
+	if f.Malformed {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, r)
+		for k, vs := range rec.Header() {
+			w.Header()[k] = vs
+		}
+		w.WriteHeader(rec.Code)
+		buf := rec.Body.Bytes()
+		if len(buf) > 8 {
+			buf = buf[:8]
+		}
+		w.Write(buf)
+		return
+	}
+
+	mux.ServeHTTP(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeHTML(w http.ResponseWriter, html string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(html))
+}
+
+// contextWith is implemented by the fake servers.
+type contextWith interface {
+	Context(ctx context.Context) context.Context
+}
+
+// Context returns a context which makes the extractors use all of the specified
+// fake servers.
+func Context(ctx context.Context, servers ...contextWith) context.Context {
+	for _, s := range servers {
+		ctx = s.Context(ctx)
+	}
+	return ctx
+}
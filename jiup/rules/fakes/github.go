@@ -0,0 +1,122 @@
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+)
+
+// Release is a GitHub release.
+type Release struct {
+	Tag    string    `json:"tag"`
+	Date   time.Time `json:"date"`
+	Assets []string  `json:"assets"`
+}
+
+// GitHub is a fake GitHub server. It serves the latest release page, the tags page,
+// the latest release API, and release assets for the repositories it knows about.
+type GitHub struct {
+	*server
+
+	mu       sync.Mutex
+	releases map[string]Release
+	tags     map[string][]string
+}
+
+// NewGitHub starts a fake GitHub server. It should be closed after use.
+func NewGitHub() *GitHub {
+	g := &GitHub{releases: map[string]Release{}, tags: map[string][]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", g.serve)
+	g.server = newServer(mux)
+	return g
+}
+
+// SetRelease sets the latest release of a repository (owner/name).
+func (g *GitHub) SetRelease(repo string, r Release) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.releases[repo] = r
+}
+
+// SetTags sets the tags of a repository (owner/name), newest first.
+func (g *GitHub) SetTags(repo string, tags ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tags[repo] = tags
+}
+
+// Context returns a context which makes the extractors use the server.
+func (g *GitHub) Context(ctx context.Context) context.Context {
+	return h.WithBaseURLs(ctx, h.BaseURLs{GitHub: g.URL})
+}
+
+func (g *GitHub) serve(w http.ResponseWriter, r *http.Request) {
+	spl := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	api := len(spl) > 0 && spl[0] == "repos"
+	if api {
+		spl = spl[1:]
+	}
+	if len(spl) < 3 {
+		http.NotFound(w, r)
+		return
+	}
+	repo, rest := spl[0]+"/"+spl[1], strings.Join(spl[2:], "/")
+
+	g.mu.Lock()
+	release, hasRelease := g.releases[repo]
+	tags, hasTags := g.tags[repo]
+	g.mu.Unlock()
+
+	switch {
+	case rest == "releases/latest" && hasRelease && api:
+		assets := []map[string]string{}
+		for _, a := range release.Assets {
+			assets = append(assets, map[string]string{
+				"name":                 a,
+				"browser_download_url": g.URL + "/" + repo + "/releases/download/" + release.Tag + "/" + a,
+			})
+		}
+		writeJSON(w, map[string]interface{}{
+			"tag_name":     release.Tag,
+			"published_at": release.Date,
+			"assets":       assets,
+		})
+	case rest == "releases/latest" && hasRelease:
+		var b strings.Builder
+		fmt.Fprintf(&b, "<div class=\"release\">\n")
+		fmt.Fprintf(&b, "<a href=\"/%s/tree/%s\"><svg class=\"octicon octicon-tag\"></svg><span>%s</span></a>\n", repo, html.EscapeString(release.Tag), html.EscapeString(release.Tag))
+		if !release.Date.IsZero() {
+			fmt.Fprintf(&b, "<relative-time datetime=\"%s\"></relative-time>\n", release.Date.Format(time.RFC3339))
+		}
+		fmt.Fprintf(&b, "<details class=\"Details-element\" open><summary>Assets</summary><div class=\"Box\">\n")
+		for _, a := range release.Assets {
+			fmt.Fprintf(&b, "<a href=\"/%s/releases/download/%s/%s\">%s</a>\n", repo, html.EscapeString(release.Tag), html.EscapeString(a), html.EscapeString(a))
+		}
+		fmt.Fprintf(&b, "</div></details>\n</div>\n")
+		writeHTML(w, b.String())
+	case rest == "tags" && hasTags && !api:
+		var b strings.Builder
+		for _, t := range tags {
+			fmt.Fprintf(&b, "<div class=\"commit Details\"><h4 class=\"commit-title\"><a href=\"/%s/releases/tag/%s\">%s</a></h4></div>\n", repo, html.EscapeString(t), html.EscapeString(t))
+		}
+		writeHTML(w, b.String())
+	case strings.HasPrefix(rest, "releases/download/") && hasRelease && !api:
+		for _, a := range release.Assets {
+			if rest == "releases/download/"+release.Tag+"/"+a {
+				w.Header().Set("Content-Type", "application/octet-stream")
+				w.Write([]byte("fake " + a))
+				return
+			}
+		}
+		http.NotFound(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,205 @@
+// Package fixture tests rules against saved copies of the pages they request.
+//
+// A fixture is a directory containing fixture.json, which lists the pages (by url)
+// and the expected results, and a file for the body of each page. The pages are
+// served by a local server, and the requests made by the rules are redirected to it
+// by rewriting the hosts.
+package fixture
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+)
+
+// FileName is the name of the fixture file in a fixture directory.
+const FileName = "fixture.json"
+
+// Fixture is a set of saved pages and the results a rule is expected to return for them.
+// Recorded is the time the pages were recorded from the live sites by Record, and
+// is empty for fixtures which were written by hand.
+type Fixture struct {
+	Recorded  string            `json:"recorded,omitempty"`
+	Version   string            `json:"version"`
+	Downloads map[c.Arch]string `json:"downloads"`
+	Pages     map[string]Page   `json:"pages"`
+}
+
+// Page is a saved response.
+type Page struct {
+	File        string `json:"file"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Location    string `json:"location,omitempty"`
+}
+
+// Load loads a fixture from a directory.
+func Load(dir string) (*Fixture, error) {
+	buf, err := ioutil.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(buf, &f); err != nil {
+		return nil, fmt.Errorf("could not parse fixture %#v: %v", dir, err)
+	}
+	return &f, nil
+}
+
+// Exists checks if a directory contains a fixture.
+func Exists(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, FileName))
+	return err == nil
+}
+
+// Check checks if the results of a rule match the expected ones.
+func (f *Fixture) Check(vi c.VersionInfo, dls c.Downloads) error {
+	errs := []string{}
+	if vi.Version != f.Version {
+		errs = append(errs, fmt.Sprintf("expected version %#v, got %#v", f.Version, vi.Version))
+	}
+	for _, arch := range c.Archs {
+		exp, ok := f.Downloads[arch]
+		if dl, dok := dls[arch]; dok != ok {
+			if ok {
+				errs = append(errs, fmt.Sprintf("expected %s link %#v, got none", arch, exp))
+			} else {
+				errs = append(errs, fmt.Sprintf("expected no %s link, got %#v", arch, dl.URL))
+			}
+		} else if ok && dl.URL != exp {
+			errs = append(errs, fmt.Sprintf("expected %s link %#v, got %#v", arch, exp, dl.URL))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Server serves the pages of a fixture.
+type Server struct {
+	*httptest.Server
+	dir string
+	f   *Fixture
+
+	mu     sync.Mutex
+	missed []string
+}
+
+const (
+	hostHeader   = "X-Fixture-Host"
+	schemeHeader = "X-Fixture-Scheme"
+)
+
+// NewServer starts a server for the fixture in a directory. It should be closed after use.
+func NewServer(dir string) (*Server, error) {
+	f, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{dir: dir, f: f}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s, nil
+}
+
+// Fixture returns the fixture being served.
+func (s *Server) Fixture() *Fixture {
+	return s.f
+}
+
+// Missed returns the urls which were requested but are not in the fixture.
+func (s *Server) Missed() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.missed...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	u := *r.URL
+	u.Scheme, u.Host = r.Header.Get(schemeHeader), r.Header.Get(hostHeader)
+
+	p, ok := s.f.Pages[u.String()]
+	if !ok {
+		s.mu.Lock()
+		s.missed = append(s.missed, u.String())
+		s.mu.Unlock()
+		http.Error(w, "no fixture for "+u.String(), http.StatusNotFound)
+		return
+	}
+
+	buf, err := ioutil.ReadFile(filepath.Join(s.dir, p.File))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p.ContentType != "" {
+		w.Header().Set("Content-Type", p.ContentType)
+	}
+	if p.Location != "" {
+		w.Header().Set("Location", p.Location)
+	}
+	if p.Status != 0 {
+		w.WriteHeader(p.Status)
+	}
+	w.Write(buf)
+}
+
+// ClientFactory returns a ClientFactory for clients which send all requests to the
+// server. Only the timeout from the options is used.
+func (s *Server) ClientFactory() h.ClientFactory {
+	return func(opts h.ClientOptions) (*http.Client, error) {
+		return &http.Client{Transport: rewriteTransport{s.Server.URL[len("http://"):]}, Timeout: opts.Timeout}, nil
+	}
+}
+
+// Context returns a context which makes the rules use the server (see h.WithClientFactory).
+func (s *Server) Context(ctx context.Context) context.Context {
+	return h.WithClientFactory(ctx, h.CachedClientFactory(s.ClientFactory()), h.ClientOptions{})
+}
+
+type rewriteTransport struct {
+	host string
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(hostHeader, req.URL.Host)
+	req.Header.Set(schemeHeader, req.URL.Scheme)
+	req.URL.Scheme, req.URL.Host, req.Host = "http", t.host, t.host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// Test runs a rule against the fixture in a directory and checks the results.
+func Test(ctx context.Context, dir string, v c.VersionExtractor, d c.DownloadExtractor) error {
+	s, err := NewServer(dir)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	ctx = s.Context(ctx)
+	vi, err := v.Structured()(ctx)
+	if err == nil {
+		var dls c.Downloads
+		if dls, err = d.Structured()(ctx, vi); err == nil {
+			err = s.Fixture().Check(vi, dls)
+		}
+	}
+	if missed := s.Missed(); len(missed) > 0 {
+		sort.Strings(missed)
+		return fmt.Errorf("requested pages not in fixture: %s (%v)", strings.Join(missed, ", "), err)
+	}
+	return err
+}
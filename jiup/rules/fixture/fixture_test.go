@@ -0,0 +1,81 @@
+package fixture
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	d "github.com/just-install/just-install-updater-go/jiup/rules/download"
+	v "github.com/just-install/just-install-updater-go/jiup/rules/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixture(t *testing.T) {
+	const page = `<a href="/files/app-1.2.3.exe">Download 1.2.3</a>`
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(page))
+		case "/old":
+			http.Redirect(w, r, "/download", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer live.Close()
+
+	dir, err := ioutil.TempDir("", "jiup-fixture")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	fdir := filepath.Join(dir, "app")
+
+	vfn := v.Regexp(live.URL+"/old", regexp.MustCompile(`app-([0-9.]+)\.exe`))
+	dfn := d.HTMLA(live.URL+"/download", "a[href$='.exe']", "")
+
+	f, err := Record(context.Background(), fdir, vfn, dfn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotEmpty(t, f.Recorded)
+	assert.Equal(t, "1.2.3", f.Version)
+	assert.Equal(t, map[c.Arch]string{c.ArchX86: live.URL + "/files/app-1.2.3.exe"}, f.Downloads)
+	assert.Len(t, f.Pages, 2)
+	assert.True(t, Exists(fdir))
+
+	live.Close()
+	assert.NoError(t, Test(context.Background(), fdir, vfn, dfn), "should pass using the fixture")
+
+	assert.Error(t, Test(context.Background(), fdir, v.Regexp(live.URL+"/download", regexp.MustCompile(`app-([0-9.]+)\.[0-9]+\.exe`)), dfn), "should fail if the version is different")
+	assert.Error(t, Test(context.Background(), fdir, v.Regexp(live.URL+"/other", regexp.MustCompile(`app-([0-9.]+)\.exe`)), dfn), "should fail if a page is missing")
+}
+
+func TestMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jiup-fixture")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "missing.txt")
+
+	if !assert.NoError(t, ioutil.WriteFile(fn, []byte("# comment\nabc\n abcd\nxyz\n"), 0644)) {
+		return
+	}
+	pkgs, err := LoadMissing(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc", "abcd", "xyz"}, pkgs)
+
+	assert.NoError(t, Unlist(fn, "abc"))
+	assert.NoError(t, Unlist(fn, "none"))
+	buf, err := ioutil.ReadFile(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "# comment\n abcd\nxyz\n", string(buf))
+}
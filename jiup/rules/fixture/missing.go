@@ -0,0 +1,42 @@
+package fixture
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+// LoadMissing loads a list of packages which don't have a recorded fixture (one
+// package per line, with comments starting with #).
+func LoadMissing(fn string) ([]string, error) {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []string
+	for _, line := range strings.Split(string(buf), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			pkgs = append(pkgs, line)
+		}
+	}
+	return pkgs, nil
+}
+
+// Unlist removes a package from a list loaded by LoadMissing, keeping the comments
+// and the other lines as-is. It does nothing if the package isn't listed.
+func Unlist(fn, pkg string) error {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	lines := strings.SplitAfter(string(buf), "\n")
+	out := lines[:0]
+	for _, line := range lines {
+		if strings.TrimSpace(line) != pkg {
+			out = append(out, line)
+		}
+	}
+	if len(out) == len(lines) {
+		return nil
+	}
+	return ioutil.WriteFile(fn, []byte(strings.Join(out, "")), 0644)
+}
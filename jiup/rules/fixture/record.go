@@ -0,0 +1,107 @@
+package fixture
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+)
+
+// Record runs a rule against the live sites using the client factory in the context,
+// then replaces the fixture in a directory with the pages it requested and the results
+// it returned. Rules which make range requests (i.e. for partial downloads) can't be
+// recorded.
+func Record(ctx context.Context, dir string, v c.VersionExtractor, d c.DownloadExtractor) (*Fixture, error) {
+	r := h.NewHARRecorder(nil)
+	ctx = h.WithHARRecorder(ctx, r)
+
+	vi, err := v.Structured()(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get version: %v", err)
+	}
+	dls, err := d.Structured()(ctx, vi)
+	if err != nil {
+		return nil, fmt.Errorf("could not get downloads: %v", err)
+	}
+
+	f := &Fixture{
+		Recorded:  time.Now().UTC().Format(time.RFC3339),
+		Version:   vi.Version,
+		Downloads: map[c.Arch]string{},
+		Pages:     map[string]Page{},
+	}
+	for arch, dl := range dls {
+		f.Downloads[arch] = dl.URL
+	}
+
+	files := map[string][]byte{}
+	for i, e := range r.HAR().Log.Entries {
+		for _, hdr := range e.Request.Headers {
+			if strings.EqualFold(hdr.Name, "Range") {
+				return nil, fmt.Errorf("could not record range request for %s", e.Request.URL)
+			}
+		}
+
+		buf := []byte(e.Response.Content.Text)
+		if e.Response.Content.Encoding == "base64" {
+			if buf, err = base64.StdEncoding.DecodeString(e.Response.Content.Text); err != nil {
+				return nil, err
+			}
+		}
+
+		p := Page{
+			File:        fmt.Sprintf("%d%s", i, extension(e.Response.Content.MimeType)),
+			ContentType: e.Response.Content.MimeType,
+			Location:    e.Response.RedirectURL,
+		}
+		if e.Response.Status != 200 {
+			p.Status = e.Response.Status
+		}
+		f.Pages[e.Request.URL] = p
+		files[p.File] = buf
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	for fn, buf := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, fn), buf, 0644); err != nil {
+			return nil, err
+		}
+	}
+	buf, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, FileName), append(buf, '\n'), 0644); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func extension(contentType string) string {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mt == "text/html":
+		return ".html"
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return ".json"
+	case mt == "text/xml" || mt == "application/xml" || strings.HasSuffix(mt, "+xml"):
+		return ".xml"
+	case strings.HasPrefix(mt, "text/"):
+		return ".txt"
+	}
+	return ""
+}
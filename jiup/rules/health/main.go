@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/just-install/just-install-updater-go/jiup/history"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	stateDir := pflag.String("state-dir", history.DefaultDir(), "The directory the health of the rules was recorded in")
+	window := pflag.IntP("window", "w", 20, "The number of most recent runs of each rule to consider (0 for all)")
+	limit := pflag.IntP("limit", "n", 20, "The maximum number of rules to show (0 for no limit)")
+	all := pflag.BoolP("all", "a", false, "Show all rules, not only the ones which are flaky, failing or broken")
+	help := pflag.Bool("help", false, "Show this help text")
+	pflag.Parse()
+
+	if *help || *stateDir == "" {
+		helpExit()
+	}
+
+	s, err := history.Open(*stateDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open state dir: %v\n", err)
+		os.Exit(1)
+	}
+	results, err := s.Results()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read rule health: %v\n", err)
+		os.Exit(1)
+	}
+
+	stats := history.ComputeStats(results, *window)
+	if pflag.NArg() > 0 {
+		filtered := map[string]*history.Stats{}
+		for _, p := range pflag.Args() {
+			if st, ok := stats[p]; ok {
+				filtered[p] = st
+			} else {
+				fmt.Fprintf(os.Stderr, "Warning: no results for %s\n", p)
+			}
+		}
+		stats = filtered
+	}
+
+	worst := history.Worst(stats, *all || pflag.NArg() > 0)
+	counts := map[history.Status]int{}
+	for _, st := range stats {
+		counts[st.Status]++
+	}
+	if *limit > 0 && len(worst) > *limit {
+		worst = worst[:*limit]
+	}
+
+	if len(worst) > 0 {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "RULE\tSTATUS\tSUCCESS\tSTREAK\tLATENCY\tLAST SUCCESS\tLAST ERROR\n")
+		for _, st := range worst {
+			fmt.Fprintf(tw, "%s\t%s\t%.0f%% of %d\t%d\t%s\t%s\t%s\n", st.Package, st.Status, st.SuccessRate*100, st.Runs, st.Streak, st.MeanLatency.Round(time.Millisecond), formatTime(st.LastSuccess), formatError(st))
+		}
+		tw.Flush()
+		fmt.Printf("\n")
+	}
+
+	fmt.Printf("Summary: %d rules, %d ok, %d flaky, %d failing, %d broken (%d results)\n", len(stats), counts[history.StatusOK], counts[history.StatusFlaky], counts[history.StatusFailing], counts[history.StatusBroken], len(results))
+	os.Exit(0)
+}
+
+func helpExit() {
+	fmt.Fprintf(os.Stderr, "Usage: health [options] [packages...]\n\n")
+	pflag.PrintDefaults()
+	os.Exit(1)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// formatError formats the last error of a rule, truncated to fit on a line.
+func formatError(st *history.Stats) string {
+	if st.LastFailure.IsZero() {
+		return "-"
+	}
+	msg := strings.Join(strings.Fields(st.LastError), " ")
+	if len(msg) > 80 {
+		msg = msg[:77] + "..."
+	}
+	return fmt.Sprintf("%s: %s (%s)", st.LastCategory, msg, formatTime(st.LastFailure))
+}
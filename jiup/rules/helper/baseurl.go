@@ -0,0 +1,52 @@
+package h
+
+import (
+	"context"
+	"strings"
+)
+
+// BaseURLs are the base urls of the services used by the extractors (without a
+// trailing slash). Empty ones are left unchanged.
+type BaseURLs struct {
+	GitHub   string
+	AppVeyor string
+}
+
+// DefaultBaseURLs are the base urls of the real services.
+var DefaultBaseURLs = BaseURLs{
+	GitHub:   "https://github.com",
+	AppVeyor: "https://ci.appveyor.com",
+}
+
+type baseURLsKey struct{}
+
+// WithBaseURLs returns a context which makes the extractors use different base urls
+// (e.g. for a fake server).
+func WithBaseURLs(ctx context.Context, urls BaseURLs) context.Context {
+	cur := GetBaseURLs(ctx)
+	if urls.GitHub != "" {
+		cur.GitHub = strings.TrimSuffix(urls.GitHub, "/")
+	}
+	if urls.AppVeyor != "" {
+		cur.AppVeyor = strings.TrimSuffix(urls.AppVeyor, "/")
+	}
+	return context.WithValue(ctx, baseURLsKey{}, cur)
+}
+
+// GetBaseURLs gets the base urls from the context (see WithBaseURLs), or the default ones.
+func GetBaseURLs(ctx context.Context) BaseURLs {
+	if urls, ok := ctx.Value(baseURLsKey{}).(BaseURLs); ok {
+		return urls
+	}
+	return DefaultBaseURLs
+}
+
+// GitHubURL returns the url of a path on GitHub.
+func GitHubURL(ctx context.Context, path string) string {
+	return GetBaseURLs(ctx).GitHub + "/" + strings.TrimPrefix(path, "/")
+}
+
+// AppVeyorURL returns the url of a path on AppVeyor.
+func AppVeyorURL(ctx context.Context, path string) string {
+	return GetBaseURLs(ctx).AppVeyor + "/" + strings.TrimPrefix(path, "/")
+}
@@ -0,0 +1,21 @@
+package h
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseURLs(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "https://github.com/a/b/tags", GitHubURL(ctx, "a/b/tags"))
+	assert.Equal(t, "https://ci.appveyor.com/api/projects/a/b", AppVeyorURL(ctx, "/api/projects/a/b"))
+
+	ctx = WithBaseURLs(ctx, BaseURLs{GitHub: "http://127.0.0.1:1234/"})
+	assert.Equal(t, "http://127.0.0.1:1234/a/b/tags", GitHubURL(ctx, "a/b/tags"))
+	assert.Equal(t, "https://ci.appveyor.com/api/projects/a/b", AppVeyorURL(ctx, "api/projects/a/b"), "should not change unset urls")
+
+	ctx = WithBaseURLs(ctx, BaseURLs{AppVeyor: "http://127.0.0.1:5678"})
+	assert.Equal(t, BaseURLs{GitHub: "http://127.0.0.1:1234", AppVeyor: "http://127.0.0.1:5678"}, GetBaseURLs(ctx))
+}
@@ -0,0 +1,213 @@
+package h
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+)
+
+// ClientOptions configures a HTTP client. The zero value is a client with the
+// default settings.
+type ClientOptions struct {
+	// Timeout is the timeout for each request (default 10 seconds).
+	Timeout time.Duration
+	// Insecure disables certificate verification.
+	Insecure bool
+	// RootCAs contains PEM-encoded certificates to trust instead of the system roots.
+	RootCAs []byte
+	// Pins contains the hex-encoded SHA-256 fingerprints of the certificates to accept.
+	// If set, at least one certificate in the chain must match. To trust a self-signed
+	// certificate, also set Insecure.
+	Pins []string
+	// Proxy is the proxy url to use instead of the one from the environment.
+	Proxy string
+	// UserAgent overrides the User-Agent header if set.
+	UserAgent string
+}
+
+// Merge returns the options with the non-zero fields of o overriding them.
+func (opts ClientOptions) Merge(o ClientOptions) ClientOptions {
+	if o.Timeout != 0 {
+		opts.Timeout = o.Timeout
+	}
+	if o.Insecure {
+		opts.Insecure = true
+	}
+	if o.RootCAs != nil {
+		opts.RootCAs = o.RootCAs
+	}
+	if o.Pins != nil {
+		opts.Pins = o.Pins
+	}
+	if o.Proxy != "" {
+		opts.Proxy = o.Proxy
+	}
+	if o.UserAgent != "" {
+		opts.UserAgent = o.UserAgent
+	}
+	return opts
+}
+
+// ClientFactory creates a HTTP client with the specified options.
+type ClientFactory func(opts ClientOptions) (*http.Client, error)
+
+// NewClient is a ClientFactory which creates a new client and transport.
+func NewClient(opts ClientOptions) (*http.Client, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{InsecureSkipVerify: opts.Insecure}
+
+	if opts.RootCAs != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(opts.RootCAs) {
+			return nil, errors.New("no valid certificates in root CAs")
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+
+	if len(opts.Pins) != 0 {
+		pins := map[string]bool{}
+		for _, pin := range opts.Pins {
+			pins[strings.ToLower(strings.Replace(pin, ":", "", -1))] = true
+		}
+		t.TLSClientConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			for _, cert := range rawCerts {
+				sum := sha256.Sum256(cert)
+				if pins[hex.EncodeToString(sum[:])] {
+					return nil
+				}
+			}
+			return c.NewError(c.CategoryTLS, "", errors.New("no certificate matches the pinned fingerprints"))
+		}
+	}
+
+	if opts.Proxy != "" {
+		u, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %v", err)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	var rt http.RoundTripper = t
+	if opts.UserAgent != "" {
+		rt = &userAgentTransport{rt, opts.UserAgent}
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = time.Second * 10
+	}
+
+	return &http.Client{Transport: rt, Timeout: timeout}, nil
+}
+
+// CachedClientFactory wraps a ClientFactory to reuse the client for identical options.
+// Each client is also given an ID, which is used to cache responses in memory per
+// client (see GetURL).
+func CachedClientFactory(f ClientFactory) ClientFactory {
+	var mu sync.Mutex
+	clients := map[string]*http.Client{}
+	return func(opts ClientOptions) (*http.Client, error) {
+		key := fmt.Sprintf("%#v", opts)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if c, ok := clients[key]; ok {
+			return c, nil
+		}
+		c, err := f(opts)
+		if err != nil {
+			return nil, err
+		}
+		cc := *c
+		rt := cc.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		cc.Transport = &clientIDTransport{rt, atomic.AddUint64(&lastClientID, 1)}
+		clients[key] = &cc
+		return &cc, nil
+	}
+}
+
+var lastClientID uint64
+
+// clientIDTransport identifies a client from a CachedClientFactory. Unlike the
+// address of the client, the ID is never reused.
+type clientIDTransport struct {
+	http.RoundTripper
+	id uint64
+}
+
+// clientID returns the ID of a client from a CachedClientFactory.
+func clientID(client *http.Client) (uint64, bool) {
+	if t, ok := client.Transport.(*clientIDTransport); ok {
+		return t.id, true
+	}
+	return 0, false
+}
+
+var defaultClientFactory = CachedClientFactory(NewClient)
+
+type userAgentTransport struct {
+	http.RoundTripper
+	ua string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.ua)
+	return t.RoundTripper.RoundTrip(req)
+}
+
+type clientFactoryKey struct{}
+type clientOptionsKey struct{}
+
+type clientFactoryValue struct {
+	f    ClientFactory
+	opts ClientOptions
+}
+
+// WithClientFactory returns a context which makes Client use the specified factory
+// and base options. If the factory is nil, the default one is used.
+func WithClientFactory(ctx context.Context, f ClientFactory, opts ClientOptions) context.Context {
+	return context.WithValue(ctx, clientFactoryKey{}, clientFactoryValue{f, opts})
+}
+
+// WithClientOptions returns a context which makes Client merge the specified options
+// (e.g. for a single rule) over the ones already in the context.
+func WithClientOptions(ctx context.Context, opts ClientOptions) context.Context {
+	if cur, ok := ctx.Value(clientOptionsKey{}).(ClientOptions); ok {
+		opts = cur.Merge(opts)
+	}
+	return context.WithValue(ctx, clientOptionsKey{}, opts)
+}
+
+// Client gets a client from the factory in the context (see WithClientFactory) using
+// the base options, the options in the context (see WithClientOptions), then opts.
+func Client(ctx context.Context, opts ClientOptions) (*http.Client, error) {
+	f, base := defaultClientFactory, ClientOptions{}
+	if v, ok := ctx.Value(clientFactoryKey{}).(clientFactoryValue); ok {
+		if v.f != nil {
+			f = v.f
+		}
+		base = v.opts
+	}
+	if ctxOpts, ok := ctx.Value(clientOptionsKey{}).(ClientOptions); ok {
+		base = base.Merge(ctxOpts)
+	}
+	return f(base.Merge(opts))
+}
@@ -0,0 +1,119 @@
+package h
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientTLS(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+
+	cert := s.Certificate().Raw
+	sum := sha256.Sum256(cert)
+	pin := hex.EncodeToString(sum[:])
+
+	for _, tc := range []struct {
+		Name string
+		Opts ClientOptions
+		OK   bool
+	}{
+		{"default", ClientOptions{}, false},
+		{"insecure", ClientOptions{Insecure: true}, true},
+		{"root ca", ClientOptions{RootCAs: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})}, true},
+		{"pinned self-signed", ClientOptions{Insecure: true, Pins: []string{pin}}, true},
+		{"pinned self-signed wrong", ClientOptions{Insecure: true, Pins: []string{"00"}}, false},
+		{"pinned root ca", ClientOptions{RootCAs: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), Pins: []string{pin}}, true},
+		{"pinned untrusted", ClientOptions{Pins: []string{pin}}, false},
+	} {
+		c, err := NewClient(tc.Opts)
+		if !assert.NoError(t, err, tc.Name) {
+			continue
+		}
+		resp, err := c.Get(s.URL)
+		if tc.OK {
+			if assert.NoError(t, err, tc.Name) {
+				resp.Body.Close()
+			}
+		} else {
+			assert.Error(t, err, tc.Name)
+		}
+	}
+
+	_, err := NewClient(ClientOptions{RootCAs: []byte("invalid")})
+	assert.Error(t, err)
+}
+
+func TestNewClientProxyUserAgent(t *testing.T) {
+	var gotURL, gotUA string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL, gotUA = r.URL.String(), r.UserAgent()
+		w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	c, err := NewClient(ClientOptions{Proxy: proxy.URL, UserAgent: "jiup-test"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	resp, err := c.Get("http://example.invalid/test")
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+	assert.Equal(t, "http://example.invalid/test", gotURL)
+	assert.Equal(t, "jiup-test", gotUA)
+
+	_, err = NewClient(ClientOptions{Proxy: "://invalid"})
+	assert.Error(t, err)
+}
+
+func TestClient(t *testing.T) {
+	var got []ClientOptions
+	f := func(opts ClientOptions) (*http.Client, error) {
+		got = append(got, opts)
+		return &http.Client{}, nil
+	}
+
+	ctx := WithClientFactory(context.Background(), f, ClientOptions{UserAgent: "base", Proxy: "http://proxy"})
+	ctx = WithClientOptions(ctx, ClientOptions{Insecure: true})
+	ctx = WithClientOptions(ctx, ClientOptions{UserAgent: "rule"})
+
+	_, err := Client(ctx, ClientOptions{Timeout: time.Minute})
+	assert.NoError(t, err)
+	assert.Equal(t, []ClientOptions{{Timeout: time.Minute, Insecure: true, Proxy: "http://proxy", UserAgent: "rule"}}, got)
+}
+
+func TestCachedClientFactory(t *testing.T) {
+	n := 0
+	f := CachedClientFactory(func(opts ClientOptions) (*http.Client, error) {
+		n++
+		return NewClient(opts)
+	})
+
+	a, _ := f(ClientOptions{Pins: []string{"00"}})
+	b, _ := f(ClientOptions{Pins: []string{"00"}})
+	c, _ := f(ClientOptions{Insecure: true})
+	assert.True(t, a == b, "clients for the same options should be reused")
+	assert.False(t, a == c, "clients for different options should not be reused")
+	assert.Equal(t, 2, n)
+
+	aid, ok := clientID(a)
+	assert.True(t, ok)
+	cid, _ := clientID(c)
+	d, _ := CachedClientFactory(NewClient)(ClientOptions{Pins: []string{"00"}})
+	did, _ := clientID(d)
+	assert.NotEqual(t, aid, cid)
+	assert.NotEqual(t, aid, did, "clients from different factories should have different IDs")
+	_, ok = clientID(&http.Client{})
+	assert.False(t, ok)
+}
@@ -0,0 +1,245 @@
+package h
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default limits for a DiskCache.
+const (
+	DefaultCacheMaxSize = 100 * 1024 * 1024
+	DefaultCacheMaxAge  = time.Hour * 24 * 7
+)
+
+// DiskCache is a persistent HTTP cache which stores responses with an ETag or
+// Last-Modified header, and revalidates them with conditional requests. Entries
+// older than MaxAge are discarded, and the oldest entries are removed by Prune
+// when the total size is larger than MaxSize.
+type DiskCache struct {
+	Dir     string
+	MaxSize int64         // bytes, or 0 for DefaultCacheMaxSize
+	MaxAge  time.Duration // or 0 for DefaultCacheMaxAge
+
+	mu sync.Mutex
+}
+
+type diskCacheEntry struct {
+	URL          string
+	Status       int
+	Header       http.Header
+	Body         []byte
+	ETag         string
+	LastModified string
+	Stored       time.Time
+}
+
+// NewDiskCache opens (and creates if needed) a disk cache, and prunes it.
+func NewDiskCache(dir string, maxSize int64, maxAge time.Duration) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	dc := &DiskCache{Dir: dir, MaxSize: maxSize, MaxAge: maxAge}
+	if err := dc.Prune(); err != nil {
+		return nil, err
+	}
+	return dc, nil
+}
+
+func (dc *DiskCache) maxSize() int64 {
+	if dc.MaxSize == 0 {
+		return DefaultCacheMaxSize
+	}
+	return dc.MaxSize
+}
+
+func (dc *DiskCache) maxAge() time.Duration {
+	if dc.MaxAge == 0 {
+		return DefaultCacheMaxAge
+	}
+	return dc.MaxAge
+}
+
+// path returns the file for a request, which is keyed on the same things as the
+// memory cache (see memCacheKey).
+func (dc *DiskCache) path(req *http.Request) string {
+	headers := map[string]string{}
+	for k, vs := range req.Header {
+		headers[k] = strings.Join(vs, ", ")
+	}
+	sum := sha256.Sum256([]byte(memCacheKey(req.Method, req.URL.String(), headers)))
+	return filepath.Join(dc.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (dc *DiskCache) load(fn string) *diskCacheEntry {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil
+	}
+	var e diskCacheEntry
+	if err := json.Unmarshal(buf, &e); err != nil || time.Since(e.Stored) > dc.maxAge() {
+		os.Remove(fn)
+		return nil
+	}
+	return &e
+}
+
+func (dc *DiskCache) store(fn string, e *diskCacheEntry) error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmp := fn + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
+
+// Prune removes expired entries, then removes the oldest entries until the cache is
+// smaller than MaxSize.
+func (dc *DiskCache) Prune() error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	fis, err := ioutil.ReadDir(dc.Dir)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	entries := []os.FileInfo{}
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".json") {
+			continue
+		}
+		if time.Since(fi.ModTime()) > dc.maxAge() {
+			if err := os.Remove(filepath.Join(dc.Dir, fi.Name())); err != nil {
+				return err
+			}
+			continue
+		}
+		total += fi.Size()
+		entries = append(entries, fi)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+	for _, fi := range entries {
+		if total <= dc.maxSize() {
+			break
+		}
+		if err := os.Remove(filepath.Join(dc.Dir, fi.Name())); err != nil {
+			return err
+		}
+		total -= fi.Size()
+	}
+	return nil
+}
+
+// Transport wraps a http.RoundTripper to use the cache for GET requests (except for
+// range requests).
+func (dc *DiskCache) Transport(rt http.RoundTripper) http.RoundTripper {
+	return &diskCacheTransport{dc, rt}
+}
+
+// ClientFactory wraps a ClientFactory to use the cache for the created clients.
+func (dc *DiskCache) ClientFactory(f ClientFactory) ClientFactory {
+	return func(opts ClientOptions) (*http.Client, error) {
+		c, err := f(opts)
+		if err != nil {
+			return nil, err
+		}
+		cc := *c
+		rt := cc.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		cc.Transport = dc.Transport(rt)
+		return &cc, nil
+	}
+}
+
+type diskCacheTransport struct {
+	dc *DiskCache
+	rt http.RoundTripper
+}
+
+func (t *diskCacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" || req.Header.Get("Range") != "" {
+		return t.rt.RoundTrip(req)
+	}
+
+	fn := t.dc.path(req)
+	e := t.dc.load(fn)
+	if e != nil {
+		req = req.Clone(req.Context())
+		if e.ETag != "" {
+			req.Header.Set("If-None-Match", e.ETag)
+		}
+		if e.LastModified != "" {
+			req.Header.Set("If-Modified-Since", e.LastModified)
+		}
+	}
+
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if e != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		e.Stored = time.Now()
+		t.dc.store(fn, e) // refresh the age; the cached response is still usable if this fails
+		return &http.Response{
+			Status:        http.StatusText(e.Status),
+			StatusCode:    e.Status,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        e.Header,
+			Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+			ContentLength: int64(len(e.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return resp, nil
+	}
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(buf))
+
+	t.dc.store(fn, &diskCacheEntry{
+		URL:          req.URL.String(),
+		Status:       resp.StatusCode,
+		Header:       resp.Header,
+		Body:         buf,
+		ETag:         etag,
+		LastModified: lastModified,
+		Stored:       time.Now(),
+	}) // not being able to cache isn't fatal
+	return resp, nil
+}
@@ -0,0 +1,152 @@
+package h
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskCache(t *testing.T) {
+	body, requests, conditional, notModified := "version 1", 0, 0, 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		etag := `"` + body + `"`
+		if r.URL.Path == "/noetag" {
+			w.Write([]byte(body))
+			return
+		}
+		if r.Header.Get("If-None-Match") != "" {
+			conditional++
+			if r.Header.Get("If-None-Match") == etag {
+				notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "jiup-cache")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dc, err := NewDiskCache(dir, 0, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	c := &http.Client{Transport: dc.Transport(http.DefaultTransport)}
+
+	get := func(path string) string {
+		resp, err := c.Get(s.URL + path)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		defer resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+		buf, _ := ioutil.ReadAll(resp.Body)
+		return string(buf)
+	}
+
+	assert.Equal(t, "version 1", get("/"))
+	assert.Equal(t, 0, conditional, "first request should not be cached")
+
+	assert.Equal(t, "version 1", get("/"), "cached body should be returned for a 304")
+	assert.Equal(t, 1, conditional, "second request should be revalidated")
+	assert.Equal(t, 1, notModified)
+
+	body = "version 2"
+	assert.Equal(t, "version 2", get("/"), "new body should be returned if changed")
+	assert.Equal(t, 2, conditional)
+	assert.Equal(t, 1, notModified)
+
+	get("/noetag")
+	assert.Equal(t, "version 2", get("/noetag"))
+	assert.Equal(t, 2, conditional, "responses without validators should not be cached")
+
+	// a new instance should use the existing entries
+	dc2, err := NewDiskCache(dir, 0, 0)
+	if assert.NoError(t, err) {
+		c.Transport = dc2.Transport(http.DefaultTransport)
+		get("/")
+		assert.Equal(t, 2, notModified, "cache should persist across instances")
+	}
+
+	// expired entries should not be used
+	dc3, err := NewDiskCache(dir, 0, time.Nanosecond)
+	if assert.NoError(t, err) {
+		c.Transport = dc3.Transport(http.DefaultTransport)
+		get("/")
+		assert.Equal(t, 3, conditional, "expired entries should not be used")
+	}
+	assert.Equal(t, 7, requests)
+}
+
+func TestDiskCachePath(t *testing.T) {
+	dc := &DiskCache{Dir: "cache"}
+	path := func(headers map[string]string) string {
+		r, _ := http.NewRequest("GET", "https://example.com", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		return dc.path(r)
+	}
+	assert.Equal(t, path(map[string]string{"Accept": "a", "X-Test": "b"}), path(map[string]string{"x-test": "b", "accept": "a"}))
+	assert.NotEqual(t, path(map[string]string{"Authorization": "token a"}), path(map[string]string{"Authorization": "token b"}), "responses for different headers should not be shared")
+	assert.NotEqual(t, path(nil), path(map[string]string{"User-Agent": "test"}))
+}
+
+func TestDiskCachePrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jiup-cache")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	for i, name := range []string{"a", "b", "c"} {
+		fn := filepath.Join(dir, name+".json")
+		assert.NoError(t, ioutil.WriteFile(fn, []byte(strings.Repeat("x", 100)), 0644))
+		mt := time.Now().Add(-time.Hour * time.Duration(3-i))
+		assert.NoError(t, os.Chtimes(fn, mt, mt))
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "other"), []byte(strings.Repeat("x", 1000)), 0644))
+
+	_, err = NewDiskCache(dir, 250, time.Hour*24)
+	assert.NoError(t, err)
+
+	fis, _ := ioutil.ReadDir(dir)
+	names := []string{}
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	assert.Equal(t, []string{"b.json", "c.json", "other"}, names, "oldest entries should be removed first, and other files should be ignored")
+
+	_, err = NewDiskCache(dir, 0, time.Minute*90)
+	assert.NoError(t, err)
+
+	fis, _ = ioutil.ReadDir(dir)
+	names = []string{}
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	assert.Equal(t, []string{"c.json", "other"}, names, "expired entries should be removed")
+}
+
+func TestDiskCacheClientFactory(t *testing.T) {
+	dc := &DiskCache{Dir: "unused"}
+	c, err := dc.ClientFactory(NewClient)(ClientOptions{Timeout: time.Minute})
+	if assert.NoError(t, err) {
+		assert.IsType(t, &diskCacheTransport{}, c.Transport)
+		assert.Equal(t, time.Minute, c.Timeout)
+	}
+}
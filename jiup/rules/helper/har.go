@@ -0,0 +1,390 @@
+package h
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// HAR is a HTTP Archive (only the fields used for recording and replaying).
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the log of a HAR.
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator is the application which created a HAR.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is a request and its response.
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+}
+
+// HARRequest is a recorded request.
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	Cookies     []HARNameValue `json:"cookies"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse is a recorded response.
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARContent is the body of a response. Text is base64 encoded if it isn't valid UTF-8.
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARNameValue is a header, query parameter, or cookie.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARTimings contains the time taken by each part of a request in milliseconds.
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// LoadHAR reads a HAR file.
+func LoadHAR(fn string) (*HAR, error) {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var har HAR
+	if err := json.Unmarshal(buf, &har); err != nil {
+		return nil, fmt.Errorf("could not parse har %#v: %v", fn, err)
+	}
+	return &har, nil
+}
+
+// Save writes a HAR file.
+func (har *HAR) Save(fn string) error {
+	buf, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fn, append(buf, '\n'), 0644)
+}
+
+// HARRecorder is a http.RoundTripper which records all requests and responses.
+type HARRecorder struct {
+	rt      http.RoundTripper
+	mu      sync.Mutex
+	entries []HAREntry
+}
+
+// NewHARRecorder creates a HARRecorder which makes requests using rt (or
+// http.DefaultTransport if nil).
+func NewHARRecorder(rt http.RoundTripper) *HARRecorder {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &HARRecorder{rt: rt}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *HARRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.record(r.rt, req)
+}
+
+func (r *HARRecorder) record(rt http.RoundTripper, req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	wait := time.Since(start)
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(buf))
+
+	content := HARContent{Size: len(buf), MimeType: resp.Header.Get("Content-Type")}
+	if utf8.Valid(buf) {
+		content.Text = string(buf)
+	} else {
+		content.Text, content.Encoding = base64.StdEncoding.EncodeToString(buf), "base64"
+	}
+
+	query := []HARNameValue{}
+	for k, vs := range req.URL.Query() {
+		for _, v := range vs {
+			query = append(query, HARNameValue{k, v})
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, HAREntry{
+		StartedDateTime: start,
+		Time:            float64(time.Since(start)) / float64(time.Millisecond),
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Headers:     harHeaders(req.Header),
+			QueryString: query,
+			Cookies:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: HARResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: resp.Proto,
+			Headers:     harHeaders(resp.Header),
+			Cookies:     []HARNameValue{},
+			Content:     content,
+			RedirectURL: resp.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(buf),
+		},
+		Timings: HARTimings{
+			Wait:    float64(wait) / float64(time.Millisecond),
+			Receive: float64(time.Since(start)-wait) / float64(time.Millisecond),
+		},
+	})
+	return resp, nil
+}
+
+// HAR returns the recorded requests.
+func (r *HARRecorder) HAR() *HAR {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "just-install-updater-go", Version: "1"},
+		Entries: append([]HAREntry{}, r.entries...),
+	}}
+}
+
+// ClientFactory wraps a ClientFactory to record the requests made by the created clients.
+func (r *HARRecorder) ClientFactory(f ClientFactory) ClientFactory {
+	return func(opts ClientOptions) (*http.Client, error) {
+		c, err := f(opts)
+		if err != nil {
+			return nil, err
+		}
+		rt := c.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		cc := *c
+		cc.Transport = harRecorderTransport{r, rt}
+		return &cc, nil
+	}
+}
+
+type harRecorderTransport struct {
+	r  *HARRecorder
+	rt http.RoundTripper
+}
+
+func (t harRecorderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.r.record(t.rt, req)
+}
+
+// HARReplayer is a http.RoundTripper which responds with the responses from a HAR
+// instead of making requests. Requests are matched by the method and url. If there
+// is more than one response for a request, they are returned in order, and the last
+// one is repeated. Requests which were not recorded return an error.
+type HARReplayer struct {
+	mu      sync.Mutex
+	entries map[string][]HAREntry
+}
+
+// NewHARReplayer creates a HARReplayer for a HAR.
+func NewHARReplayer(har *HAR) *HARReplayer {
+	r := &HARReplayer{entries: map[string][]HAREntry{}}
+	for _, e := range har.Log.Entries {
+		k := e.Request.Method + " " + e.Request.URL
+		r.entries[k] = append(r.entries[k], e)
+	}
+	return r
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *HARReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	k := req.Method + " " + req.URL.String()
+
+	r.mu.Lock()
+	es := r.entries[k]
+	if len(es) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("no recorded response for %s", k)
+	}
+	e := es[0]
+	if len(es) > 1 {
+		r.entries[k] = es[1:]
+	}
+	r.mu.Unlock()
+
+	buf := []byte(e.Response.Content.Text)
+	if e.Response.Content.Encoding == "base64" {
+		var err error
+		if buf, err = base64.StdEncoding.DecodeString(e.Response.Content.Text); err != nil {
+			return nil, fmt.Errorf("could not decode recorded response for %s: %v", k, err)
+		}
+	}
+
+	header := http.Header{}
+	for _, hdr := range e.Response.Headers {
+		header.Add(hdr.Name, hdr.Value)
+	}
+	// the body is not compressed anymore
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Response.Status, e.Response.StatusText),
+		StatusCode:    e.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(buf)),
+		ContentLength: int64(len(buf)),
+		Request:       req,
+	}, nil
+}
+
+// ClientFactory returns a ClientFactory for clients which replay the HAR. Only the
+// timeout from the options is used.
+func (r *HARReplayer) ClientFactory() ClientFactory {
+	return func(opts ClientOptions) (*http.Client, error) {
+		return &http.Client{Transport: r, Timeout: opts.Timeout}, nil
+	}
+}
+
+// LoadHARReplayer creates a HARReplayer from a HAR file. If the file does not exist,
+// all requests will return an error.
+func LoadHARReplayer(fn string) (*HARReplayer, error) {
+	har, err := LoadHAR(fn)
+	if os.IsNotExist(err) {
+		return NewHARReplayer(&HAR{}), nil
+	} else if err != nil {
+		return nil, err
+	}
+	return NewHARReplayer(har), nil
+}
+
+// harSensitiveHeaders are the headers which aren't saved in a HAR, since they may
+// contain credentials, and the recorded HARs are meant to be shared (e.g. from CI).
+var harSensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+	"X-Auth-Token":        true,
+}
+
+func harHeaders(h http.Header) []HARNameValue {
+	hs := []HARNameValue{}
+	for k, vs := range h {
+		if harSensitiveHeaders[http.CanonicalHeaderKey(k)] {
+			continue
+		}
+		for _, v := range vs {
+			hs = append(hs, HARNameValue{k, v})
+		}
+	}
+	sortHARNameValues(hs)
+	return hs
+}
+
+func sortHARNameValues(nvs []HARNameValue) {
+	for i := 1; i < len(nvs); i++ {
+		for j := i; j > 0 && strings.ToLower(nvs[j].Name) < strings.ToLower(nvs[j-1].Name); j-- {
+			nvs[j], nvs[j-1] = nvs[j-1], nvs[j]
+		}
+	}
+}
+
+// WithHARRecorder returns a context which makes Client record the requests made by
+// the clients from the factory already in the context into r.
+func WithHARRecorder(ctx context.Context, r *HARRecorder) context.Context {
+	f, opts := defaultClientFactory, ClientOptions{}
+	if v, ok := ctx.Value(clientFactoryKey{}).(clientFactoryValue); ok {
+		if v.f != nil {
+			f = v.f
+		}
+		opts = v.opts
+	}
+	return WithClientFactory(ctx, CachedClientFactory(r.ClientFactory(f)), opts)
+}
+
+// WithHARReplayer returns a context which makes Client replay requests from r.
+func WithHARReplayer(ctx context.Context, r *HARReplayer) context.Context {
+	opts := ClientOptions{}
+	if v, ok := ctx.Value(clientFactoryKey{}).(clientFactoryValue); ok {
+		opts = v.opts
+	}
+	return WithClientFactory(ctx, CachedClientFactory(r.ClientFactory()), opts)
+}
+
+// SaveHAR saves the requests recorded by r into a file (creating the directory if
+// needed) if there were any.
+func SaveHAR(r *HARRecorder, fn string) error {
+	har := r.HAR()
+	if len(har.Log.Entries) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		return err
+	}
+	return har.Save(fn)
+}
@@ -0,0 +1,133 @@
+package h
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHAR(t *testing.T) {
+	n := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		switch r.URL.Path {
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("hello " + r.URL.Query().Get("q")))
+		case "/binary":
+			w.Write([]byte{0xff, 0x00, 0xfe})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "jiup-har")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "test.har")
+
+	rec := NewHARRecorder(nil)
+	rctx := WithClientFactory(context.Background(), rec.ClientFactory(NewClient), ClientOptions{})
+	for _, p := range []string{"/text?q=world", "/binary", "/missing"} {
+		_, _, _, err := GetURL(rctx, nil, s.URL+p, nil, []int{http.StatusOK})
+		assert.NoError(t, err, p)
+	}
+	assert.Len(t, rec.HAR().Log.Entries, 3)
+	if !assert.NoError(t, rec.HAR().Save(fn)) {
+		return
+	}
+
+	replayer, err := LoadHARReplayer(fn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	s.Close()
+	before := n
+
+	pctx := WithClientFactory(context.Background(), replayer.ClientFactory(), ClientOptions{})
+	for _, tc := range []struct {
+		path string
+		body []byte
+		code int
+	}{
+		{"/text?q=world", []byte("hello world"), http.StatusOK},
+		{"/binary", []byte{0xff, 0x00, 0xfe}, http.StatusOK},
+		{"/missing", []byte("404 page not found\n"), http.StatusNotFound},
+	} {
+		buf, code, _, err := GetURL(pctx, nil, s.URL+tc.path, nil, []int{http.StatusOK})
+		if assert.NoError(t, err, tc.path) {
+			assert.Equal(t, tc.body, buf, tc.path)
+			assert.Equal(t, tc.code, code, tc.path)
+		}
+	}
+	assert.Equal(t, before, n, "should not make requests when replaying")
+
+	_, _, _, err = GetURL(pctx, nil, s.URL+"/text?q=other", nil, []int{http.StatusOK})
+	assert.Error(t, err, "should fail on unrecorded requests")
+
+	empty, err := LoadHARReplayer(filepath.Join(dir, "nonexistent.har"))
+	if assert.NoError(t, err) {
+		_, err := empty.RoundTrip(httptest.NewRequest("GET", s.URL+"/text", nil))
+		assert.Error(t, err)
+	}
+}
+
+func TestHARRecorderSensitiveHeaders(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	}))
+	defer s.Close()
+
+	req := httptest.NewRequest("GET", s.URL, nil)
+	req.RequestURI = ""
+	req.Header.Set("Authorization", "token secret")
+	req.Header.Set("Proxy-Authorization", "Basic secret")
+	req.Header.Set("Cookie", "session=secret")
+	req.Header.Set("x-api-key", "secret")
+	req.Header.Set("Accept", "text/plain")
+
+	rec := NewHARRecorder(nil)
+	resp, err := rec.RoundTrip(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	resp.Body.Close()
+	assert.NotEmpty(t, resp.Header.Get("Set-Cookie"), "should not change the response")
+
+	es := rec.HAR().Log.Entries
+	if !assert.Len(t, es, 1) {
+		return
+	}
+	assert.Equal(t, []HARNameValue{{"Accept", "text/plain"}}, es[0].Request.Headers)
+	for _, hdr := range es[0].Response.Headers {
+		assert.NotEqual(t, "Set-Cookie", hdr.Name)
+	}
+}
+
+func TestHARReplayerOrder(t *testing.T) {
+	entry := func(body string) HAREntry {
+		var e HAREntry
+		e.Request.Method, e.Request.URL = "GET", "https://example.com/"
+		e.Response.Status, e.Response.Content.Text = http.StatusOK, body
+		return e
+	}
+	r := NewHARReplayer(&HAR{Log: HARLog{Entries: []HAREntry{entry("1"), entry("2")}}})
+	for _, exp := range []string{"1", "2", "2"} {
+		resp, err := r.RoundTrip(httptest.NewRequest("GET", "https://example.com/", nil))
+		if assert.NoError(t, err) {
+			buf, _ := ioutil.ReadAll(resp.Body)
+			assert.Equal(t, exp, string(buf))
+		}
+	}
+}
@@ -2,98 +2,103 @@ package h
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
 )
 
-type resps struct {
-	Buf  []byte
-	Code int
-	OK   bool
-	Err  error
-}
-type reqi string
-
-var cache = map[reqi]resps{}
-
-func mkreqi(url string, headers map[string]string, acceptableStatuses []int) reqi {
-	return reqi(fmt.Sprintf("%#v;;;%#v;;;%#v", url, headers, acceptableStatuses))
-}
-
-// GetURL gets a url. The client is optional.
-func GetURL(c *http.Client, url string, headers map[string]string, acceptableStatuses []int) ([]byte, int, bool, error) {
-	ri := mkreqi(url, headers, acceptableStatuses)
-	if r, ok := cache[ri]; ok {
-		return r.Buf, r.Code, r.OK, r.Err
+// GetURL gets a url. The client is optional (see Client). Responses are cached in
+// memory by the client and request (see memCache) if the client is from a
+// CachedClientFactory, but errors are not.
+func GetURL(ctx context.Context, client *http.Client, url string, headers map[string]string, acceptableStatuses []int) ([]byte, int, bool, error) {
+	if client == nil {
+		var err error
+		if client, err = Client(ctx, ClientOptions{}); err != nil {
+			return nil, 0, false, c.NewError(c.CategoryValidation, url, err)
+		}
 	}
 
-	if c == nil {
-		c = &http.Client{
-			Timeout: time.Duration(time.Second * 10),
+	fetch := func() ([]byte, int, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, 0, c.NewError(c.CategoryValidation, url, err)
 		}
-	}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		cache[ri] = resps{nil, 0, false, err}
-		return nil, 0, false, err
-	}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
 
-	for k, v := range headers {
-		req.Header.Set(k, v)
-	}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, 0, c.NewError(c.RequestCategory(err), url, err)
+		}
+		defer resp.Body.Close()
 
-	resp, err := c.Do(req)
-	if err != nil {
-		cache[ri] = resps{nil, 0, false, err}
-		return nil, 0, false, err
+		buf, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, 0, c.NewError(c.RequestCategory(err), url, err)
+		}
+		return buf, resp.StatusCode, nil
 	}
-	defer resp.Body.Close()
 
-	buf, err := ioutil.ReadAll(resp.Body)
+	// the client is part of the key so responses aren't shared between clients
+	// which may behave differently (e.g. when recording or replaying), and other
+	// clients can't be identified, so their responses aren't cached
+	var buf []byte
+	var code int
+	var err error
+	if id, ok := clientID(client); ok {
+		buf, code, err = memoryCache.Do(ctx, fmt.Sprintf("%d ", id)+memCacheKey("GET", url, headers), fetch)
+	} else {
+		buf, code, err = fetch()
+	}
 	if err != nil {
-		cache[ri] = resps{nil, 0, false, err}
 		return nil, 0, false, err
 	}
 
 	a := false
 	for _, s := range acceptableStatuses {
-		if s == resp.StatusCode {
+		if s == code {
 			a = true
 			break
 		}
 	}
-
-	cache[ri] = resps{buf, resp.StatusCode, a, nil}
-	return buf, resp.StatusCode, a, nil
+	return buf, code, a, nil
 }
 
 // GetDoc gets a goquery doc from a url.
-func GetDoc(c *http.Client, url string, headers map[string]string, acceptableStatuses []int) (*goquery.Document, error) {
-	buf, s, a, err := GetURL(c, url, headers, acceptableStatuses)
+func GetDoc(ctx context.Context, client *http.Client, url string, headers map[string]string, acceptableStatuses []int) (*goquery.Document, error) {
+	buf, s, a, err := GetURL(ctx, client, url, headers, acceptableStatuses)
 	if err != nil {
 		return nil, err
 	} else if !a {
-		return nil, fmt.Errorf("unexpected response status: %d", s)
+		return nil, c.Errorf(c.CategoryHTTPStatus, url, "unexpected response status: %d", s)
 	}
-	return goquery.NewDocumentFromReader(bytes.NewReader(buf))
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, c.NewError(c.CategoryParse, url, err)
+	}
+	return doc, nil
 }
 
 // GetJSON gets a JSON document from a url.
-func GetJSON(c *http.Client, url string, headers map[string]string, acceptableStatuses []int, out interface{}) error {
-	buf, s, a, err := GetURL(c, url, headers, acceptableStatuses)
+func GetJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, acceptableStatuses []int, out interface{}) error {
+	buf, s, a, err := GetURL(ctx, client, url, headers, acceptableStatuses)
 	if err != nil {
 		return err
 	} else if !a {
-		return fmt.Errorf("unexpected response status: %d", s)
+		return c.Errorf(c.CategoryHTTPStatus, url, "unexpected response status: %d", s)
+	}
+	if err := json.Unmarshal(buf, out); err != nil {
+		return c.NewError(c.CategoryParse, url, err)
 	}
-	return json.Unmarshal(buf, out)
+	return nil
 }
 
 // ResolveURL resolves a relative url.
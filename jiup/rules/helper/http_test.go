@@ -2,7 +2,9 @@ package h
 
 import (
 	"bytes"
+	"context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -23,7 +25,7 @@ func TestGetURL(t *testing.T) {
 		{nil, "http://httpbin.org/status/400", map[string]string{}, []int{200, 400}, []byte(""), 400, true, false},
 		{nil, "http://httpbin.org/status/400", map[string]string{}, []int{200}, []byte(""), 400, false, false},
 	} {
-		buf, code, ok, err := GetURL(c.C, c.URL, c.Headers, c.AcceptableStatuses)
+		buf, code, ok, err := GetURL(context.Background(), c.C, c.URL, c.Headers, c.AcceptableStatuses)
 		if c.HasError {
 			assert.Error(t, err)
 			continue
@@ -34,16 +36,35 @@ func TestGetURL(t *testing.T) {
 	}
 }
 
+func TestGetURLContext(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	}))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, _, err := GetURL(ctx, nil, s.URL+"/context", map[string]string{}, []int{200})
+	assert.Error(t, err)
+
+	buf, code, ok, err := GetURL(context.Background(), nil, s.URL+"/context", map[string]string{}, []int{200})
+	assert.NoError(t, err, "errors from a cancelled context should not be cached")
+	assert.Equal(t, 200, code)
+	assert.True(t, ok)
+	assert.Equal(t, "hello world", string(buf))
+}
+
 func TestGetDoc(t *testing.T) {
-	doc, err := GetDoc(nil, "http://httpbin.org/html", map[string]string{}, []int{200})
+	doc, err := GetDoc(context.Background(), nil, "http://httpbin.org/html", map[string]string{}, []int{200})
 	assert.NoError(t, err)
 	assert.Equal(t, "Herman Melville - Moby-Dick", doc.Find("h1").Text())
 
-	doc, err = GetDoc(nil, "http://httpbin.org/404/html", map[string]string{}, []int{200, 404})
+	doc, err = GetDoc(context.Background(), nil, "http://httpbin.org/404/html", map[string]string{}, []int{200, 404})
 	assert.NoError(t, err)
 	assert.Equal(t, "Not Found", doc.Find("h1").Text())
 
-	doc, err = GetDoc(nil, "http://httpbin.org/404/html", map[string]string{}, []int{200})
+	doc, err = GetDoc(context.Background(), nil, "http://httpbin.org/404/html", map[string]string{}, []int{200})
 	assert.Error(t, err)
 }
 
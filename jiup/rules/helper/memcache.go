@@ -0,0 +1,124 @@
+package h
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultMemoryCacheSize is the byte budget for the in-memory response cache used
+// by GetURL.
+const DefaultMemoryCacheSize = 64 * 1024 * 1024
+
+var memoryCache = newMemCache(DefaultMemoryCacheSize)
+
+// memCache is an in-memory response cache with a LRU byte budget, which also
+// de-duplicates concurrent identical requests. Errors are never cached.
+type memCache struct {
+	mu       sync.Mutex
+	budget   int64
+	size     int64
+	lru      *list.List // of *memEntry, most recently used first
+	entries  map[string]*list.Element
+	inflight map[string]*memCall
+}
+
+type memEntry struct {
+	key  string
+	buf  []byte
+	code int
+}
+
+type memCall struct {
+	done chan struct{}
+	buf  []byte
+	code int
+	err  error
+}
+
+func newMemCache(budget int64) *memCache {
+	return &memCache{
+		budget:   budget,
+		lru:      list.New(),
+		entries:  map[string]*list.Element{},
+		inflight: map[string]*memCall{},
+	}
+}
+
+// memCacheKey returns the key for a request.
+func memCacheKey(method, url string, headers map[string]string) string {
+	hs := []string{}
+	for k, v := range headers {
+		hs = append(hs, strings.ToLower(k)+": "+v)
+	}
+	sort.Strings(hs)
+	return method + " " + url + "\n" + strings.Join(hs, "\n")
+}
+
+// Do returns the cached response for the key, or calls fetch (once for concurrent
+// calls with the same key) and caches the result if it succeeded and the status
+// is not a server error or 429 (which are likely to be temporary).
+func (m *memCache) Do(ctx context.Context, key string, fetch func() ([]byte, int, error)) ([]byte, int, error) {
+	for {
+		m.mu.Lock()
+		if el, ok := m.entries[key]; ok {
+			m.lru.MoveToFront(el)
+			e := el.Value.(*memEntry)
+			m.mu.Unlock()
+			return e.buf, e.code, nil
+		}
+
+		if call, ok := m.inflight[key]; ok {
+			m.mu.Unlock()
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return nil, 0, ctx.Err()
+			}
+			if (errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded)) && ctx.Err() == nil {
+				continue // the other caller's context was done, so try again with ours
+			}
+			return call.buf, call.code, call.err
+		}
+
+		call := &memCall{done: make(chan struct{})}
+		m.inflight[key] = call
+		m.mu.Unlock()
+
+		call.buf, call.code, call.err = fetch()
+
+		m.mu.Lock()
+		delete(m.inflight, key)
+		if call.err == nil && call.code < 500 && call.code != 429 {
+			m.add(key, call.buf, call.code)
+		}
+		m.mu.Unlock()
+		close(call.done)
+
+		return call.buf, call.code, call.err
+	}
+}
+
+// add adds an entry and evicts the least recently used ones until it is within
+// the budget. The lock must be held.
+func (m *memCache) add(key string, buf []byte, code int) {
+	if int64(len(buf)) > m.budget {
+		return
+	}
+	if el, ok := m.entries[key]; ok {
+		m.size -= int64(len(el.Value.(*memEntry).buf))
+		m.lru.Remove(el)
+	}
+	m.entries[key] = m.lru.PushFront(&memEntry{key, buf, code})
+	m.size += int64(len(buf))
+	for m.size > m.budget {
+		el := m.lru.Back()
+		e := el.Value.(*memEntry)
+		m.lru.Remove(el)
+		delete(m.entries, e.key)
+		m.size -= int64(len(e.buf))
+	}
+}
@@ -0,0 +1,147 @@
+package h
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemCache(t *testing.T) {
+	m := newMemCache(10)
+	ctx := context.Background()
+
+	n := 0
+	fetch := func(body string, code int, err error) func() ([]byte, int, error) {
+		return func() ([]byte, int, error) {
+			n++
+			return []byte(body), code, err
+		}
+	}
+
+	buf, code, err := m.Do(ctx, "a", fetch("aaaa", 200, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaa", string(buf))
+	assert.Equal(t, 200, code)
+
+	buf, _, _ = m.Do(ctx, "a", fetch("other", 200, nil))
+	assert.Equal(t, "aaaa", string(buf), "response should be cached")
+	assert.Equal(t, 1, n)
+
+	_, _, err = m.Do(ctx, "err", fetch("", 0, errors.New("network error")))
+	assert.Error(t, err)
+	_, _, err = m.Do(ctx, "err", fetch("", 200, nil))
+	assert.NoError(t, err, "errors should not be cached")
+
+	m.Do(ctx, "503", fetch("", 503, nil))
+	m.Do(ctx, "503", fetch("", 503, nil))
+	assert.Equal(t, 5, n, "server errors should not be cached")
+
+	m.Do(ctx, "404", fetch("", 404, nil))
+	m.Do(ctx, "404", fetch("", 404, nil))
+	assert.Equal(t, 6, n, "client errors should be cached")
+
+	// LRU eviction
+	m.Do(ctx, "b", fetch("bbbb", 200, nil)) // a, b = 8 bytes
+	m.Do(ctx, "a", fetch("", 200, nil))     // a is now the most recently used
+	m.Do(ctx, "c", fetch("cccc", 200, nil)) // b should be evicted
+	assert.Equal(t, int64(8), m.size)
+	_, ok := m.entries["b"]
+	assert.False(t, ok, "least recently used entry should be evicted")
+	_, ok = m.entries["a"]
+	assert.True(t, ok)
+
+	m.Do(ctx, "big", fetch("0123456789a", 200, nil))
+	_, ok = m.entries["big"]
+	assert.False(t, ok, "entries larger than the budget should not be cached")
+	assert.Equal(t, int64(8), m.size)
+}
+
+func TestMemCacheSingleflight(t *testing.T) {
+	m := newMemCache(DefaultMemoryCacheSize)
+
+	var n int32
+	release := make(chan struct{})
+	fetch := func() ([]byte, int, error) {
+		atomic.AddInt32(&n, 1)
+		<-release
+		return []byte("ok"), 200, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf, _, err := m.Do(context.Background(), "key", fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, "ok", string(buf))
+		}()
+	}
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), n, "concurrent identical requests should only be fetched once")
+}
+
+func TestMemCacheCancelledLeader(t *testing.T) {
+	m := newMemCache(DefaultMemoryCacheSize)
+
+	started := make(chan struct{})
+	lctx, cancel := context.WithCancel(context.Background())
+	go m.Do(lctx, "key", func() ([]byte, int, error) {
+		close(started)
+		<-lctx.Done()
+		return nil, 0, lctx.Err()
+	})
+	<-started
+
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		cancel()
+	}()
+	buf, _, err := m.Do(context.Background(), "key", func() ([]byte, int, error) {
+		return []byte("ok"), 200, nil
+	})
+	assert.NoError(t, err, "a cancelled leader should not fail other callers")
+	assert.Equal(t, "ok", string(buf))
+}
+
+func TestMemCacheKey(t *testing.T) {
+	assert.Equal(t,
+		memCacheKey("GET", "https://example.com", map[string]string{"Accept": "a", "X-Test": "b"}),
+		memCacheKey("GET", "https://example.com", map[string]string{"x-test": "b", "accept": "a"}))
+	assert.NotEqual(t,
+		memCacheKey("GET", "https://example.com", nil),
+		memCacheKey("GET", "https://example.com", map[string]string{"Accept": "application/json"}))
+}
+
+func TestGetURLStatuses(t *testing.T) {
+	var n int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}))
+	defer s.Close()
+
+	_, code, ok, err := GetURL(context.Background(), nil, s.URL+"/statuses", nil, []int{200})
+	assert.NoError(t, err)
+	assert.Equal(t, 404, code)
+	assert.False(t, ok)
+
+	buf, code, ok, err := GetURL(context.Background(), nil, s.URL+"/statuses", nil, []int{200, 404})
+	assert.NoError(t, err)
+	assert.Equal(t, 404, code)
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(string(buf), "not found"))
+
+	assert.Equal(t, int32(1), n, "the acceptable statuses should not be part of the cache key")
+}
@@ -0,0 +1,285 @@
+package h
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+)
+
+// rangeChunkSize is the minimum amount of data fetched by each range request.
+// Readers such as archive/zip do many small reads, so they are batched into
+// larger requests.
+const rangeChunkSize = 64 * 1024
+
+// RangeReader is an io.ReaderAt for a remote file which uses HTTP Range requests
+// to only download the parts which are read. If the server does not support
+// range requests, the whole file is downloaded once and read from memory.
+type RangeReader struct {
+	ctx  context.Context
+	c    *http.Client
+	url  string
+	size int64
+
+	full []byte // the whole file if the server ignored the range header
+
+	chunk    []byte // the last fetched chunk
+	chunkOff int64
+
+	// Requests is the number of requests made (including the initial one).
+	Requests int
+	// Downloaded is the number of body bytes downloaded.
+	Downloaded int64
+}
+
+// NewRangeReader opens a remote file for partial reading. The client is optional (see Client).
+// The context is used for all requests, including the ones made by ReadAt.
+func NewRangeReader(ctx context.Context, client *http.Client, url string) (*RangeReader, error) {
+	if client == nil {
+		var err error
+		if client, err = Client(ctx, ClientOptions{Timeout: time.Second * 60}); err != nil {
+			return nil, c.NewError(c.CategoryValidation, url, err)
+		}
+	}
+
+	r := &RangeReader{ctx: ctx, c: client, url: url}
+
+	resp, err := r.get(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Use the final url for the following requests to skip any redirects.
+	r.url = resp.Request.URL.String()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		_, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err == nil && size < 0 {
+			err = fmt.Errorf("unknown length in content-range %#v", resp.Header.Get("Content-Range"))
+		}
+		if err != nil {
+			return nil, c.NewError(c.CategoryParse, url, err)
+		}
+		r.size = size
+		n, _ := io.Copy(ioutil.Discard, resp.Body)
+		r.Downloaded += n
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The first byte doesn't exist, so the file must be empty (e.g. bytes */0).
+		if _, size, err := parseContentRange(resp.Header.Get("Content-Range")); err != nil || size != 0 {
+			return nil, c.Errorf(c.CategoryHTTPStatus, url, "unexpected response status: %d", resp.StatusCode)
+		}
+	case http.StatusOK:
+		// The server ignored the range header, so fall back to the full file.
+		buf, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, c.NewError(c.RequestCategory(err), url, err)
+		}
+		r.full = buf
+		r.size = int64(len(buf))
+		r.Downloaded += int64(len(buf))
+	default:
+		return nil, c.Errorf(c.CategoryHTTPStatus, url, "unexpected response status: %d", resp.StatusCode)
+	}
+
+	return r, nil
+}
+
+// Size returns the total size of the remote file.
+func (r *RangeReader) Size() int64 {
+	return r.size
+}
+
+// Partial returns true if the server supports range requests.
+func (r *RangeReader) Partial() bool {
+	return r.full == nil
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *RangeReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.full != nil {
+		n := copy(p, r.full[off:])
+		if n < len(p) {
+			return n, io.EOF
+		}
+		return n, nil
+	}
+
+	n := 0
+	for n < len(p) && off+int64(n) < r.size {
+		cur := off + int64(n)
+		if r.chunk == nil || cur < r.chunkOff || cur >= r.chunkOff+int64(len(r.chunk)) {
+			if err := r.fetch(cur, len(p)-n); err != nil {
+				return n, err
+			}
+			if r.full != nil {
+				// The server stopped honouring range requests.
+				return r.ReadAt(p, off)
+			}
+		}
+		n += copy(p[n:], r.chunk[cur-r.chunkOff:])
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// fetch downloads a chunk starting at off which is at least min bytes long.
+func (r *RangeReader) fetch(off int64, min int) error {
+	l := int64(min)
+	if l < rangeChunkSize {
+		l = rangeChunkSize
+	}
+	end := off + l - 1
+	if end >= r.size {
+		end = r.size - 1
+	}
+
+	resp, err := r.get(off, end)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return c.NewError(c.RequestCategory(err), r.url, err)
+	}
+	r.Downloaded += int64(len(buf))
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, _, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return c.NewError(c.CategoryParse, r.url, err)
+		}
+		if start != off {
+			return c.Errorf(c.CategoryParse, r.url, "requested range starting at %d, got %d", off, start)
+		}
+		if len(buf) == 0 {
+			// otherwise ReadAt would keep requesting the same range
+			return c.NewError(c.CategoryParse, r.url, io.ErrUnexpectedEOF)
+		}
+		r.chunk, r.chunkOff = buf, off
+	case http.StatusOK:
+		r.full, r.size = buf, int64(len(buf))
+		r.chunk = nil
+	default:
+		return c.Errorf(c.CategoryHTTPStatus, r.url, "unexpected response status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (r *RangeReader) get(start, end int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.ctx, "GET", r.url, nil)
+	if err != nil {
+		return nil, c.NewError(c.CategoryValidation, r.url, err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	// Transparent compression would make the offsets meaningless.
+	req.Header.Set("Accept-Encoding", "identity")
+
+	r.Requests++
+	resp, err := r.c.Do(req)
+	if err != nil {
+		return nil, c.NewError(c.RequestCategory(err), r.url, err)
+	}
+	return resp, nil
+}
+
+// parseContentRange gets the first byte position and the complete length from a
+// Content-Range header (e.g. bytes 0-0/1234). The position is -1 for an unsatisfied
+// range (e.g. bytes */1234), and the length is -1 if it is unknown (e.g. bytes 0-0/*).
+func parseContentRange(cr string) (start, size int64, err error) {
+	spl := strings.SplitN(cr, "/", 2)
+	if len(spl) != 2 || !strings.HasPrefix(spl[0], "bytes ") {
+		return 0, 0, fmt.Errorf("invalid content-range %#v", cr)
+	}
+	if rng := strings.TrimPrefix(spl[0], "bytes "); rng == "*" {
+		start = -1
+	} else if start, err = strconv.ParseInt(strings.SplitN(rng, "-", 2)[0], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid range in content-range %#v: %v", cr, err)
+	}
+	if spl[1] == "*" {
+		return start, -1, nil
+	}
+	if size, err = strconv.ParseInt(spl[1], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid length in content-range %#v: %v", cr, err)
+	}
+	return start, size, nil
+}
+
+// ZipEntries gets the names of the files in a remote zip archive. Only the
+// central directory is downloaded if the server supports range requests. The
+// client is optional.
+func ZipEntries(ctx context.Context, client *http.Client, url string) ([]string, error) {
+	r, err := NewRangeReader(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := zip.NewReader(r, r.Size())
+	if err != nil {
+		return nil, c.Errorf(readCategory(err), url, "could not read zip: %w", err)
+	}
+
+	names := make([]string, len(zr.File))
+	for i, f := range zr.File {
+		names[i] = f.Name
+	}
+	return names, nil
+}
+
+// MissingZipEntries returns the paths which do not exist in a remote zip archive.
+// Paths are compared case-insensitively, and backslashes are treated as slashes
+// (like the paths in the registry). The client is optional.
+func MissingZipEntries(ctx context.Context, client *http.Client, url string, paths []string) ([]string, error) {
+	names, err := ZipEntries(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+
+	norm := func(p string) string {
+		return strings.ToLower(strings.TrimPrefix(strings.Replace(p, "\\", "/", -1), "/"))
+	}
+
+	exists := map[string]bool{}
+	for _, name := range names {
+		exists[norm(name)] = true
+	}
+
+	missing := []string{}
+	for _, p := range paths {
+		if !exists[norm(p)] {
+			missing = append(missing, p)
+		}
+	}
+	return missing, nil
+}
+
+// readCategory returns the category of an error from parsing a remote file, which is
+// either from the requests or from the file being invalid.
+func readCategory(err error) c.ErrorCategory {
+	if cat := c.Category(err); cat != c.CategoryOther {
+		return cat
+	}
+	return c.CategoryParse
+}
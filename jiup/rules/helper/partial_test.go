@@ -0,0 +1,183 @@
+package h
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testZip(t *testing.T) []byte {
+	buf := bytes.NewBuffer(nil)
+	zw := zip.NewWriter(buf)
+	for _, f := range []struct {
+		Name string
+		Size int
+	}{
+		{"bin/test.exe", 1024 * 1024},
+		{"README.txt", 100},
+		{"lib/test.dll", 512 * 1024},
+	} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: zip.Store})
+		assert.NoError(t, err)
+		data := make([]byte, f.Size)
+		rand.Read(data)
+		_, err = w.Write(data)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func serve(buf []byte, ranges bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/file", http.StatusFound)
+			return
+		}
+		if r.URL.Path != "/file" {
+			http.NotFound(w, r)
+			return
+		}
+		if !ranges {
+			w.Write(buf)
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(buf))
+	}))
+}
+
+func TestRangeReader(t *testing.T) {
+	buf := testZip(t)
+	for _, ranges := range []bool{true, false} {
+		s := serve(buf, ranges)
+
+		r, err := NewRangeReader(context.Background(), nil, s.URL+"/redirect")
+		assert.NoError(t, err)
+		assert.Equal(t, ranges, r.Partial())
+		assert.Equal(t, int64(len(buf)), r.Size())
+
+		for _, c := range []struct {
+			Off int64
+			Len int
+		}{
+			{0, 10},
+			{int64(len(buf)) - 10, 10},
+			{1000, rangeChunkSize * 3},
+			{1005, 20},
+		} {
+			p := make([]byte, c.Len)
+			n, err := r.ReadAt(p, c.Off)
+			assert.NoError(t, err)
+			assert.Equal(t, c.Len, n)
+			assert.Equal(t, buf[c.Off:c.Off+int64(c.Len)], p)
+		}
+
+		p := make([]byte, 20)
+		n, err := r.ReadAt(p, int64(len(buf))-10)
+		assert.Equal(t, io.EOF, err)
+		assert.Equal(t, 10, n)
+
+		s.Close()
+	}
+
+	s := serve(buf, true)
+	defer s.Close()
+	_, err := NewRangeReader(context.Background(), nil, s.URL+"/404")
+	assert.Error(t, err)
+}
+
+func TestZipEntries(t *testing.T) {
+	buf := testZip(t)
+	for _, ranges := range []bool{true, false} {
+		s := serve(buf, ranges)
+
+		names, err := ZipEntries(context.Background(), nil, s.URL+"/file")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"bin/test.exe", "README.txt", "lib/test.dll"}, names)
+
+		missing, err := MissingZipEntries(context.Background(), nil, s.URL+"/file", []string{"bin\\Test.exe", "readme.txt", "lib/other.dll"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"lib/other.dll"}, missing)
+
+		s.Close()
+	}
+
+	s := serve(buf, true)
+	defer s.Close()
+
+	r, err := NewRangeReader(context.Background(), nil, s.URL+"/file")
+	assert.NoError(t, err)
+	_, err = zip.NewReader(r, r.Size())
+	assert.NoError(t, err)
+	assert.True(t, r.Downloaded < int64(len(buf))/10, "should only download the central directory")
+
+	s = serve([]byte("not a zip"), true)
+	defer s.Close()
+	_, err = ZipEntries(context.Background(), nil, s.URL+"/file")
+	assert.Error(t, err)
+}
+
+func TestRangeReaderEmpty(t *testing.T) {
+	s := serve([]byte{}, true)
+	defer s.Close()
+
+	r, err := NewRangeReader(context.Background(), nil, s.URL+"/file")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(0), r.Size())
+		n, err := r.ReadAt(make([]byte, 10), 0)
+		assert.Equal(t, io.EOF, err)
+		assert.Equal(t, 0, n)
+	}
+}
+
+func TestRangeReaderInvalid(t *testing.T) {
+	buf := testZip(t)
+	for _, tc := range []struct {
+		name string
+		eof  bool
+		fn   func(w http.ResponseWriter, start, end int)
+	}{
+		{"empty body", true, func(w http.ResponseWriter, start, end int) {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(buf)))
+			w.WriteHeader(http.StatusPartialContent)
+		}},
+		{"wrong start", false, func(w http.ResponseWriter, start, end int) {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", end-start, len(buf)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(buf[:end-start+1])
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var start, end int
+				fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+				if start == 0 && end == 0 {
+					http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(buf))
+					return
+				}
+				tc.fn(w, start, end)
+			}))
+			defer s.Close()
+
+			r, err := NewRangeReader(context.Background(), nil, s.URL)
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, err = r.ReadAt(make([]byte, 10), 100)
+			assert.Error(t, err)
+			if tc.eof {
+				assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+			}
+		})
+	}
+}
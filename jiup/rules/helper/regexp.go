@@ -1,8 +1,81 @@
 package h
 
-import "regexp"
+import (
+	"regexp"
+)
 
 // Re is an alias for regexp.MustCompile.
 func Re(str string) *regexp.Regexp {
 	return regexp.MustCompile(str)
 }
+
+// FindGroups finds the first match of a regexp, and returns a result assembled from
+// the capture groups along with the named capture groups. The result is:
+//
+//   - the format rendered as a template (see ExecTemplate) with the named capture
+//     groups (e.g. {{.Name}}), if the format is not empty.
+//   - the capture group with the specified name, if there is one.
+//   - the first capture group.
+//
+// It returns false if there isn't a match, if the format could not be rendered, or if
+// the result is empty.
+func FindGroups(re *regexp.Regexp, s, name, format string) (res string, groups map[string]string, ok bool) {
+	return matchGroups(re, re.FindStringSubmatch(s), name, format)
+}
+
+// FindVersion finds the first match of a regexp for a version. The version is
+// assembled as described in FindGroups, using the capture group named Version if
+// there is one. All other named capture groups are returned as fields.
+func FindVersion(re *regexp.Regexp, s, format string) (version string, fields map[string]string, ok bool) {
+	return matchVersion(re, re.FindStringSubmatch(s), format)
+}
+
+// FindAllVersions is like FindVersion, but returns every match with a non-empty version.
+func FindAllVersions(re *regexp.Regexp, s, format string) (versions []string, fields []map[string]string) {
+	for _, m := range re.FindAllStringSubmatch(s, -1) {
+		if version, f, ok := matchVersion(re, m, format); ok {
+			versions = append(versions, version)
+			fields = append(fields, f)
+		}
+	}
+	return versions, fields
+}
+
+func matchVersion(re *regexp.Regexp, m []string, format string) (version string, fields map[string]string, ok bool) {
+	version, fields, ok = matchGroups(re, m, "Version", format)
+	if ok && format == "" {
+		if i := re.SubexpIndex("Version"); i > 0 {
+			delete(fields, "Version")
+		} else if n := re.SubexpNames()[1]; n != "" {
+			delete(fields, n)
+		}
+	}
+	return version, fields, ok
+}
+
+func matchGroups(re *regexp.Regexp, m []string, name, format string) (res string, groups map[string]string, ok bool) {
+	if len(m) < 2 {
+		return "", nil, false
+	}
+
+	groups = map[string]string{}
+	for i, n := range re.SubexpNames() {
+		if n != "" {
+			groups[n] = m[i]
+		}
+	}
+
+	switch {
+	case format != "":
+		var err error
+		if res, err = ExecTemplate(format, groups); err != nil {
+			return "", groups, false
+		}
+	case name != "" && re.SubexpIndex(name) > 0:
+		res = m[re.SubexpIndex(name)]
+	default:
+		res = m[1]
+	}
+
+	return res, groups, res != ""
+}
@@ -0,0 +1,74 @@
+package h
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindGroups(t *testing.T) {
+	for _, c := range []struct {
+		Re     string
+		Str    string
+		Name   string
+		Format string
+		Res    string
+		Groups map[string]string
+		OK     bool
+	}{
+		{"v([0-9.]+)", "test v1.2.3 test", "", "", "1.2.3", map[string]string{}, true},
+		{"v([0-9.]+)", "test", "", "", "", nil, false},
+		{"v", "test v test", "", "", "", nil, false},
+		{"href=\"(?P<Base>[^\"]+)/(?P<URL>[^\"/]+.exe)\"", "<a href=\"dl/test.exe\">", "URL", "", "test.exe", map[string]string{"Base": "dl", "URL": "test.exe"}, true},
+		{"href=\"(?P<Base>[^\"]+)/(?P<URL>[^\"/]+.exe)\"", "<a href=\"dl/test.exe\">", "", "", "dl", map[string]string{"Base": "dl", "URL": "test.exe"}, true},
+		{"ccsetup(?P<major>[0-9])(?P<minor>[0-9]+)", "ccsetup582.exe", "", "{{.major}}.{{.minor}}", "5.82", map[string]string{"major": "5", "minor": "82"}, true},
+		{"ccsetup(?P<major>[0-9])(?P<minor>[0-9]*)", "ccsetup5.exe", "", "{{.minor}}", "", map[string]string{"major": "5", "minor": ""}, false},
+		{"v(?P<v>[0-9.]+)", "v1.2.3", "", "{{.v | replace \".\" \"_\"}}", "1_2_3", map[string]string{"v": "1.2.3"}, true},
+		{"v(?P<v>[0-9.]+)", "v1.2.3", "", "{{.missing}}", "", map[string]string{"v": "1.2.3"}, false},
+	} {
+		res, groups, ok := FindGroups(Re(c.Re), c.Str, c.Name, c.Format)
+		assert.Equal(t, c.OK, ok, c.Re)
+		assert.Equal(t, c.Res, res, c.Re)
+		assert.Equal(t, c.Groups, groups, c.Re)
+	}
+}
+
+func TestFindVersion(t *testing.T) {
+	for _, c := range []struct {
+		Re      string
+		Str     string
+		Format  string
+		Version string
+		Fields  map[string]string
+		OK      bool
+	}{
+		{"v([0-9.]+)", "test v1.2.3 test", "", "1.2.3", map[string]string{}, true},
+		{"v([0-9.]+)", "test", "", "", nil, false},
+		{"v([0-9.]*)", "test v test", "", "", map[string]string{}, false},
+		{"v", "test v test", "", "", nil, false},
+		{"install/(?P<Version>[0-9.]+)/android-studio-ide-(?P<Build>[0-9.]+)-windows.exe", "install/3.1.1.0/android-studio-ide-173.4697961-windows.exe", "", "3.1.1.0", map[string]string{"Build": "173.4697961"}, true},
+		{"(?P<Build>[0-9]+)-(?P<Version>[0-9.]+)", "12-1.0", "", "1.0", map[string]string{"Build": "12"}, true},
+		{"(?P<Name>[a-z]+)-([0-9.]+)", "photon-1.0", "", "photon", map[string]string{}, true},
+		{"ccsetup(?P<major>[0-9])(?P<minor>[0-9]+)", "ccsetup582.exe", "{{.major}}.{{.minor}}", "5.82", map[string]string{"major": "5", "minor": "82"}, true},
+	} {
+		version, fields, ok := FindVersion(Re(c.Re), c.Str, c.Format)
+		assert.Equal(t, c.OK, ok, c.Re)
+		assert.Equal(t, c.Version, version, c.Re)
+		assert.Equal(t, c.Fields, fields, c.Re)
+	}
+}
+
+func TestFindAllVersions(t *testing.T) {
+	versions, fields := FindAllVersions(Re("python-(?P<Version>[0-9.]+(?:rc[0-9]+)?)-(?P<Arch>[a-z0-9]+)\\.exe"), `
+		python-3.8.6-amd64.exe
+		python-3.9.0rc2-amd64.exe
+		python-.exe
+		python-2.7.18-win32.exe
+	`, "")
+	assert.Equal(t, []string{"3.8.6", "3.9.0rc2", "2.7.18"}, versions)
+	assert.Equal(t, []map[string]string{{"Arch": "amd64"}, {"Arch": "amd64"}, {"Arch": "win32"}}, fields)
+
+	versions, fields = FindAllVersions(Re("v([0-9.]+)"), "test", "")
+	assert.Nil(t, versions)
+	assert.Nil(t, fields)
+}
@@ -0,0 +1,108 @@
+package h
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// TemplateFuncs contains the functions available in templates. The value being
+// operated on is always the last argument, so they can be used in pipelines
+// (e.g. {{.Version | replace "." "_"}}).
+var TemplateFuncs = template.FuncMap{
+	"split": func(sep, s string) []string {
+		return strings.Split(s, sep)
+	},
+	"join": func(sep string, a []string) string {
+		return strings.Join(a, sep)
+	},
+	"replace": func(old, new, s string) string {
+		return strings.Replace(s, old, new, -1)
+	},
+	"trimPrefix": func(prefix, s string) string {
+		return strings.TrimPrefix(s, prefix)
+	},
+	"trimSuffix": func(suffix, s string) string {
+		return strings.TrimSuffix(s, suffix)
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"major": func(version string) string {
+		return versionComponent(version, 0)
+	},
+	"minor": func(version string) string {
+		return versionComponent(version, 1)
+	},
+	"patch": func(version string) string {
+		return versionComponent(version, 2)
+	},
+	"component": func(n int, version string) string {
+		return versionComponent(version, n)
+	},
+	"first": func(n int, version string) string {
+		spl := strings.Split(version, ".")
+		if n < len(spl) {
+			spl = spl[:n]
+		}
+		return strings.Join(spl, ".")
+	},
+	"pad": func(n int, version string) string {
+		spl := strings.Split(version, ".")
+		for len(spl) < n {
+			spl = append(spl, "0")
+		}
+		return strings.Join(spl, ".")
+	},
+	"zeroPad": func(n int, s string) string {
+		for len(s) < n {
+			s = "0" + s
+		}
+		return s
+	},
+}
+
+// versionComponent returns the nth dot-separated component of a version, or an
+// empty string if it doesn't have one.
+func versionComponent(version string, n int) string {
+	spl := strings.Split(version, ".")
+	if n < 0 || n >= len(spl) {
+		return ""
+	}
+	return spl[n]
+}
+
+// ParseTemplate parses a template with TemplateFuncs. Referencing a missing
+// value is an error when executing it.
+func ParseTemplate(tmpl string) (*template.Template, error) {
+	return template.New("").Funcs(TemplateFuncs).Option("missingkey=error").Parse(tmpl)
+}
+
+// ExecTemplate renders a template (see ParseTemplate) with the provided data.
+func ExecTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := ParseTemplate(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("could not parse template %#v: %v", tmpl, err)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := t.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("could not render template %#v: %v", tmpl, err)
+	}
+	return buf.String(), nil
+}
+
+// VersionTemplateData returns the data for rendering a template for a version.
+// It contains the fields, Version, and the aliases for common transformations
+// of it (VersionU, VersionD, Version0 and VersionN).
+func VersionTemplateData(version string, fields map[string]string) map[string]string {
+	data := map[string]string{}
+	for k, v := range fields {
+		data[k] = v
+	}
+	data["Version"] = version
+	data["VersionU"] = strings.Replace(version, ".", "_", -1)
+	data["VersionD"] = strings.Replace(version, ".", "-", -1)
+	data["Version0"] = strings.Split(version, ".")[0]
+	data["VersionN"] = strings.Replace(version, ".", "", -1)
+	return data
+}
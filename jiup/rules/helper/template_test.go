@@ -0,0 +1,50 @@
+package h
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecTemplate(t *testing.T) {
+	for _, c := range []struct {
+		Tmpl     string
+		Version  string
+		Fields   map[string]string
+		Res      string
+		HasError bool
+	}{
+		{"{{.Version}}", "1.2.3", nil, "1.2.3", false},
+		{"{{.VersionU}}", "1.2.3", nil, "1_2_3", false},
+		{"{{.VersionD}}", "1.2.3", nil, "1-2-3", false},
+		{"{{.Version0}}", "1.2.3", nil, "1", false},
+		{"{{.VersionN}}", "1.2.3", nil, "123", false},
+		{"{{.Build}}", "1.2.3", map[string]string{"Build": "173.4697961"}, "173.4697961", false},
+		{"{{.Version}}", "1.2.3", map[string]string{"Version": "overridden"}, "1.2.3", false},
+		{"{{split \".\" .Version | join \"_\"}}", "1.2.3", nil, "1_2_3", false},
+		{"{{.Version | replace \".\" \"\"}}", "1.2.3", nil, "123", false},
+		{"{{trimPrefix \"v\" .Version}}", "v1.2.3", nil, "1.2.3", false},
+		{"{{trimSuffix \"-rc\" .Version}}", "1.2.3-rc", nil, "1.2.3", false},
+		{"{{lower .Version}}-{{upper .Version}}", "1.0b", nil, "1.0b-1.0B", false},
+		{"Blender{{major .Version}}.{{minor .Version}}", "2.93.1", nil, "Blender2.93", false},
+		{"{{patch .Version}}", "2.93.1", nil, "1", false},
+		{"{{patch .Version}}", "2.93", nil, "", false},
+		{"{{component 3 .Version}}", "1.2.3.4", nil, "4", false},
+		{"{{first 2 .Version}}", "1.2.3.4", nil, "1.2", false},
+		{"{{first 5 .Version}}", "1.2", nil, "1.2", false},
+		{"{{pad 3 .Version}}", "1.2", nil, "1.2.0", false},
+		{"{{pad 1 .Version}}", "1.2", nil, "1.2", false},
+		{"{{zeroPad 2 (minor .Version)}}", "1.2", nil, "02", false},
+		{"{{.Missing}}", "1.2.3", nil, "", true},
+		{"{{.Version", "1.2.3", nil, "", true},
+		{"{{unknown .Version}}", "1.2.3", nil, "", true},
+	} {
+		res, err := ExecTemplate(c.Tmpl, VersionTemplateData(c.Version, c.Fields))
+		if c.HasError {
+			assert.Error(t, err, c.Tmpl)
+			continue
+		}
+		assert.NoError(t, err, c.Tmpl)
+		assert.Equal(t, c.Res, res, c.Tmpl)
+	}
+}
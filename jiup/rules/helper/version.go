@@ -0,0 +1,100 @@
+package h
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// CompareVersions compares two versions, and returns -1 if a < b, 0 if a == b, and
+// 1 if a > b. Versions are split into numeric and non-numeric parts (ignoring
+// separators), and numeric parts are compared numerically. A version with an
+// additional non-numeric part (e.g. 1.0-rc1 or 1.0beta) is older than one without.
+func CompareVersions(a, b string) int {
+	ap, bp := versionParts(a), versionParts(b)
+	for i := 0; i < len(ap) || i < len(bp); i++ {
+		switch {
+		case i >= len(ap):
+			if isNumeric(bp[i]) {
+				return -1
+			}
+			return 1
+		case i >= len(bp):
+			if isNumeric(ap[i]) {
+				return 1
+			}
+			return -1
+		}
+
+		an, bn := isNumeric(ap[i]), isNumeric(bp[i])
+		switch {
+		case an && bn:
+			// compare without parsing to allow arbitrarily long numbers (e.g. dates)
+			ai, bi := strings.TrimLeft(ap[i], "0"), strings.TrimLeft(bp[i], "0")
+			if len(ai) != len(bi) {
+				if len(ai) < len(bi) {
+					return -1
+				}
+				return 1
+			}
+			if c := strings.Compare(ai, bi); c != 0 {
+				return c
+			}
+		case an:
+			return 1
+		case bn:
+			return -1
+		default:
+			if c := strings.Compare(strings.ToLower(ap[i]), strings.ToLower(bp[i])); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+// MaxVersion returns the index of the highest version which matches the include
+// regexp and does not match the exclude regexp (either can be nil), or -1 if none do.
+func MaxVersion(versions []string, include, exclude *regexp.Regexp) int {
+	max := -1
+	for i, version := range versions {
+		if include != nil && !include.MatchString(version) {
+			continue
+		}
+		if exclude != nil && exclude.MatchString(version) {
+			continue
+		}
+		if max == -1 || CompareVersions(version, versions[max]) > 0 {
+			max = i
+		}
+	}
+	return max
+}
+
+// versionParts splits a version into runs of digits and letters.
+func versionParts(version string) []string {
+	parts := []string{}
+	cur := []rune{}
+	for _, r := range version {
+		if !unicode.IsDigit(r) && !unicode.IsLetter(r) {
+			if len(cur) > 0 {
+				parts = append(parts, string(cur))
+				cur = cur[:0]
+			}
+			continue
+		}
+		if len(cur) > 0 && unicode.IsDigit(cur[0]) != unicode.IsDigit(r) {
+			parts = append(parts, string(cur))
+			cur = cur[:0]
+		}
+		cur = append(cur, r)
+	}
+	if len(cur) > 0 {
+		parts = append(parts, string(cur))
+	}
+	return parts
+}
+
+func isNumeric(part string) bool {
+	return part != "" && unicode.IsDigit([]rune(part)[0])
+}
@@ -0,0 +1,60 @@
+package h
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, c := range []struct {
+		A, B string
+		Res  int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.00", 0},
+		{"1.0", "1_0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"2.0", "10.0", -1},
+		{"1.0", "1.0.1", -1},
+		{"1.0.1", "1.0", 1},
+		{"1.0-rc1", "1.0", -1},
+		{"1.0", "1.0beta", 1},
+		{"1.0-beta", "1.0-rc", -1},
+		{"1.0-rc1", "1.0-rc2", -1},
+		{"1.0-RC2", "1.0-rc2", 0},
+		{"1.0b", "1.0.1", -1},
+		{"20180406214816", "20180406214817", -1},
+		{"v1.2", "v1.10", -1},
+	} {
+		assert.Equal(t, c.Res, CompareVersions(c.A, c.B), "%s vs %s", c.A, c.B)
+		assert.Equal(t, -c.Res, CompareVersions(c.B, c.A), "%s vs %s", c.B, c.A)
+	}
+}
+
+func TestMaxVersion(t *testing.T) {
+	versions := []string{"2.7.18", "3.9.0-rc2", "3.8.6", "3.10.0b1", "3.8.10"}
+	for _, c := range []struct {
+		Include string
+		Exclude string
+		Res     int
+	}{
+		{"", "", 3},
+		{"", "[a-z]", 4},
+		{"^2\\.", "", 0},
+		{"^3\\.9\\.", "", 1},
+		{"^4\\.", "", -1},
+	} {
+		var include, exclude = Re("."), Re("^$")
+		if c.Include != "" {
+			include = Re(c.Include)
+		}
+		if c.Exclude != "" {
+			exclude = Re(c.Exclude)
+		}
+		assert.Equal(t, c.Res, MaxVersion(versions, include, exclude), "include=%s exclude=%s", c.Include, c.Exclude)
+	}
+	assert.Equal(t, 3, MaxVersion(versions, nil, nil))
+	assert.Equal(t, -1, MaxVersion(nil, nil, nil))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/just-install/just-install-updater-go/jiup/rules"
+	"github.com/just-install/just-install-updater-go/jiup/rules/declarative"
+	"github.com/just-install/just-install-updater-go/jiup/rules/lint"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	errorsOnly := pflag.BoolP("errors", "e", false, "Only show errors, not warnings")
+	rulesDir := pflag.String("rules-dir", "", "If set, rules will also be loaded from the JSON files in the specified directory, replacing the built-in rules for the same packages")
+	help := pflag.Bool("help", false, "Show this help text")
+	pflag.Parse()
+
+	if *help {
+		helpExit()
+	}
+
+	if *rulesDir != "" {
+		// lint errors in the rules are returned as load errors
+		set, err := declarative.Load(*rulesDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading rules:\n%v\n", err)
+			os.Exit(1)
+		}
+		rules.Default.Merge(set)
+	}
+
+	pkgs := pflag.Args()
+	if len(pkgs) == 0 {
+		pkgs = rules.Default.List()
+	}
+	sort.Strings(pkgs)
+
+	errs, warnings := 0, 0
+	for _, p := range pkgs {
+		r, ok := rules.Default.Get(p)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Warning: no rule for %s\n", p)
+			continue
+		}
+		for _, problem := range lint.Rule(p, r.VDesc, r.DDesc) {
+			if problem.Severity == lint.SeverityError {
+				errs++
+			} else if warnings++; *errorsOnly {
+				continue
+			}
+			fmt.Printf("%s\n", problem)
+		}
+	}
+
+	fmt.Printf("\nSummary: %d rules, %d errors, %d warnings\n", len(pkgs), errs, warnings)
+	if errs > 0 {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func helpExit() {
+	fmt.Fprintf(os.Stderr, "Usage: lint-rules [options] [packages...]\n\n")
+	pflag.PrintDefaults()
+	os.Exit(1)
+}
@@ -0,0 +1,328 @@
+// Package lint checks the extractors of rules for mistakes which would otherwise only
+// be found when running them (e.g. a regexp without a capture group for the version),
+// using their descriptors (see c.Descriptor).
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+)
+
+// Severity is how serious a problem is.
+type Severity string
+
+// Severities.
+const (
+	SeverityError   Severity = "error"   // the extractor can't work
+	SeverityWarning Severity = "warning" // the extractor may not work as intended
+)
+
+// Problem is a mistake found in a rule.
+type Problem struct {
+	Package string `json:"package"`
+	// Extractor is the path to the extractor in the rule (e.g. download > w.SplitDownload
+	// > x64: d.HTML).
+	Extractor string   `json:"extractor"`
+	Severity  Severity `json:"severity"`
+	Message   string   `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s: %s", p.Package, p.Extractor, p.Severity, p.Message)
+}
+
+// Errors returns the problems with SeverityError.
+func Errors(problems []Problem) []Problem {
+	errs := []Problem{}
+	for _, p := range problems {
+		if p.Severity == SeverityError {
+			errs = append(errs, p)
+		}
+	}
+	return errs
+}
+
+// Rule checks the descriptors of the version and download extractors of a rule.
+func Rule(pkg string, version, download *c.Descriptor) []Problem {
+	l := &linter{pkg: pkg, rolling: version != nil && version.Kind == "v.Latest"}
+	if version != nil {
+		l.check("version", version)
+	}
+	if download != nil {
+		l.check("download", download)
+	}
+	return l.problems
+}
+
+// archs are the prefixes of the per-architecture params of the download extractors.
+var archs = []string{"x86", "x64", "arm64"}
+
+// groupKinds are the extractors which use a capture group of their regexps, and the
+// name of the capture group used instead of the first if present.
+var groupKinds = map[string]string{
+	"v.Regexp":        "Version",
+	"v.RegexpMax":     "Version",
+	"v.HTML":          "Version",
+	"v.HTMLMax":       "Version",
+	"v.GitHubTag":     "Version",
+	"v.GitHubRelease": "Version",
+	"d.Regexp":        "URL",
+	"d.HTML":          "URL",
+}
+
+// checks contains the checks for the params of each kind of extractor.
+var checks = map[string]func(l *linter, d *c.Descriptor){
+	"v.HTML":                   checkHTML,
+	"v.HTMLMax":                checkHTML,
+	"d.HTML":                   checkHTMLDownloads,
+	"d.Regexp":                 checkRegexpDownloads,
+	"d.GitHubRelease":          checkRegexpDownloads,
+	"d.AppVeyorArtifacts":      checkRegexpDownloads,
+	"d.Template":               checkTemplate,
+	"w.ClientOptions":          checkOf,
+	"w.ClientOptionsDownloads": checkOf,
+	"w.Timeout":                checkTimeout,
+	"w.TimeoutDownloads":       checkTimeout,
+	"w.Transform":              checkTransform,
+	"w.AppendToURL":            checkAppendToURL,
+	"w.FirstOf":                checkFirstOf,
+	"w.FirstOfDownloads":       checkFirstOf,
+	"w.Consensus":              checkConsensus,
+	"w.ConsensusDownloads":     checkConsensus,
+	"w.SplitDownload":          checkSplitDownload,
+}
+
+type linter struct {
+	pkg      string
+	rolling  bool
+	path     string
+	problems []Problem
+}
+
+func (l *linter) report(severity Severity, format string, a ...interface{}) {
+	l.problems = append(l.problems, Problem{
+		Package:   l.pkg,
+		Extractor: l.path,
+		Severity:  severity,
+		Message:   fmt.Sprintf(format, a...),
+	})
+}
+
+func (l *linter) check(path string, d *c.Descriptor) {
+	if d.Role != "" {
+		path += " > " + d.Role + ": " + d.Kind
+	} else {
+		path += " > " + d.Kind
+	}
+	l.path = path
+
+	if d.Kind != c.KindCustom {
+		checkURLs(l, d)
+		checkRegexps(l, d)
+		if check, ok := checks[d.Kind]; ok {
+			check(l, d)
+		}
+	}
+
+	for _, child := range d.Children {
+		l.check(path, child)
+	}
+}
+
+// checkURLs checks the url and template params.
+func checkURLs(l *linter, d *c.Descriptor) {
+	for _, name := range sortedParams(d) {
+		if name != "url" && !strings.HasSuffix(name, "Template") {
+			continue
+		}
+		u := d.Params[name]
+		switch {
+		case strings.HasPrefix(u, "{{"):
+		case !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://"):
+			l.report(SeverityError, "%s %q is not an http(s) url", name, u)
+		case strings.HasPrefix(u, "http://"):
+			l.report(SeverityWarning, "%s %q uses http (check if https works)", name, u)
+		}
+	}
+}
+
+// checkRegexps checks the capture groups of the regexps and the format which uses them.
+func checkRegexps(l *linter, d *c.Descriptor) {
+	group, usesGroup := groupKinds[d.Kind]
+	format := d.Params["format"]
+	named := map[string]string{}
+	for _, name := range sortedParams(d) {
+		if name != "regexp" && !strings.HasSuffix(name, "Regexp") {
+			continue
+		}
+		re, err := regexp.Compile(d.Params[name])
+		if err != nil {
+			l.report(SeverityError, "%s is invalid: %v", name, err)
+			continue
+		}
+		for _, n := range re.SubexpNames() {
+			if n != "" {
+				named[n] = "x"
+			}
+		}
+		if !usesGroup {
+			continue
+		}
+		switch {
+		case re.NumSubexp() == 0:
+			l.report(SeverityError, "%s %q has no capture group", name, re)
+		case format == "" && re.SubexpIndex(group) == -1 && unnamed(re) > 1 && re.SubexpNames()[1] == "":
+			l.report(SeverityWarning, "%s %q has %d capture groups, but only the first is used (use (?:...) for the others)", name, re, re.NumSubexp())
+		}
+	}
+	if format != "" {
+		if _, err := h.ParseTemplate(format); err != nil {
+			l.report(SeverityError, "format is invalid: %v", err)
+		} else if _, err := h.ExecTemplate(format, named); err != nil {
+			l.report(SeverityError, "format uses a capture group which the regexps don't have: %v", err)
+		}
+	}
+}
+
+func checkHTML(l *linter, d *c.Descriptor) {
+	if d.Params["selector"] == "" {
+		l.report(SeverityError, "selector is empty")
+	}
+	if d.Params["attr"] == "" {
+		l.report(SeverityError, "attr is empty")
+	}
+}
+
+func checkHTMLDownloads(l *linter, d *c.Descriptor) {
+	n := 0
+	for _, arch := range archs {
+		switch {
+		case d.Params[arch+"Selector"] != "":
+			n++
+			if d.Params[arch+"Attr"] == "" {
+				l.report(SeverityError, "%sAttr is empty", arch)
+			}
+		case d.Params[arch+"Regexp"] != "":
+			l.report(SeverityError, "%sRegexp is set without %sSelector", arch, arch)
+		}
+	}
+	if n == 0 {
+		l.report(SeverityError, "no selectors are set")
+	}
+}
+
+func checkRegexpDownloads(l *linter, d *c.Descriptor) {
+	for _, arch := range archs {
+		if d.Params[arch+"Regexp"] != "" {
+			return
+		}
+	}
+	l.report(SeverityError, "no regexps are set")
+}
+
+func checkTemplate(l *linter, d *c.Descriptor) {
+	n := 0
+	for _, arch := range archs {
+		tmpl, ok := d.Params[arch+"Template"]
+		if !ok {
+			continue
+		}
+		n++
+		if _, err := h.ParseTemplate(tmpl); err != nil {
+			l.report(SeverityError, "%sTemplate is invalid: %v", arch, err)
+		} else if !strings.Contains(tmpl, "{{") && !l.rolling {
+			l.report(SeverityError, "%sTemplate %q doesn't use the version", arch, tmpl)
+		}
+	}
+	if n == 0 {
+		l.report(SeverityError, "no templates are set")
+	}
+}
+
+func checkOf(l *linter, d *c.Descriptor) {
+	if len(children(d, "of")) == 0 {
+		l.report(SeverityError, "no extractor to wrap")
+	}
+}
+
+func checkTimeout(l *linter, d *c.Descriptor) {
+	checkOf(l, d)
+	if d.Params["timeout"] == "" {
+		l.report(SeverityError, "timeout is zero")
+	}
+}
+
+func checkTransform(l *linter, d *c.Descriptor) {
+	checkOf(l, d)
+	for _, t := range d.Children {
+		if t.Role == "transforms" {
+			return
+		}
+	}
+	l.report(SeverityWarning, "no transforms")
+}
+
+func checkAppendToURL(l *linter, d *c.Descriptor) {
+	checkOf(l, d)
+	if d.Params["suffix"] == "" {
+		l.report(SeverityWarning, "suffix is empty")
+	}
+}
+
+func checkFirstOf(l *linter, d *c.Descriptor) {
+	switch len(children(d, "all")) {
+	case 0:
+		l.report(SeverityError, "no extractors")
+	case 1:
+		l.report(SeverityWarning, "only one extractor")
+	}
+}
+
+func checkConsensus(l *linter, d *c.Descriptor) {
+	n, _ := strconv.Atoi(d.Params["n"])
+	if all := len(children(d, "all")); n < 1 || n > all {
+		l.report(SeverityError, "n is %d, but there are %d extractors", n, all)
+	}
+}
+
+func checkSplitDownload(l *linter, d *c.Descriptor) {
+	if len(d.Children) == 0 {
+		l.report(SeverityError, "no extractors")
+	}
+}
+
+func children(d *c.Descriptor, role string) []*c.Descriptor {
+	cs := []*c.Descriptor{}
+	for _, child := range d.Children {
+		if child.Role == role {
+			cs = append(cs, child)
+		}
+	}
+	return cs
+}
+
+// unnamed returns the number of unnamed capture groups in a regexp.
+func unnamed(re *regexp.Regexp) int {
+	n := 0
+	for _, name := range re.SubexpNames()[1:] {
+		if name == "" {
+			n++
+		}
+	}
+	return n
+}
+
+func sortedParams(d *c.Descriptor) []string {
+	names := []string{}
+	for name := range d.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
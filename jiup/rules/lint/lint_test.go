@@ -0,0 +1,106 @@
+package lint
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	d "github.com/just-install/just-install-updater-go/jiup/rules/download"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+	v "github.com/just-install/just-install-updater-go/jiup/rules/version"
+	w "github.com/just-install/just-install-updater-go/jiup/rules/wrapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRule(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		v        c.VersionExtractor
+		d        c.DownloadExtractor
+		problems []string
+	}{
+		{"ok",
+			v.Regexp("https://example.com", h.Re("Version ([0-9.]+)")),
+			d.Template("https://example.com/{{.Version}}.exe", ""),
+			nil,
+		},
+		{"groups",
+			v.RegexpF("https://example.com", h.Re("Version ([0-9]+)\\.([0-9]+)"), "{{.major}}"),
+			d.Regexp("https://example.com", h.Re("href=\"(.+\\.exe)\""), h.Re("href=\".+\\.exe\"")),
+			[]string{
+				`test: version > v.Regexp: error: format uses a capture group which the regexps don't have: could not render template "{{.major}}": template: :1:2: executing "" at <.major>: map has no entry for key "major"`,
+				`test: download > d.Regexp: error: x64Regexp "href=\".+\\.exe\"" has no capture group`,
+			},
+		},
+		{"unused groups",
+			v.Regexp("https://example.com", h.Re("Version ([0-9.]+) (beta)?")),
+			d.Regexp("https://example.com", h.Re("href=\"(?P<URL>(.+)\\.exe)\""), nil),
+			[]string{
+				`test: version > v.Regexp: warning: regexp "Version ([0-9.]+) (beta)?" has 2 capture groups, but only the first is used (use (?:...) for the others)`,
+			},
+		},
+		{"html",
+			v.HTML("https://example.com", "a", "", nil),
+			d.HTML("https://example.com", "", "a", "", "", h.Re("(.+)"), nil),
+			[]string{
+				"test: version > v.HTML: error: attr is empty",
+				"test: download > d.HTML: error: x86Regexp is set without x86Selector",
+				"test: download > d.HTML: error: x64Attr is empty",
+			},
+		},
+		{"transforms",
+			w.Transform(v.Regexp("https://example.com", h.Re("Version ([0-9.]+)"))),
+			d.Template("https://example.com/{{.Version}}.exe", ""),
+			[]string{
+				"test: version > w.Transform: warning: no transforms",
+			},
+		},
+		{"templates",
+			v.Regexp("http://example.com", h.Re("([0-9.]+)")),
+			w.SplitDownload(d.Template("https://example.com/latest.exe", ""), d.Template("", "example.com/{{.Version}}")),
+			[]string{
+				`test: version > v.Regexp: warning: url "http://example.com" uses http (check if https works)`,
+				`test: download > w.SplitDownload > x86: d.Template: error: x86Template "https://example.com/latest.exe" doesn't use the version`,
+				`test: download > w.SplitDownload > x64: d.Template: error: x64Template "example.com/{{.Version}}" is not an http(s) url`,
+			},
+		},
+		{"rolling",
+			v.Latest(),
+			d.Template("https://example.com/latest.exe", ""),
+			nil,
+		},
+		{"wrappers",
+			w.Consensus(3, v.Latest(), v.Latest()),
+			w.TimeoutDownloads(0, w.FirstOfDownloads(d.Regexp("https://example.com", nil, nil))),
+			[]string{
+				"test: version > w.Consensus: error: n is 3, but there are 2 extractors",
+				"test: download > w.TimeoutDownloads: error: timeout is zero",
+				"test: download > w.TimeoutDownloads > of: w.FirstOfDownloads: warning: only one extractor",
+				"test: download > w.TimeoutDownloads > of: w.FirstOfDownloads > all: d.Regexp: error: no regexps are set",
+			},
+		},
+		{"custom",
+			w.Timeout(time.Second, c.VersionExtractorFunc(func() (string, error) { return "1.0", nil })),
+			c.DownloadsExtractorFunc(func(context.Context, c.VersionInfo) (c.Downloads, error) { return nil, nil }),
+			nil,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var problems []string
+			for _, p := range Rule("test", c.DescribeVersion(tc.v), c.DescribeDownloads(tc.d)) {
+				problems = append(problems, p.String())
+			}
+			assert.Equal(t, tc.problems, problems)
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	problems := []Problem{
+		{Package: "a", Severity: SeverityWarning, Message: "warning"},
+		{Package: "b", Severity: SeverityError, Message: "error"},
+	}
+	assert.Equal(t, problems[1:], Errors(problems))
+	assert.Empty(t, Errors(problems[:1]))
+}
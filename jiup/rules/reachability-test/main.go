@@ -1,29 +1,42 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/just-install/just-install-updater-go/jiup/history"
+	"github.com/just-install/just-install-updater-go/jiup/ledger"
 	"github.com/just-install/just-install-updater-go/jiup/rules"
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/just-install/just-install-updater-go/jiup/rules/declarative"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+	w "github.com/just-install/just-install-updater-go/jiup/rules/wrapper"
 	"github.com/spf13/pflag"
 )
 
-// KnownBroken contains packages known to be broken.
-// Errors during the check will not count as a failure.
-var KnownBroken = []string{}
-
 const overwrite = "\x1b[1A\x1b[K\r      \r" // up 1, clear line, carriage return (fallback for when the escapes aren't supported)
 
 func main() {
 	//verbose := pflag.BoolP("verbose", "v", false, "Show more output")
 	nodownload := pflag.BoolP("no-download", "d", false, "Do not test downloadability")
 	downloadLinks := pflag.BoolP("download-links", "l", false, "Show download links")
-	writeBroken := pflag.StringP("write-broken", "b", "", "If set, broken rules will be written into the specified file")
+	checkVersions := pflag.Bool("check-versions", false, "Check that the download links contain the version")
+	timeout := pflag.Duration("timeout", 0, "The maximum time to spend on each extractor of a package (0 for no limit)")
+	proxy := pflag.String("proxy", "", "The proxy to use for HTTP requests (default is from the environment)")
+	userAgent := pflag.String("user-agent", "", "The User-Agent to use for HTTP requests")
+	recordHAR := pflag.String("record-har", "", "If set, the HTTP requests for each rule will be recorded into HAR files in the specified directory")
+	replayHAR := pflag.String("replay-har", "", "If set, the HTTP requests for each rule will be replayed from HAR files in the specified directory")
+	ledgerFile := pflag.StringP("ledger", "b", "", "If set, rules manually marked as broken in the specified ledger will be skipped, and broken rules will be recorded into it")
+	stateDir := pflag.String("state-dir", history.DefaultDir(), "The directory to record the health of the rules in across runs (see the health command)")
+	noHistory := pflag.Bool("no-history", false, "Do not record the health of the rules")
+	rulesDir := pflag.String("rules-dir", "", "If set, rules will also be loaded from the JSON files in the specified directory, replacing the built-in rules for the same packages")
 	help := pflag.Bool("help", false, "Show this help text")
 	pflag.Parse()
 
@@ -31,22 +44,89 @@ func main() {
 		helpExit()
 	}
 
-	working, broken, knownBroken := testAll(*nodownload, *downloadLinks, pflag.Args())
+	if *rulesDir != "" {
+		set, err := declarative.Load(*rulesDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to load rules:\n%v\n", err)
+			os.Exit(1)
+		}
+		rules.Default.Merge(set)
+	}
+
+	l := ledger.New()
+	if *ledgerFile != "" {
+		var err error
+		if l, err = ledger.Load(*ledgerFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to read ledger %q: %v\n", *ledgerFile, err)
+			os.Exit(1)
+		}
+	}
+
+	var hs *history.Store
+	if !*noHistory && *stateDir != "" && *replayHAR == "" {
+		var err error
+		if hs, err = history.Open(*stateDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to open state dir: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	start := time.Now()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx = h.WithClientFactory(ctx, nil, h.ClientOptions{Proxy: *proxy, UserAgent: *userAgent})
+	working, broken, knownBroken, timedOut, latency := testAll(ctx, *nodownload, *downloadLinks, *checkVersions, *timeout, *recordHAR, *replayHAR, l, pflag.Args())
+	interrupted := ctx.Err() != nil
+	stop()
+
+	groups := c.GroupByCategory(broken)
+	for _, cat := range c.ErrorCategories {
+		if len(groups[cat]) == 0 {
+			continue
+		}
+		fmt.Printf("\nBroken (%s):\n", cat)
+		for _, p := range groups[cat] {
+			fmt.Printf("  %s: %v\n", p, broken[p])
+		}
+	}
+
+	fmt.Printf("\nSummary: %d working, %d broken, %d known broken", len(working), len(broken), len(knownBroken))
+	if len(broken) > 0 {
+		fmt.Printf(" (%s)", c.FormatCategoryCounts(groups))
+	}
+	fmt.Printf("\n")
 
-	fmt.Printf("\nSummary: %d working, %d broken, %d known broken\n", len(working), len(broken), len(knownBroken))
+	if interrupted {
+		fmt.Printf("Interrupted.\n")
+		os.Exit(130)
+	}
 
-	if *writeBroken != "" {
-		arr := []string{}
-		for _, x := range knownBroken {
-			arr = append(arr, x+":"+"manually marked as broken")
+	if hs != nil {
+		results := []history.Result{}
+		run := history.RunID("reachability-test", start)
+		for p, l := range latency {
+			err := broken[p]
+			if err == nil {
+				err = timedOut[p]
+			}
+			results = append(results, history.NewResult(run, p, start, l, err))
 		}
-		for x, err := range broken {
-			arr = append(arr, x+":"+strings.ReplaceAll(err.Error(), "\n", " "))
+		if err := hs.AppendResults(results...); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record rule health: %v\n", err)
+		} else if err := hs.PruneResults(time.Now().Add(-history.MaxAge)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to prune rule health: %v\n", err)
 		}
-		sort.Strings(arr)
+	}
 
-		if err := ioutil.WriteFile(*writeBroken, []byte(strings.Join(arr, "\n")+"\n"), 0644); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: failed to write broken rules to %q: %v\n", *writeBroken, err)
+	if *ledgerFile != "" {
+		now := time.Now()
+		for _, p := range working {
+			l.Record(p, nil, now)
+		}
+		for p, err := range broken {
+			l.Record(p, err, now)
+		}
+		if err := l.Save(*ledgerFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write ledger %q: %v\n", *ledgerFile, err)
 			os.Exit(1)
 		}
 	}
@@ -64,10 +144,12 @@ func helpExit() {
 	os.Exit(1)
 }
 
-func testAll(nodownload, downloadLinks bool, packages []string) ([]string, map[string]error, []string) {
+func testAll(ctx context.Context, nodownload, downloadLinks, checkVersions bool, timeout time.Duration, recordHAR, replayHAR string, l *ledger.Ledger, packages []string) ([]string, map[string]error, []string, map[string]error, map[string]time.Duration) {
 	working := []string{}
 	broken := map[string]error{}
 	knownBroken := []string{}
+	timedOut := map[string]error{}
+	latency := map[string]time.Duration{}
 	// TODO: multithreaded for loop
 
 	allrules := []string{}
@@ -77,122 +159,164 @@ func testAll(nodownload, downloadLinks bool, packages []string) ([]string, map[s
 	sort.Strings(allrules)
 
 	for _, p := range allrules {
+		if ctx.Err() != nil {
+			break
+		}
+
 		vfn, dfn, ok := rules.GetRule(p)
 		if !ok {
 			panic("could not get rule which should exist: " + p)
 		}
+		if timeout > 0 {
+			vfn, dfn = w.Timeout(timeout, vfn).Structured(), w.TimeoutDownloads(timeout, dfn).Structured()
+		}
 
 		if len(packages) != 0 {
-			c := true
+			skip := true
 			for _, pp := range packages {
 				if pp == p {
-					c = false
+					skip = false
 				}
 			}
-			if c {
+			if skip {
 				continue
 			}
 		}
 
 		fmt.Printf("    %s: testing\n", p)
 
-		c := false
-		for _, kb := range KnownBroken {
-			if p == kb {
-				knownBroken = append(knownBroken, p)
-				fmt.Printf("%s -  %s: manually marked as broken\n", overwrite, p)
-				c = true
-				break
-			}
-		}
-		if c {
+		if e, ok := l.Skip(p, time.Now()); ok {
+			knownBroken = append(knownBroken, p)
+			fmt.Printf("%s -  %s: %v\n", overwrite, p, e.Err())
 			continue
 		}
 
-		version, err := vfn()
+		// the download tests are not recorded or replayed
+		rctx, save := ctx, func() {}
+		if replayHAR != "" {
+			r, err := h.LoadHARReplayer(filepath.Join(replayHAR, p+".har"))
+			if err != nil {
+				broken[p] = err
+				fmt.Printf("%s ✗  %s: %v\n", overwrite, p, broken[p])
+				continue
+			}
+			rctx = h.WithHARReplayer(ctx, r)
+		} else if recordHAR != "" {
+			r, fn := h.NewHARRecorder(nil), filepath.Join(recordHAR, p+".har")
+			rctx = h.WithHARRecorder(ctx, r)
+			save = func() {
+				if err := h.SaveHAR(r, fn); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to save recorded requests to %q: %v\n", fn, err)
+				}
+			}
+		}
+
+		vstart := time.Now()
+		vi, err := vfn(rctx)
+		save()
+		if ctx.Err() != nil {
+			break
+		}
+		latency[p] = time.Since(vstart)
 		if err != nil {
 			fmt.Printf("%s ✗  %s: %v\n", overwrite, p, err)
-			if strings.Contains(err.Error(), "Client.Timeout") {
+			if c.IsTimeout(err) {
 				fmt.Printf(" [IGNORING TIMEOUT]")
+				timedOut[p] = err
 			} else {
 				broken[p] = err
 			}
 			continue
 		}
+		version := vi.Version
 		if strings.TrimSpace(version) == "" {
-			broken[p] = errors.New("empty version")
+			broken[p] = c.Errorf(c.CategoryValidation, "", "empty version")
 			fmt.Printf("%s ✗  %s: %v\n", overwrite, p, broken[p])
 			continue
 		}
 		if strings.TrimSpace(version) != version {
-			broken[p] = errors.New("version has whitespace (probably a bad regexp)")
+			broken[p] = c.Errorf(c.CategoryValidation, "", "version has whitespace (probably a bad regexp)")
 			fmt.Printf("%s ✗  %s: %v\n", overwrite, p, broken[p])
 			continue
 		}
 		if strings.HasSuffix(version, ".") {
-			broken[p] = errors.New("version ends with a dot (probably a bad regexp)")
+			broken[p] = c.Errorf(c.CategoryValidation, "", "version ends with a dot (probably a bad regexp)")
 			fmt.Printf("%s ✗  %s: %v\n", overwrite, p, broken[p])
 			continue
 		}
 
-		x86dl, x64dl, err := dfn(version)
+		dstart := time.Now()
+		dls, err := dfn(rctx, vi)
+		save()
+		if ctx.Err() != nil {
+			break
+		}
+		latency[p] += time.Since(dstart)
 		if err != nil {
 			fmt.Printf("%s ✗  %s: %v\n", overwrite, p, err)
-			if strings.Contains(err.Error(), "Client.Timeout") {
+			if c.IsTimeout(err) {
 				fmt.Print(" [IGNORING TIMEOUT]")
+				timedOut[p] = err
 			} else {
 				broken[p] = err
 			}
 			continue
 		}
-		if x86dl == nil && x64dl == nil {
-			broken[p] = errors.New("one or both of x86 and x64 must be defined")
+		if len(dls) == 0 {
+			broken[p] = c.Errorf(c.CategoryValidation, "", "at least one of x86, x86_64 and arm64 must be defined")
 			fmt.Printf("%s ✗  %s: %v\n", overwrite, p, broken[p])
 			continue
 		}
-		if (x86dl != nil && strings.TrimSpace(*x86dl) == "") || (x64dl != nil && strings.TrimSpace(*x64dl) == "") {
-			broken[p] = errors.New("use nil if no link, not a blank string")
-			fmt.Printf("%s ✗  %s: %v\n", overwrite, p, broken[p])
-			continue
+
+		if checkVersions {
+			if err := rules.CheckVersion(p, vi, dls); err != nil {
+				broken[p] = err
+				fmt.Printf("%s ✗  %s: %v\n", overwrite, p, broken[p])
+				continue
+			}
 		}
 
 		res := fmt.Sprintf(" ✓  %s: %s", p, version)
-		for _, l := range []struct {
-			arch string
-			link *string
-		}{{"x86", x86dl}, {"x86_64", x64dl}} {
-			if l.link == nil {
+		for _, arch := range c.Archs {
+			dl, ok := dls[arch]
+			if !ok {
 				continue
 			}
-			if !strings.HasPrefix(*l.link, "http") {
-				broken[p] = fmt.Errorf("%s link (%s) does not start with http", l.arch, *l.link)
+			if strings.TrimSpace(dl.URL) == "" {
+				broken[p] = c.Errorf(c.CategoryValidation, "", "leave out the architecture if no link, not a blank string").WithArch(arch)
+				fmt.Printf("%s ✗  %s: %v\n", overwrite, p, broken[p])
+				break
+			}
+			if !strings.HasPrefix(dl.URL, "http") {
+				broken[p] = c.Errorf(c.CategoryValidation, dl.URL, "%s link does not start with http", arch).WithArch(arch)
 				fmt.Printf("%s ✗  %s: %v\n", overwrite, p, broken[p])
 				break
 			}
 			if !nodownload {
-				code, mime, err := testDL(*l.link)
+				code, mime, err := testDL(ctx, dl.URL)
 				if err != nil && !(p == "tightvnc" && strings.Contains(err.Error(), "connection reset")) {
 					fmt.Printf("%s ✗  %s: %v\n", overwrite, p, err)
-					if strings.Contains(err.Error(), "Client.Timeout") {
+					if c.IsTimeout(err) {
 						fmt.Print(" [IGNORING TIMEOUT]")
+						timedOut[p] = err
 					} else {
 						broken[p] = err
 					}
 					break
 				}
 				if code != 200 {
-					broken[p] = fmt.Errorf("%s download status code %d (%s)", l.arch, code, *l.link)
+					broken[p] = c.Errorf(c.CategoryHTTPStatus, dl.URL, "%s download status code %d", arch, code).WithArch(arch)
 					fmt.Printf("%s ✗  %s: %v\n", overwrite, p, broken[p])
 					break
 				}
-				if strings.HasPrefix(mime, "text/html") && !strings.Contains(*l.link, "sourceforge") && !strings.Contains(*l.link, "freefilesync") {
-					broken[p] = fmt.Errorf("%s download mime text/html", l.arch)
-					fmt.Printf("%s ✗  %s: %v (%s)\n", overwrite, p, broken[p], *l.link)
+				if strings.HasPrefix(mime, "text/html") && !strings.Contains(dl.URL, "sourceforge") && !strings.Contains(dl.URL, "freefilesync") {
+					broken[p] = c.Errorf(c.CategoryValidation, "", "%s download mime text/html", arch).WithArch(arch)
+					fmt.Printf("%s ✗  %s: %v (%s)\n", overwrite, p, broken[p], dl.URL)
 					break
 				}
 			}
 			if downloadLinks {
-				res = fmt.Sprintf("%s %s(%s)", res, l.arch, *l.link)
+				res = fmt.Sprintf("%s %s(%s)", res, arch, describeDL(dl))
 			} else {
 				res += "                        " // workaround for carriage returns
 			}
@@ -203,17 +327,37 @@ func testAll(nodownload, downloadLinks bool, packages []string) ([]string, map[s
 		}
 	}
 
-	return working, broken, knownBroken
+	return working, broken, knownBroken, timedOut, latency
+}
+
+// describeDL formats a download link with any metadata it has.
+func describeDL(dl *c.Download) string {
+	meta := []string{}
+	if dl.FileName != "" {
+		meta = append(meta, dl.FileName)
+	}
+	if dl.Size > 0 {
+		meta = append(meta, fmt.Sprintf("%d bytes", dl.Size))
+	}
+	if dl.Checksum != "" {
+		meta = append(meta, dl.Checksum)
+	}
+	if !dl.ReleaseDate.IsZero() {
+		meta = append(meta, dl.ReleaseDate.Format("2006-01-02"))
+	}
+	if len(meta) == 0 {
+		return dl.URL
+	}
+	return dl.URL + " [" + strings.Join(meta, ", ") + "]"
 }
 
-func testDL(url string) (code int, mime string, err error) {
-	resp, err := http.Get(url)
+func testDL(ctx context.Context, url string) (code int, mime string, err error) {
+	resp, err := doDL(ctx, "GET", url)
 	if err != nil {
-		resp, err = http.Head(url)
+		resp, err = doDL(ctx, "HEAD", url)
 		if err != nil {
 			return 0, "", err
 		}
-		defer resp.Body.Close()
 	}
 	defer resp.Body.Close()
 
@@ -222,3 +366,19 @@ func testDL(url string) (code int, mime string, err error) {
 
 	return code, mime, nil
 }
+
+func doDL(ctx context.Context, method, url string) (*http.Response, error) {
+	client, err := h.Client(ctx, h.ClientOptions{Timeout: time.Minute})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, c.NewError(c.CategoryValidation, url, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, c.NewError(c.RequestCategory(err), url, err)
+	}
+	return resp, nil
+}
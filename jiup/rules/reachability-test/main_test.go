@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -20,7 +21,7 @@ func TestTestDL(t *testing.T) {
 		{"http://127.0.0.1:3453", 0, "", true},
 		{"https://eu.httpbin.org/get", 200, "application/json", false},
 	} {
-		code, mime, err := testDL(c.URL)
+		code, mime, err := testDL(context.Background(), c.URL)
 		if c.HasError {
 			assert.Error(t, err)
 			continue
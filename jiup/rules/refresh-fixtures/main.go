@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+
+	"github.com/just-install/just-install-updater-go/jiup/rules"
+	"github.com/just-install/just-install-updater-go/jiup/rules/fixture"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+	w "github.com/just-install/just-install-updater-go/jiup/rules/wrapper"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	dir := pflag.String("dir", filepath.Join("jiup", "rules", "testdata", "fixtures"), "The directory containing the fixtures")
+	all := pflag.BoolP("all", "a", false, "Create fixtures for all rules, not only the ones which already have one")
+	missingList := pflag.String("missing-list", filepath.Join("jiup", "rules", "testdata", "fixtures-missing.txt"), "The list of packages without a recorded fixture, which recorded packages are removed from")
+	missing := pflag.BoolP("missing", "m", false, "Create fixtures for the packages in --missing-list")
+	timeout := pflag.Duration("timeout", 0, "The maximum time to spend on each extractor of a package (0 for no limit)")
+	proxy := pflag.String("proxy", "", "The proxy to use for HTTP requests (default is from the environment)")
+	userAgent := pflag.String("user-agent", "", "The User-Agent to use for HTTP requests")
+	help := pflag.Bool("help", false, "Show this help text")
+	pflag.Parse()
+
+	if *help {
+		helpExit()
+	}
+
+	packages := pflag.Args()
+	if len(packages) == 0 && *missing {
+		var err error
+		if packages, err = fixture.LoadMissing(*missingList); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: could not load list of missing fixtures: %v\n", err)
+			os.Exit(1)
+		}
+	} else if len(packages) == 0 {
+		for p := range rules.GetRules() {
+			if *all || fixture.Exists(filepath.Join(*dir, p)) {
+				packages = append(packages, p)
+			}
+		}
+		sort.Strings(packages)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx = h.WithClientFactory(ctx, nil, h.ClientOptions{Proxy: *proxy, UserAgent: *userAgent})
+
+	failed := 0
+	for _, p := range packages {
+		if ctx.Err() != nil {
+			fmt.Printf("Interrupted.\n")
+			os.Exit(130)
+		}
+
+		vfn, dfn, ok := rules.GetRule(p)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: no rule for %s\n", p)
+			os.Exit(1)
+		}
+		if *timeout > 0 {
+			vfn, dfn = w.Timeout(*timeout, vfn).Structured(), w.TimeoutDownloads(*timeout, dfn).Structured()
+		}
+
+		// record into a temporary directory so a failure doesn't remove the current fixture
+		tmp, err := ioutil.TempDir("", "jiup-fixture")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		f, err := fixture.Record(ctx, filepath.Join(tmp, p), vfn, dfn)
+		if err == nil {
+			err = replace(filepath.Join(tmp, p), filepath.Join(*dir, p))
+		}
+		if err == nil && *missingList != "" {
+			err = fixture.Unlist(*missingList, p)
+		}
+		os.RemoveAll(tmp)
+		if err != nil {
+			fmt.Printf(" ✗  %s: %v\n", p, err)
+			failed++
+			continue
+		}
+		fmt.Printf(" ✓  %s: %s (%d pages)\n", p, f.Version, len(f.Pages))
+	}
+
+	fmt.Printf("\nSummary: %d refreshed, %d failed\n", len(packages)-failed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func helpExit() {
+	fmt.Fprintf(os.Stderr, "Usage: refresh-fixtures [options] [packages...]\n\n")
+	pflag.PrintDefaults()
+	os.Exit(1)
+}
+
+// replace replaces the contents of dst with the files in src.
+func replace(src, dst string) error {
+	fis, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		buf, err := ioutil.ReadFile(filepath.Join(src, fi.Name()))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dst, fi.Name()), buf, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,10 +1,10 @@
 package rules
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"strings"
 
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
 	d "github.com/just-install/just-install-updater-go/jiup/rules/download"
 	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
 	v "github.com/just-install/just-install-updater-go/jiup/rules/version"
@@ -147,11 +147,10 @@ func init() {
 			"innerText",
 			h.Re("Download Blender ([0-9.]+)"),
 		),
-		func(version string) (*string, *string, error) {
-			versionSplit := strings.Split(version, ".")
-			x64 := fmt.Sprintf("https://download.blender.org/release/Blender%s.%s/blender-%s-windows-x64.msi", versionSplit[0], versionSplit[1], version)
-			return nil, &x64, nil
-		},
+		d.Template(
+			"",
+			"https://download.blender.org/release/Blender{{major .Version}}.{{minor .Version}}/blender-{{.Version}}-windows-x64.msi",
+		),
 	)
 	Rule("bcc",
 		v.GitHubRelease(
@@ -198,16 +197,11 @@ func init() {
 		),
 	)
 	Rule("ccleaner",
-		func() (string, error) {
-			version, err := v.Regexp(
-				"https://www.ccleaner.com/ccleaner/download/standard",
-				h.Re("ccsetup([0-9]+)"),
-			)()
-			if err != nil {
-				return "", err
-			}
-			return string(version[0]) + "." + string(version[1:]), nil
-		},
+		v.RegexpF(
+			"https://www.ccleaner.com/ccleaner/download/standard",
+			h.Re("ccsetup(?P<major>[0-9])(?P<minor>[0-9]+)"),
+			"{{.major}}.{{.minor}}",
+		),
 		d.HTMLA(
 			"https://www.ccleaner.com/ccleaner/download/standard",
 			"a[href$='.exe']:contains('start the download')",
@@ -230,11 +224,10 @@ func init() {
 			"https://sourceforge.net/projects/classicshell/files/",
 			h.Re("Classic Shell v([0-9.]+)"),
 		),
-		func(version string) (*string, *string, error) {
-			vu := strings.Replace(version, ".", "_", -1)
-
-			return h.StrPtr("https://sourceforge.net/projects/classicshell/files/Version%20" + version + "%20general%20release/ClassicShellSetup_" + vu + ".exe/download"), nil, nil
-		},
+		d.Template(
+			"https://sourceforge.net/projects/classicshell/files/Version%20{{.Version}}%20general%20release/ClassicShellSetup_{{.VersionU}}.exe/download",
+			"",
+		),
 	)
 	Rule("clementine-player",
 		v.GitHubRelease(
@@ -339,20 +332,20 @@ func init() {
 			"href",
 			h.Re("CrystalDiskInfo([0-9_]+)(?:Src)?.zip"),
 		)),
-		func(version string) (*string, *string, error) {
-			vu := strings.Replace(version, ".", "_", -1)
+		c.DownloadsExtractorFunc(func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+			vu := strings.Replace(version.Version, ".", "_", -1)
 			dls, err := v.HTML(
 				"https://osdn.net/projects/crystaldiskinfo/releases/",
 				"a.pref-download-btn.pref-download-btn[href]",
 				"href",
 				h.Re("downloads/([0-9]+/CrystalDiskInfo"+vu+"(?:Src)?).zip"),
-			)()
+			).Structured()(ctx)
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
-			dlp := strings.Replace(dls, "Src", "", -1)
-			return h.StrPtr("http://osdn.dl.osdn.jp/crystaldiskinfo/" + dlp + ".exe"), nil, nil
-		},
+			dlp := strings.Replace(dls.Version, "Src", "", -1)
+			return c.Downloads{c.ArchX86: {URL: "http://osdn.dl.osdn.jp/crystaldiskinfo/" + dlp + ".exe"}}, nil
+		}),
 	)
 	Rule("crystaldisk-mark",
 		v.HTML(
@@ -361,15 +354,11 @@ func init() {
 			"innerText",
 			h.Re("([0-9.]+)"),
 		),
-		func(version string) (*string, *string, error) {
-			vu := strings.Replace(version, ".", "_", -1)
-			x86, x64, err := d.HTMLA(
-				"https://osdn.net/dl/crystaldiskmark/CrystalDiskMark"+vu+".exe",
-				"a.mirror_link",
-				"",
-			)(version)
-			return x86, x64, err
-		},
+		d.HTMLA(
+			"https://osdn.net/dl/crystaldiskmark/CrystalDiskMark{{.VersionU}}.exe",
+			"a.mirror_link",
+			"",
+		),
 	)
 	Rule("cyberduck",
 		v.Regexp(
@@ -482,21 +471,22 @@ func init() {
 				"https://eclipse.org/downloads/eclipse-packages/",
 				h.Re("eclipse-"+edition+"-([a-zA-Z0-9]+-[a-zA-Z0-9]+-[a-zA-Z0-9]+)-win32"), // e.g. photon-R
 			),
-			func(version string) (*string, *string, error) {
-				_, x64, err := d.HTMLA(
+			c.DownloadsExtractorFunc(func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+				dls, err := d.HTMLA(
 					"https://eclipse.org/downloads/eclipse-packages/",
 					"",
 					".downloadLink-content .windows a[href*='?file='][href*='eclipse-"+edition+"-'][href$='-win32-x86_64.zip']",
-				)(version)
+				).Structured()(ctx, version)
 
 				if err != nil {
-					return nil, nil, err
+					return nil, err
 				}
 
-				*x64 = "http://ftp.osuosl.org/pub/eclipse" + strings.SplitN(strings.SplitN(*x64, "?file=", 2)[1], "&", 2)[0]
+				x64 := dls[c.ArchX86_64]
+				x64.URL = "http://ftp.osuosl.org/pub/eclipse" + strings.SplitN(strings.SplitN(x64.URL, "?file=", 2)[1], "&", 2)[0]
 
-				return nil, x64, nil
-			},
+				return c.Downloads{c.ArchX86_64: x64}, nil
+			}),
 		)
 	}
 	Rule("eig",
@@ -511,39 +501,37 @@ func init() {
 		),
 	)
 	Rule("emacs",
-		func() (string, error) {
+		c.VersionInfoExtractorFunc(func(ctx context.Context) (c.VersionInfo, error) {
 			majorVersion, err := v.HTML(
 				"https://ftp.gnu.org/gnu/emacs/windows/?C=N;O=D",
 				"a[href*='emacs-']",
 				"href",
 				h.Re("emacs-([0-9]+)"),
-			)()
+			).Structured()(ctx)
 			if err != nil {
-				return "", err
+				return c.VersionInfo{}, err
 			}
 
 			version, err := v.HTML(
-				"https://ftp.gnu.org/gnu/emacs/windows/emacs-"+majorVersion+"/?C=N;O=D",
+				"https://ftp.gnu.org/gnu/emacs/windows/emacs-"+majorVersion.Version+"/?C=N;O=D",
 				"a[href*='emacs-']",
 				"href",
 				h.Re("emacs-([0-9.]+)"),
-			)()
+			).Structured()(ctx)
 			if err != nil {
-				return "", err
+				return c.VersionInfo{}, err
 			}
 
-			if strings.Split(version, ".")[0] != majorVersion {
-				return "", errors.New("emacs rule needs to be updated")
+			if strings.Split(version.Version, ".")[0] != majorVersion.Version {
+				return c.VersionInfo{}, c.Errorf(c.CategoryConsistency, "", "emacs rule needs to be updated")
 			}
 
 			return version, nil
-		},
-		func(version string) (*string, *string, error) {
-			majorVersion := strings.Split(version, ".")[0]
-			x86 := fmt.Sprintf("https://ftp.gnu.org/gnu/emacs/windows/emacs-%s/emacs-%s-i686.zip", majorVersion, version)
-			x64 := fmt.Sprintf("https://ftp.gnu.org/gnu/emacs/windows/emacs-%s/emacs-%s-x86_64.zip", majorVersion, version)
-			return &x86, &x64, nil
-		},
+		}),
+		d.Template(
+			"https://ftp.gnu.org/gnu/emacs/windows/emacs-{{major .Version}}/emacs-{{.Version}}-i686.zip",
+			"https://ftp.gnu.org/gnu/emacs/windows/emacs-{{major .Version}}/emacs-{{.Version}}-x86_64.zip",
+		),
 	)
 	Rule("empoche",
 		v.HTML(
@@ -1390,13 +1378,13 @@ func init() {
 		),
 	)
 	Rule("seafile-client",
-		w.NoHTTPSForVersionExtractor(v.HTML(
+		w.ClientOptions(h.ClientOptions{Insecure: true}, v.HTML(
 			"https://www.seafile.com/en/download/",
 			".txt > h3:contains('Client for Windows')~a[href*='seafile'][href$='en.msi'].download-op",
 			"innerText",
 			h.Re("([0-9.]+)"),
 		)),
-		w.NoHTTPSForDownloadExtractor(d.HTML(
+		w.ClientOptionsDownloads(h.ClientOptions{Insecure: true}, d.HTML(
 			"https://www.seafile.com/en/download/",
 			".txt > h3:contains('Client for Windows')~a[href*='seafile'][href$='en.msi'].download-op",
 			"",
@@ -1542,13 +1530,13 @@ func init() {
 			"https://www.sumatrapdfreader.org/download-free-pdf-viewer",
 			h.Re("SumatraPDF-([0-9.]+)-"),
 		),
-		func(version string) (*string, *string, error) {
+		c.DownloadsExtractorFunc(func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
 			return d.HTMLA(
 				"https://www.sumatrapdfreader.org/download-free-pdf-viewer",
-				"a[href$='SumatraPDF-"+version+"-install.exe']",
-				"a[href$='SumatraPDF-"+version+"-64-install.exe']",
-			)(version)
-		},
+				"a[href$='SumatraPDF-"+version.Version+"-install.exe']",
+				"a[href$='SumatraPDF-"+version.Version+"-64-install.exe']",
+			).Structured()(ctx, version)
+		}),
 	)
 	Rule("syncthing",
 		v.GitHubRelease(
@@ -1614,38 +1602,42 @@ func init() {
 			"https://tortoisesvn.net/downloads.html",
 			h.Re("The current version is ([0-9.]+)"),
 		),
-		func(version string) (*string, *string, error) {
+		c.DownloadsExtractorFunc(func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
 			// Layer 1: Link to OSDN
-			x86, x64, err := d.HTMLA(
+			osdn, err := d.HTMLA(
 				"https://tortoisesvn.net/downloads.html",
 				"a[href^='https://osdn.net'][href*='win32-svn']",
 				"a[href^='https://osdn.net'][href*='x64-svn']",
-			)(version)
+			).Structured()(ctx, version)
 			if err != nil {
-				return nil, nil, err
+				return nil, err
+			}
+			x86, x64 := osdn.URL(c.ArchX86), osdn.URL(c.ArchX86_64)
+			if x86 == nil {
+				return nil, c.Errorf(c.CategorySelector, "https://tortoisesvn.net/downloads.html", "x86 link empty").WithArch(c.ArchX86)
 			}
 			if x64 == nil {
-				return nil, nil, errors.New("x64 link empty")
+				return nil, c.Errorf(c.CategorySelector, "https://tortoisesvn.net/downloads.html", "x64 link empty").WithArch(c.ArchX86_64)
 			}
 			// Layer 2: OSDN to redir link
-			x86, _, err = d.HTMLA(
+			x86dls, err := d.HTMLA(
 				*x86,
 				"a.mirror_link[href*='/frs/redir'][href*='win32-svn']",
 				"",
-			)(version)
+			).Structured()(ctx, version)
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
-			_, x64, err = d.HTMLA(
+			x64dls, err := d.HTMLA(
 				*x64,
 				"a",
 				"a.mirror_link[href*='/frs/redir'][href*='x64-svn']",
-			)(version)
+			).Structured()(ctx, version)
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
-			return x86, x64, nil
-		},
+			return c.Downloads{c.ArchX86: x86dls[c.ArchX86], c.ArchX86_64: x64dls[c.ArchX86_64]}, nil
+		}),
 	)
 	Rule("transmission",
 		v.Regexp(
@@ -1839,4 +1831,8 @@ func init() {
 			nil,
 		),
 	)
+
+	NoVersionCheck(
+		"bootnext", // the artifact links only contain the job id and bootnext.msi, not the build version
+	)
 }
@@ -1,15 +1,135 @@
 package rules
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
 
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/just-install/just-install-updater-go/jiup/rules/fixture"
+	"github.com/just-install/just-install-updater-go/jiup/rules/lint"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestValidity(t *testing.T) {
-	for p, r := range rules {
+	for p, r := range Default.rules {
 		assert.NotEmpty(t, p, "rule package name should not be empty")
 		assert.NotNil(t, r.V, "version extractor should not be nil")
 		assert.NotNil(t, r.D, "download extractor should not be nil")
 	}
 }
+
+func TestNoVersionCheck(t *testing.T) {
+	for p := range Default.noVersionCheck {
+		_, ok := Default.rules[p]
+		assert.True(t, ok, "version check disabled for %s, which does not have a rule", p)
+	}
+}
+
+// custom contains the rules which use custom functions, so they can't be fully described.
+var custom = map[string]bool{
+	"crystaldisk-info":   true,
+	"eclipse-committers": true,
+	"eclipse-cpp":        true,
+	"eclipse-java":       true,
+	"eclipse-jee":        true,
+	"eclipse-php":        true,
+	"emacs":              true,
+	"sumatrapdf":         true,
+	"tortoisesvn":        true,
+}
+
+func TestDescriptors(t *testing.T) {
+	sourceforge := map[string]bool{}
+	for p, r := range Default.rules {
+		if !assert.NotNil(t, r.VDesc, "%s: version descriptor should not be nil", p) || !assert.NotNil(t, r.DDesc, "%s: download descriptor should not be nil", p) {
+			continue
+		}
+		hasCustom := false
+		for _, desc := range []*c.Descriptor{r.VDesc, r.DDesc} {
+			desc.Walk(func(d *c.Descriptor) {
+				hasCustom = hasCustom || d.Kind == c.KindCustom
+			})
+			for _, host := range desc.Hosts() {
+				if host == "sourceforge.net" {
+					sourceforge[p] = true
+				}
+			}
+		}
+		assert.Equal(t, custom[p], hasCustom, "%s: unexpected custom extractors (update custom if a rule was changed): %s, %s", p, r.VDesc, r.DDesc)
+	}
+	assert.True(t, sourceforge["classic-shell"], "classic-shell should use sourceforge.net")
+	assert.False(t, sourceforge["7zip"], "7zip should not use sourceforge.net")
+}
+
+// TestLint checks the rules for lint errors, which Rule panics on, and logs the
+// warnings (which are only fixed if they are confirmed, e.g. https working).
+func TestLint(t *testing.T) {
+	for p, r := range Default.rules {
+		for _, problem := range lint.Rule(p, r.VDesc, r.DDesc) {
+			if problem.Severity == lint.SeverityError {
+				t.Errorf("%s", problem)
+			} else {
+				t.Logf("%s", problem)
+			}
+		}
+	}
+}
+
+// TestFixtures runs the rules against the saved pages in testdata/fixtures (which
+// can be refreshed with refresh-fixtures).
+func TestFixtures(t *testing.T) {
+	n := 0
+	for p, r := range Default.rules {
+		dir := filepath.Join("testdata", "fixtures", p)
+		if !fixture.Exists(dir) {
+			continue
+		}
+		n++
+		assert.NoError(t, fixture.Test(context.Background(), dir, r.V, r.D), p)
+	}
+	if n == 0 {
+		t.Skip("no fixtures")
+	}
+}
+
+// TestFixtureCoverage checks that every rule either has a fixture recorded from the
+// live sites, or is listed in testdata/fixtures-missing.txt, so the rules which aren't
+// tested against real saved pages stay the exception. Fixtures written by hand (i.e.
+// without a recording time) are still tested, but don't count.
+func TestFixtureCoverage(t *testing.T) {
+	list, err := fixture.LoadMissing(filepath.Join("testdata", "fixtures-missing.txt"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	missing := map[string]bool{}
+	for _, p := range list {
+		missing[p] = true
+	}
+
+	n := 0
+	for _, p := range Default.List() {
+		var recorded bool
+		if dir := filepath.Join("testdata", "fixtures", p); fixture.Exists(dir) {
+			f, err := fixture.Load(dir)
+			if !assert.NoError(t, err, p) {
+				continue
+			}
+			recorded = f.Recorded != ""
+		}
+		if recorded {
+			n++
+		}
+		switch {
+		case !recorded && !missing[p]:
+			t.Errorf("no recorded fixture for %s (record one with refresh-fixtures %s, or add it to testdata/fixtures-missing.txt)", p, p)
+		case recorded && missing[p]:
+			t.Errorf("%s has a recorded fixture, but is listed in testdata/fixtures-missing.txt", p)
+		}
+		delete(missing, p)
+	}
+	for p := range missing {
+		t.Errorf("%s is listed in testdata/fixtures-missing.txt, but does not have a rule", p)
+	}
+	t.Logf("%d of %d rules have recorded fixtures", n, len(Default.List()))
+}
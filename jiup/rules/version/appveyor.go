@@ -1,7 +1,7 @@
 package v
 
 import (
-	"errors"
+	"context"
 	"net/http"
 	"net/url"
 
@@ -10,26 +10,31 @@ import (
 )
 
 // AppVeyorBranch returns a version extractor for an AppVeyor branch.
-func AppVeyorBranch(repo, branch string) c.VersionExtractorFunc {
-	return func() (string, error) {
-		var build struct {
-			Build struct {
-				Version string
+func AppVeyorBranch(repo, branch string) c.DescribedVersion {
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("v.AppVeyorBranch", "repo", repo, "branch", branch, "url", h.DefaultBaseURLs.AppVeyor+"/api/projects/"+repo+"/branch/"+url.PathEscape(branch)),
+		F: func(ctx context.Context) (c.VersionInfo, error) {
+			var build struct {
+				Build struct {
+					Version string
+				}
 			}
-		}
 
-		if err := h.GetJSON(
-			nil,
-			"https://ci.appveyor.com/api/projects/"+repo+"/branch/"+url.PathEscape(branch),
-			map[string]string{"Accept": "application/json"},
-			[]int{http.StatusOK},
-			&build,
-		); err != nil {
-			return "", err
-		} else if build.Build.Version == "" {
-			return "", errors.New("no version in response")
-		}
+			u := h.AppVeyorURL(ctx, "api/projects/"+repo+"/branch/"+url.PathEscape(branch))
+			if err := h.GetJSON(
+				ctx,
+				nil,
+				u,
+				map[string]string{"Accept": "application/json"},
+				[]int{http.StatusOK},
+				&build,
+			); err != nil {
+				return c.VersionInfo{}, err
+			} else if build.Build.Version == "" {
+				return c.VersionInfo{}, c.Errorf(c.CategoryParse, u, "no version in response")
+			}
 
-		return build.Build.Version, nil
+			return c.VersionInfo{Version: build.Build.Version}, nil
+		},
 	}
 }
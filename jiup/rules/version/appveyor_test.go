@@ -0,0 +1,40 @@
+package v
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/just-install/just-install-updater-go/jiup/rules/fakes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppVeyorBranch(t *testing.T) {
+	for _, tc := range []struct {
+		Name    string
+		Branch  string
+		Fault   *fakes.Fault
+		Version string
+	}{
+		{"ok", "master", nil, "1.0.45"},
+		{"no such branch", "dev", nil, ""},
+		{"server error", "master", &fakes.Fault{Status: http.StatusInternalServerError}, ""},
+		{"malformed", "master", &fakes.Fault{Malformed: true}, ""},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			a := fakes.NewAppVeyor()
+			defer a.Close()
+			a.AddBuild("test/app", "master", fakes.Build{Version: "1.0.45"})
+			if tc.Fault != nil {
+				a.SetFault("/api/projects/", *tc.Fault)
+			}
+
+			vi, err := AppVeyorBranch("test/app", tc.Branch).Structured()(a.Context(context.Background()))
+			if tc.Version == "" {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tc.Version, vi.Version)
+			}
+		})
+	}
+}
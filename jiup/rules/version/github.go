@@ -1,8 +1,7 @@
 package v
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"regexp"
 	"strings"
 
@@ -10,56 +9,78 @@ import (
 	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
 )
 
-// GitHubTag returns a version extractor for a GitHub tag.
-func GitHubTag(repo string, tagRe *regexp.Regexp) c.VersionExtractorFunc {
-	return func() (string, error) {
-		if tagRe == nil {
-			return "", errors.New("tag regex is nil")
-		}
+// GitHubTag returns a version extractor for a GitHub tag. Named capture groups
+// in the regexp are returned as fields (see h.FindVersion).
+func GitHubTag(repo string, tagRe *regexp.Regexp) c.DescribedVersion {
+	return GitHubTagF(repo, tagRe, "")
+}
+
+// GitHubTagF is like GitHubTag, but assembles the version from the named capture
+// groups using a format (e.g. {{.major}}.{{.minor}}).
+func GitHubTagF(repo string, tagRe *regexp.Regexp, format string) c.DescribedVersion {
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("v.GitHubTag", "repo", repo, "url", h.DefaultBaseURLs.GitHub+"/"+repo+"/tags", "regexp", tagRe, "format", format),
+		F: func(ctx context.Context) (c.VersionInfo, error) {
+			if tagRe == nil {
+				return c.VersionInfo{}, c.Errorf(c.CategoryValidation, "", "tag regex is nil")
+			}
 
-		// scrape to avoid limit
-		doc, err := h.GetDoc(nil, fmt.Sprintf("https://github.com/%s/tags", repo), map[string]string{}, []int{200})
-		if err != nil {
-			return "", err
-		}
+			// scrape to avoid limit
+			url := h.GitHubURL(ctx, repo+"/tags")
+			doc, err := h.GetDoc(ctx, nil, url, map[string]string{}, []int{200})
+			if err != nil {
+				return c.VersionInfo{}, err
+			}
 
-		tag := strings.TrimSpace(doc.Find(".commit.Details .commit-title a").First().Text())
-		if tag == "" {
-			return "", errors.New("could not find tag from GitHub")
-		}
+			tag := strings.TrimSpace(doc.Find(".commit.Details .commit-title a").First().Text())
+			if tag == "" {
+				return c.VersionInfo{}, c.Errorf(c.CategorySelector, url, "could not find tag from GitHub")
+			}
 
-		m := tagRe.FindStringSubmatch(tag)
-		if len(m) != 2 || m[1] == "" {
-			return "", errors.New("could not find 2nd match group for tag regexp")
-		}
+			version, fields, ok := h.FindVersion(tagRe, tag, format)
+			if !ok {
+				return c.VersionInfo{}, c.Errorf(c.CategoryRegexp, url, "could not find match group for tag regexp")
+			}
 
-		return m[1], nil
+			return c.VersionInfo{Version: version, Fields: fields}, nil
+		},
 	}
 }
 
-// GitHubRelease returns a version extractor for a GitHub release.
-func GitHubRelease(repo string, tagRe *regexp.Regexp) c.VersionExtractorFunc {
-	return func() (string, error) {
-		if tagRe == nil {
-			return "", errors.New("tag regex is nil")
-		}
+// GitHubRelease returns a version extractor for a GitHub release. Named capture
+// groups in the regexp are returned as fields (see h.FindVersion).
+func GitHubRelease(repo string, tagRe *regexp.Regexp) c.DescribedVersion {
+	return GitHubReleaseF(repo, tagRe, "")
+}
+
+// GitHubReleaseF is like GitHubRelease, but assembles the version from the named
+// capture groups using a format (e.g. {{.major}}.{{.minor}}).
+func GitHubReleaseF(repo string, tagRe *regexp.Regexp, format string) c.DescribedVersion {
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("v.GitHubRelease", "repo", repo, "url", h.DefaultBaseURLs.GitHub+"/"+repo+"/releases/latest", "regexp", tagRe, "format", format),
+		F: func(ctx context.Context) (c.VersionInfo, error) {
+			if tagRe == nil {
+				return c.VersionInfo{}, c.Errorf(c.CategoryValidation, "", "tag regex is nil")
+			}
 
-		// scrape to avoid limit
-		doc, err := h.GetDoc(nil, fmt.Sprintf("https://github.com/%s/releases/latest", repo), map[string]string{}, []int{200})
-		if err != nil {
-			return "", err
-		}
+			// scrape to avoid limit
+			url := h.GitHubURL(ctx, repo+"/releases/latest")
+			doc, err := h.GetDoc(ctx, nil, url, map[string]string{}, []int{200})
+			if err != nil {
+				return c.VersionInfo{}, err
+			}
 
-		tag := strings.TrimSpace(doc.Find(".release .octicon-tag+span").First().Text())
-		if tag == "" {
-			return "", errors.New("could not find tag from GitHub")
-		}
+			tag := strings.TrimSpace(doc.Find(".release .octicon-tag+span").First().Text())
+			if tag == "" {
+				return c.VersionInfo{}, c.Errorf(c.CategorySelector, url, "could not find tag from GitHub")
+			}
 
-		m := tagRe.FindStringSubmatch(tag)
-		if len(m) != 2 || m[1] == "" {
-			return "", errors.New("could not find 2nd match group for tag regexp")
-		}
+			version, fields, ok := h.FindVersion(tagRe, tag, format)
+			if !ok {
+				return c.VersionInfo{}, c.Errorf(c.CategoryRegexp, url, "could not find match group for tag regexp")
+			}
 
-		return m[1], nil
+			return c.VersionInfo{Version: version, Fields: fields}, nil
+		},
 	}
 }
@@ -0,0 +1,55 @@
+package v
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/just-install/just-install-updater-go/jiup/rules/fakes"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitHub(t *testing.T) {
+	for _, tc := range []struct {
+		Name    string
+		Fault   *fakes.Fault
+		Release bool
+		Version string
+		Err     c.ErrorCategory
+	}{
+		{"release", nil, true, "1.2.3", ""},
+		{"tag", nil, false, "1.2.3", ""},
+		{"release not found", &fakes.Fault{Status: http.StatusNotFound}, true, "", c.CategoryHTTPStatus},
+		{"tag server error", &fakes.Fault{Status: http.StatusInternalServerError}, false, "", c.CategoryHTTPStatus},
+		{"release malformed", &fakes.Fault{Malformed: true}, true, "", c.CategorySelector},
+		{"tag slow", &fakes.Fault{Delay: time.Second}, false, "", c.CategoryNetwork},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			g := fakes.NewGitHub()
+			defer g.Close()
+			g.SetRelease("test/app", fakes.Release{Tag: "v1.2.3"})
+			g.SetTags("test/app", "v1.2.3", "v1.2.2")
+			if tc.Fault != nil {
+				g.SetFault("/", *tc.Fault)
+			}
+
+			ctx, cancel := context.WithTimeout(g.Context(context.Background()), time.Second/10)
+			defer cancel()
+
+			f := GitHubTag("test/app", h.Re("v([0-9.]+)"))
+			if tc.Release {
+				f = GitHubRelease("test/app", h.Re("v([0-9.]+)"))
+			}
+
+			vi, err := f.Structured()(ctx)
+			if tc.Version == "" {
+				assert.Equal(t, tc.Err, c.Category(err), "%v", err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tc.Version, vi.Version)
+			}
+		})
+	}
+}
@@ -1,46 +1,108 @@
 package v
 
 import (
-	"errors"
+	"context"
 	"regexp"
 	"strings"
 
+	"github.com/PuerkitoBio/goquery"
 	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
 	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
 )
 
 // HTML returns a version extractor for the first match of a css selector, an attribute (or innerText for the text), and an optional regexp on the attribute.
-func HTML(url string, versionSelector, versionAttr string, versionRe *regexp.Regexp) c.VersionExtractorFunc {
-	return func() (string, error) {
-		doc, err := h.GetDoc(nil, url, map[string]string{}, []int{200})
-		if err != nil {
-			return "", err
-		}
-
-		s := doc.Find(versionSelector).First()
-		if s.Length() != 1 {
-			return "", errors.New("could not find match for selector")
-		}
-
-		var a string
-		if versionAttr == "innerText" {
-			a = strings.TrimSpace(s.Text())
-		} else {
-			a = strings.TrimSpace(s.AttrOr(versionAttr, ""))
-		}
-		if a == "" {
-			return "", errors.New("specified attribute is empty")
-		}
-
-		if versionRe == nil {
-			return a, nil
-		}
-
-		m := versionRe.FindStringSubmatch(a)
-		if len(m) != 2 || m[1] == "" {
-			return "", errors.New("could not find 2nd match group for version")
-		}
-
-		return m[1], nil
+// Named capture groups in the regexp are returned as fields (see h.FindVersion).
+func HTML(url string, versionSelector, versionAttr string, versionRe *regexp.Regexp) c.DescribedVersion {
+	return HTMLF(url, versionSelector, versionAttr, versionRe, "")
+}
+
+// HTMLF is like HTML, but assembles the version from the named capture groups
+// using a format (e.g. {{.major}}.{{.minor}}).
+func HTMLF(url string, versionSelector, versionAttr string, versionRe *regexp.Regexp, format string) c.DescribedVersion {
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("v.HTML", "url", url, "selector", versionSelector, "attr", versionAttr, "regexp", versionRe, "format", format),
+		F: func(ctx context.Context) (c.VersionInfo, error) {
+			doc, err := h.GetDoc(ctx, nil, url, map[string]string{}, []int{200})
+			if err != nil {
+				return c.VersionInfo{}, err
+			}
+
+			s := doc.Find(versionSelector).First()
+			if s.Length() != 1 {
+				return c.VersionInfo{}, c.Errorf(c.CategorySelector, url, "could not find match for selector")
+			}
+
+			var a string
+			if versionAttr == "innerText" {
+				a = strings.TrimSpace(s.Text())
+			} else {
+				a = strings.TrimSpace(s.AttrOr(versionAttr, ""))
+			}
+			if a == "" {
+				return c.VersionInfo{}, c.Errorf(c.CategorySelector, url, "specified attribute is empty")
+			}
+
+			if versionRe == nil {
+				return c.VersionInfo{Version: a}, nil
+			}
+
+			version, fields, ok := h.FindVersion(versionRe, a, format)
+			if !ok {
+				return c.VersionInfo{}, c.Errorf(c.CategoryRegexp, url, "could not find match group for version")
+			}
+
+			return c.VersionInfo{Version: version, Fields: fields}, nil
+		},
+	}
+}
+
+// HTMLMax returns a version extractor for the highest version out of all matches of a css selector, an attribute
+// (or innerText for the text), and an optional regexp on the attribute. Versions which do not match the include
+// regexp, or match the exclude regexp, are ignored (either can be nil).
+func HTMLMax(url string, versionSelector, versionAttr string, versionRe, include, exclude *regexp.Regexp) c.DescribedVersion {
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("v.HTMLMax", "url", url, "selector", versionSelector, "attr", versionAttr, "regexp", versionRe, "include", include, "exclude", exclude),
+		F: func(ctx context.Context) (c.VersionInfo, error) {
+			doc, err := h.GetDoc(ctx, nil, url, map[string]string{}, []int{200})
+			if err != nil {
+				return c.VersionInfo{}, err
+			}
+
+			s := doc.Find(versionSelector)
+			if s.Length() == 0 {
+				return c.VersionInfo{}, c.Errorf(c.CategorySelector, url, "could not find match for selector")
+			}
+
+			versions, fields := []string{}, []map[string]string{}
+			s.Each(func(_ int, m *goquery.Selection) {
+				var a string
+				if versionAttr == "innerText" {
+					a = strings.TrimSpace(m.Text())
+				} else {
+					a = strings.TrimSpace(m.AttrOr(versionAttr, ""))
+				}
+				if a == "" {
+					return
+				}
+				if versionRe == nil {
+					versions = append(versions, a)
+					fields = append(fields, nil)
+					return
+				}
+				if version, f, ok := h.FindVersion(versionRe, a, ""); ok {
+					versions = append(versions, version)
+					fields = append(fields, f)
+				}
+			})
+			if len(versions) == 0 {
+				return c.VersionInfo{}, c.Errorf(c.CategoryRegexp, url, "could not find a version in any of the matches")
+			}
+
+			i := h.MaxVersion(versions, include, exclude)
+			if i == -1 {
+				return c.VersionInfo{}, c.Errorf(c.CategoryRegexp, url, "all %d versions were filtered out", len(versions))
+			}
+			return c.VersionInfo{Version: versions[i], Fields: fields[i]}, nil
+		},
 	}
 }
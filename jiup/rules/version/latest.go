@@ -1,19 +1,27 @@
 package v
 
 import (
+	"context"
+
 	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
 )
 
 // Latest always returns the version latest.
-func Latest() c.VersionExtractorFunc {
-	return func() (string, error) {
-		return "latest", nil
+func Latest() c.DescribedVersion {
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("v.Latest"),
+		F: func(context.Context) (c.VersionInfo, error) {
+			return c.VersionInfo{Version: "latest"}, nil
+		},
 	}
 }
 
 // LatestS returns the version latest with a suffix.
-func LatestS(suffix string) c.VersionExtractorFunc {
-	return func() (string, error) {
-		return "latest" + suffix, nil
+func LatestS(suffix string) c.DescribedVersion {
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("v.Latest", "suffix", suffix),
+		F: func(context.Context) (c.VersionInfo, error) {
+			return c.VersionInfo{Version: "latest" + suffix}, nil
+		},
 	}
 }
@@ -0,0 +1,53 @@
+package v
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMax(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`
+			<ul>
+				<li><a href="/dl/test-2.1.0-lts.exe">Test 2.1.0 LTS</a></li>
+				<li><a href="/dl/test-3.0.0-beta1.exe">Test 3.0.0 Beta 1</a></li>
+				<li><a href="/dl/test-2.10.3.exe">Test 2.10.3</a></li>
+				<li><a href="/dl/test-2.9.12.exe">Test 2.9.12</a></li>
+				<li><a href="/dl/test-1.12.0.exe">Test 1.12.0</a></li>
+			</ul>
+		`))
+	}))
+	defer s.Close()
+
+	for _, tc := range []struct {
+		Include *regexp.Regexp
+		Exclude *regexp.Regexp
+		Version string
+	}{
+		{nil, nil, "3.0.0-beta1"},
+		{nil, h.Re("beta|rc"), "2.10.3"},
+		{h.Re("^1\\."), nil, "1.12.0"},
+		{h.Re("^4\\."), nil, ""},
+	} {
+		for _, f := range []c.DescribedVersion{
+			RegexpMax(s.URL, h.Re("test-([0-9.]+(?:-beta[0-9]+)?)(?:-lts)?\\.exe"), tc.Include, tc.Exclude),
+			HTMLMax(s.URL, "li a", "href", h.Re("test-([0-9.]+(?:-beta[0-9]+)?)(?:-lts)?\\.exe"), tc.Include, tc.Exclude),
+		} {
+			version, err := f.Structured()(context.Background())
+			if tc.Version == "" {
+				assert.Error(t, err)
+				continue
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.Version, version.Version)
+		}
+	}
+}
@@ -1,29 +1,67 @@
 package v
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"regexp"
 
 	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
 	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
 )
 
-// Regexp returns a version extractor for the first match of a regex.
-func Regexp(url string, versionRe *regexp.Regexp) c.VersionExtractorFunc {
-	return func() (string, error) {
-		buf, code, ok, err := h.GetURL(nil, url, map[string]string{}, []int{200})
-		if err != nil {
-			return "", err
-		}
-		if !ok {
-			return "", fmt.Errorf("unexpected response status: %d", code)
-		}
-
-		m := versionRe.FindStringSubmatch(string(buf))
-		if len(m) != 2 || m[1] == "" {
-			return "", errors.New("could not find 2nd match group for version regexp")
-		}
-		return m[1], nil
+// Regexp returns a version extractor for the first match of a regex. Named
+// capture groups are returned as fields (see h.FindVersion).
+func Regexp(url string, versionRe *regexp.Regexp) c.DescribedVersion {
+	return RegexpF(url, versionRe, "")
+}
+
+// RegexpF is like Regexp, but assembles the version from the named capture groups
+// using a format (e.g. {{.major}}.{{.minor}}).
+func RegexpF(url string, versionRe *regexp.Regexp, format string) c.DescribedVersion {
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("v.Regexp", "url", url, "regexp", versionRe, "format", format),
+		F: func(ctx context.Context) (c.VersionInfo, error) {
+			buf, code, ok, err := h.GetURL(ctx, nil, url, map[string]string{}, []int{200})
+			if err != nil {
+				return c.VersionInfo{}, err
+			}
+			if !ok {
+				return c.VersionInfo{}, c.Errorf(c.CategoryHTTPStatus, url, "unexpected response status: %d", code)
+			}
+
+			version, fields, ok := h.FindVersion(versionRe, string(buf), format)
+			if !ok {
+				return c.VersionInfo{}, c.Errorf(c.CategoryRegexp, url, "could not find match group for version regexp")
+			}
+			return c.VersionInfo{Version: version, Fields: fields}, nil
+		},
+	}
+}
+
+// RegexpMax returns a version extractor for the highest version out of all matches of a regex.
+// Versions which do not match the include regexp, or match the exclude regexp, are ignored
+// (either can be nil, e.g. to exclude pre-releases or to pin a major version).
+func RegexpMax(url string, versionRe, include, exclude *regexp.Regexp) c.DescribedVersion {
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("v.RegexpMax", "url", url, "regexp", versionRe, "include", include, "exclude", exclude),
+		F: func(ctx context.Context) (c.VersionInfo, error) {
+			buf, code, ok, err := h.GetURL(ctx, nil, url, map[string]string{}, []int{200})
+			if err != nil {
+				return c.VersionInfo{}, err
+			}
+			if !ok {
+				return c.VersionInfo{}, c.Errorf(c.CategoryHTTPStatus, url, "unexpected response status: %d", code)
+			}
+
+			versions, fields := h.FindAllVersions(versionRe, string(buf), "")
+			if len(versions) == 0 {
+				return c.VersionInfo{}, c.Errorf(c.CategoryRegexp, url, "could not find match group for version regexp")
+			}
+
+			i := h.MaxVersion(versions, include, exclude)
+			if i == -1 {
+				return c.VersionInfo{}, c.Errorf(c.CategoryRegexp, url, "all %d versions were filtered out", len(versions))
+			}
+			return c.VersionInfo{Version: versions[i], Fields: fields[i]}, nil
+		},
 	}
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/just-install/just-install-updater-go/jiup/history"
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	stateDir := pflag.String("state-dir", history.DefaultDir(), "The directory the versions were recorded in")
+	since := pflag.String("since", "", "Only show versions first seen since a date (YYYY-MM-DD or RFC3339) or duration ago (e.g. 7d or 12h)")
+	until := pflag.String("until", "", "Only show versions first seen before a date or duration ago")
+	version := pflag.String("version", "", "Only show when the packages were updated to a version")
+	stale := pflag.String("stale", "", "Show the packages which have not changed since a date or duration ago, with their release cadence")
+	help := pflag.Bool("help", false, "Show this help text")
+	pflag.Parse()
+
+	if *help || *stateDir == "" {
+		helpExit()
+	}
+
+	now := time.Now()
+	from, err := parseTime(*since, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --since: %v\n", err)
+		os.Exit(1)
+	}
+	to, err := parseTime(*until, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --until: %v\n", err)
+		os.Exit(1)
+	}
+	staleBefore, err := parseTime(*stale, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --stale: %v\n", err)
+		os.Exit(1)
+	}
+
+	s, err := history.Open(*stateDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open state dir: %v\n", err)
+		os.Exit(1)
+	}
+	all, err := s.Observations()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read versions: %v\n", err)
+		os.Exit(1)
+	}
+
+	observations := all
+	if pflag.NArg() > 0 {
+		observations = []history.Observation{}
+		for _, p := range pflag.Args() {
+			observations = append(observations, history.PackageHistory(all, p)...)
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	switch {
+	case *stale != "":
+		fmt.Fprintf(tw, "PACKAGE\tVERSION\tUNCHANGED SINCE\tRELEASES\tCADENCE\n")
+		for _, o := range history.Stale(observations, staleBefore) {
+			n, interval := history.Cadence(all, o.Package)
+			cadence := "-"
+			if n > 1 {
+				cadence = formatDuration(interval)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", o.Package, o.Version, formatTime(o.Time), n, cadence)
+		}
+	case *version != "":
+		if pflag.NArg() == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --version requires at least one package\n")
+			os.Exit(1)
+		}
+		fmt.Fprintf(tw, "PACKAGE\tVERSION\tFIRST SEEN\tURLS\n")
+		for _, p := range pflag.Args() {
+			if o, ok := history.FirstSeen(all, p, *version); ok {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", o.Package, o.Version, formatTime(o.Time), formatURLs(o.URLs))
+			} else {
+				fmt.Fprintf(tw, "%s\t%s\tnever\t-\n", p, *version)
+			}
+		}
+	default:
+		fmt.Fprintf(tw, "FIRST SEEN\tPACKAGE\tVERSION\tURLS\n")
+		for _, o := range history.Between(observations, from, to) {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", formatTime(o.Time), o.Package, o.Version, formatURLs(o.URLs))
+		}
+	}
+	tw.Flush()
+	os.Exit(0)
+}
+
+func helpExit() {
+	fmt.Fprintf(os.Stderr, "Usage: versions [options] [packages...]\n\n")
+	pflag.PrintDefaults()
+	os.Exit(1)
+}
+
+// parseTime parses a date, or a duration before now (with d for days). An empty
+// string is the zero time.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date or duration", s)
+	}
+	return now.Add(-d), nil
+}
+
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func formatDuration(d time.Duration) string {
+	if d >= time.Hour*24 {
+		return fmt.Sprintf("%.0fd", d.Hours()/24)
+	}
+	return d.Round(time.Minute).String()
+}
+
+func formatURLs(urls map[c.Arch]string) string {
+	res := []string{}
+	for _, arch := range c.Archs {
+		if u, ok := urls[arch]; ok {
+			res = append(res, string(arch)+"="+u)
+		}
+	}
+	if len(res) == 0 {
+		return "-"
+	}
+	return strings.Join(res, " ")
+}
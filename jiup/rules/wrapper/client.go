@@ -0,0 +1,45 @@
+package w
+
+import (
+	"context"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+)
+
+// ClientOptions wraps a version extractor to use the specified HTTP client options
+// (e.g. h.ClientOptions{Insecure: true} for a site with a broken certificate chain)
+// over the ones from the updater.
+func ClientOptions(opts h.ClientOptions, f c.VersionExtractor) c.DescribedVersion {
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("w.ClientOptions", clientOptionsParams(opts, f)...),
+		F: func(ctx context.Context) (c.VersionInfo, error) {
+			return f.Structured()(h.WithClientOptions(ctx, opts))
+		},
+	}
+}
+
+// ClientOptionsDownloads wraps a download extractor to use the specified HTTP client
+// options over the ones from the updater.
+func ClientOptionsDownloads(opts h.ClientOptions, f c.DownloadExtractor) c.DescribedDownloads {
+	return c.DescribedDownloads{
+		Desc: c.NewDescriptor("w.ClientOptionsDownloads", clientOptionsParams(opts, f)...),
+		F: func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+			return f.Structured()(h.WithClientOptions(ctx, opts), version)
+		},
+	}
+}
+
+// clientOptionsParams returns the descriptor params of a ClientOptions wrapper (see
+// c.NewDescriptor).
+func clientOptionsParams(opts h.ClientOptions, f interface{}) []interface{} {
+	return []interface{}{
+		"timeout", opts.Timeout,
+		"insecure", opts.Insecure,
+		"rootCAs", string(opts.RootCAs),
+		"pins", opts.Pins,
+		"proxy", opts.Proxy,
+		"userAgent", opts.UserAgent,
+		"of", f,
+	}
+}
@@ -0,0 +1,36 @@
+package w
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientOptions(t *testing.T) {
+	var got []h.ClientOptions
+	ctx := h.WithClientFactory(context.Background(), func(opts h.ClientOptions) (*http.Client, error) {
+		got = append(got, opts)
+		return &http.Client{}, nil
+	}, h.ClientOptions{UserAgent: "test"})
+
+	_, err := ClientOptions(h.ClientOptions{Insecure: true}, c.VersionInfoExtractorFunc(func(ctx context.Context) (c.VersionInfo, error) {
+		_, err := h.Client(ctx, h.ClientOptions{})
+		return c.VersionInfo{Version: "1.0"}, err
+	})).Structured()(ctx)
+	assert.NoError(t, err)
+
+	_, err = ClientOptionsDownloads(h.ClientOptions{Proxy: "http://proxy"}, c.DownloadsExtractorFunc(func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+		_, err := h.Client(ctx, h.ClientOptions{})
+		return c.Downloads{}, err
+	})).Structured()(ctx, c.VersionInfo{Version: "1.0"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []h.ClientOptions{
+		{UserAgent: "test", Insecure: true},
+		{UserAgent: "test", Proxy: "http://proxy"},
+	}, got)
+}
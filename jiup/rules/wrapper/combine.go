@@ -0,0 +1,182 @@
+package w
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+)
+
+// CombinedError is returned when a combination of extractors fails. It keeps the
+// error from each source (in the order they were passed), which is nil if the source
+// succeeded (or wasn't tried).
+type CombinedError struct {
+	Reason string
+	Errors []error
+}
+
+func (e *CombinedError) Error() string {
+	s := []string{}
+	for i, err := range e.Errors {
+		if err != nil {
+			s = append(s, fmt.Sprintf("source %d: %v", i+1, err))
+		}
+	}
+	if len(s) == 0 {
+		return e.Reason
+	}
+	return e.Reason + " (" + strings.Join(s, "; ") + ")"
+}
+
+// ErrorCategory returns the category of the first error if all sources failed,
+// or c.CategoryConsistency if some of them succeeded but didn't agree.
+func (e *CombinedError) ErrorCategory() c.ErrorCategory {
+	for _, err := range e.Errors {
+		if err == nil {
+			return c.CategoryConsistency
+		}
+	}
+	if len(e.Errors) == 0 {
+		return c.CategoryValidation
+	}
+	return c.Category(e.Errors[0])
+}
+
+// FirstOf returns the result of the first version extractor which succeeds.
+func FirstOf(fs ...c.VersionExtractor) c.DescribedVersion {
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("w.FirstOf", "all", fs),
+		F: func(ctx context.Context) (c.VersionInfo, error) {
+			errs := make([]error, len(fs))
+			for i, f := range fs {
+				version, err := f.Structured()(ctx)
+				if err == nil {
+					return version, nil
+				}
+				errs[i] = err
+			}
+			return c.VersionInfo{}, &CombinedError{Reason: "all version sources failed", Errors: errs}
+		},
+	}
+}
+
+// Consensus runs all version extractors, and returns the version which at least n of
+// them agree on. If more than one version meets that, the one returned by the most
+// sources is used (or the earliest source if tied). The fields are taken from the
+// first source which returned it.
+func Consensus(n int, fs ...c.VersionExtractor) c.DescribedVersion {
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("w.Consensus", "n", n, "all", fs),
+		F: func(ctx context.Context) (c.VersionInfo, error) {
+			errs := make([]error, len(fs))
+			results := []c.VersionInfo{}
+			counts := map[string]int{}
+			for i, f := range fs {
+				version, err := f.Structured()(ctx)
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				if counts[version.Version] == 0 {
+					results = append(results, version)
+				}
+				counts[version.Version]++
+			}
+
+			var best *c.VersionInfo
+			for i, version := range results {
+				if counts[version.Version] >= n && (best == nil || counts[version.Version] > counts[best.Version]) {
+					best = &results[i]
+				}
+			}
+			if best == nil {
+				return c.VersionInfo{}, &CombinedError{Reason: fmt.Sprintf("fewer than %d version sources agree (got %s)", n, formatCounts(results, counts)), Errors: errs}
+			}
+			return *best, nil
+		},
+	}
+}
+
+// FirstOfDownloads returns the result of the first download extractor which succeeds
+// with at least one download.
+func FirstOfDownloads(fs ...c.DownloadExtractor) c.DescribedDownloads {
+	return c.DescribedDownloads{
+		Desc: c.NewDescriptor("w.FirstOfDownloads", "all", fs),
+		F: func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+			errs := make([]error, len(fs))
+			for i, f := range fs {
+				dls, err := f.Structured()(ctx, version)
+				if err == nil && len(dls) == 0 {
+					err = c.Errorf(c.CategoryValidation, "", "no downloads")
+				}
+				if err == nil {
+					return dls, nil
+				}
+				errs[i] = err
+			}
+			return nil, &CombinedError{Reason: "all download sources failed", Errors: errs}
+		},
+	}
+}
+
+// ConsensusDownloads is like Consensus, but for download extractors. Sources agree if
+// they return the same link for each architecture, and the metadata is taken from the
+// first source which returned them.
+func ConsensusDownloads(n int, fs ...c.DownloadExtractor) c.DescribedDownloads {
+	return c.DescribedDownloads{
+		Desc: c.NewDescriptor("w.ConsensusDownloads", "n", n, "all", fs),
+		F: func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+			errs := make([]error, len(fs))
+			keys := []string{}
+			results := map[string]c.Downloads{}
+			counts := map[string]int{}
+			for i, f := range fs {
+				dls, err := f.Structured()(ctx, version)
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				key := downloadsKey(dls)
+				if counts[key] == 0 {
+					keys = append(keys, key)
+					results[key] = dls
+				}
+				counts[key]++
+			}
+
+			best := ""
+			for _, key := range keys {
+				if counts[key] >= n && (best == "" || counts[key] > counts[best]) {
+					best = key
+				}
+			}
+			if best == "" {
+				return nil, &CombinedError{Reason: fmt.Sprintf("fewer than %d download sources agree (got %d different results)", n, len(keys)), Errors: errs}
+			}
+			return results[best], nil
+		},
+	}
+}
+
+// downloadsKey returns a string which is equal for downloads with the same links.
+func downloadsKey(dls c.Downloads) string {
+	s := []string{}
+	for _, arch := range c.Archs {
+		if u := dls.URL(arch); u != nil {
+			s = append(s, string(arch)+"="+*u)
+		}
+	}
+	return strings.Join(s, "\n")
+}
+
+func formatCounts(results []c.VersionInfo, counts map[string]int) string {
+	if len(results) == 0 {
+		return "none"
+	}
+	s := []string{}
+	for _, version := range results {
+		s = append(s, fmt.Sprintf("%s x%d", version.Version, counts[version.Version]))
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,103 @@
+package w
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func fixedVersion(version string, err error) c.VersionExtractor {
+	return c.VersionInfoExtractorFunc(func(context.Context) (c.VersionInfo, error) {
+		if err != nil {
+			return c.VersionInfo{}, err
+		}
+		return c.VersionInfo{Version: version}, nil
+	})
+}
+
+func fixedDownloads(x64 string, err error) c.DownloadExtractor {
+	return c.DownloadsExtractorFunc(func(context.Context, c.VersionInfo) (c.Downloads, error) {
+		if err != nil {
+			return nil, err
+		}
+		if x64 == "" {
+			return c.Downloads{}, nil
+		}
+		return c.Downloads{c.ArchX86_64: {URL: x64}}, nil
+	})
+}
+
+func TestFirstOf(t *testing.T) {
+	version, err := FirstOf(fixedVersion("", errors.New("a")), fixedVersion("1.0", nil), fixedVersion("2.0", nil)).Structured()(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0", version.Version)
+
+	_, err = FirstOf(fixedVersion("", errors.New("a")), fixedVersion("", errors.New("b"))).Structured()(context.Background())
+	if assert.IsType(t, &CombinedError{}, err) {
+		assert.Equal(t, []error{errors.New("a"), errors.New("b")}, err.(*CombinedError).Errors)
+	}
+	assert.EqualError(t, err, "all version sources failed (source 1: a; source 2: b)")
+}
+
+func TestConsensus(t *testing.T) {
+	for _, tc := range []struct {
+		Name     string
+		N        int
+		Sources  []c.VersionExtractor
+		Version  string
+		HasError bool
+	}{
+		{"all agree", 2, []c.VersionExtractor{fixedVersion("1.0", nil), fixedVersion("1.0", nil)}, "1.0", false},
+		{"majority", 2, []c.VersionExtractor{fixedVersion("0.9", nil), fixedVersion("1.0", nil), fixedVersion("1.0", nil)}, "1.0", false},
+		{"with error", 2, []c.VersionExtractor{fixedVersion("", errors.New("a")), fixedVersion("1.0", nil), fixedVersion("1.0", nil)}, "1.0", false},
+		{"tie", 1, []c.VersionExtractor{fixedVersion("0.9", nil), fixedVersion("1.0", nil)}, "0.9", false},
+		{"disagree", 2, []c.VersionExtractor{fixedVersion("0.9", nil), fixedVersion("1.0", nil)}, "", true},
+		{"too many errors", 2, []c.VersionExtractor{fixedVersion("", errors.New("a")), fixedVersion("1.0", nil)}, "", true},
+		{"none", 1, nil, "", true},
+	} {
+		version, err := Consensus(tc.N, tc.Sources...).Structured()(context.Background())
+		if tc.HasError {
+			assert.IsType(t, &CombinedError{}, err, tc.Name)
+			continue
+		}
+		assert.NoError(t, err, tc.Name)
+		assert.Equal(t, tc.Version, version.Version, tc.Name)
+	}
+
+	_, err := Consensus(2, fixedVersion("", errors.New("a")), fixedVersion("1.0", nil)).Structured()(context.Background())
+	assert.EqualError(t, err, "fewer than 2 version sources agree (got 1.0 x1) (source 1: a)")
+}
+
+func TestFirstOfDownloads(t *testing.T) {
+	dls, err := FirstOfDownloads(fixedDownloads("", errors.New("a")), fixedDownloads("", nil), fixedDownloads("https://example.com/1", nil)).Structured()(context.Background(), c.VersionInfo{Version: "1.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, c.Downloads{c.ArchX86_64: {URL: "https://example.com/1"}}, dls)
+
+	_, err = FirstOfDownloads(fixedDownloads("", errors.New("a")), fixedDownloads("", nil)).Structured()(context.Background(), c.VersionInfo{Version: "1.0"})
+	assert.EqualError(t, err, "all download sources failed (source 1: a; source 2: no downloads)")
+}
+
+func TestConsensusDownloads(t *testing.T) {
+	dls, err := ConsensusDownloads(2,
+		fixedDownloads("https://example.com/1", nil),
+		fixedDownloads("https://example.com/2", nil),
+		fixedDownloads("", errors.New("a")),
+		fixedDownloads("https://example.com/2", nil),
+	).Structured()(context.Background(), c.VersionInfo{Version: "1.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, c.Downloads{c.ArchX86_64: {URL: "https://example.com/2"}}, dls)
+
+	_, err = ConsensusDownloads(2,
+		fixedDownloads("https://example.com/1", nil),
+		fixedDownloads("https://example.com/2", nil),
+	).Structured()(context.Background(), c.VersionInfo{Version: "1.0"})
+	assert.EqualError(t, err, "fewer than 2 download sources agree (got 2 different results)")
+}
+
+func TestCombinedErrorCategory(t *testing.T) {
+	assert.Equal(t, c.CategoryRegexp, c.Category(&CombinedError{Errors: []error{c.Errorf(c.CategoryRegexp, "", "a"), errors.New("b")}}), "should use the first error if all failed")
+	assert.Equal(t, c.CategoryConsistency, c.Category(&CombinedError{Errors: []error{nil, errors.New("b")}}), "should be a consistency error if some succeeded")
+}
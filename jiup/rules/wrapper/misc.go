@@ -1,50 +1,64 @@
 package w
 
 import (
-	"strings"
+	"context"
 
 	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
-	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
 )
 
 // UnderscoreToDot wraps a version extractor and replaces underscores with dots.
-func UnderscoreToDot(f c.VersionExtractorFunc) c.VersionExtractorFunc {
-	return func() (string, error) {
-		version, err := f()
-		if err != nil {
-			return "", err
-		}
-		return strings.Replace(version, "_", ".", -1), nil
-	}
+func UnderscoreToDot(f c.VersionExtractor) c.DescribedVersion {
+	return Transform(f, Replace("_", "."))
 }
 
 // AppendToURL wraps a download extractor and appends a string to each URL.
-func AppendToURL(str string, f c.DownloadExtractorFunc) c.DownloadExtractorFunc {
-	return func(version string) (*string, *string, error) {
-		x86, x64, err := f(version)
-		if err != nil {
-			return nil, nil, err
-		}
-		if x64 != nil {
-			t := *x64 + str
-			x64 = &t
-		}
-		x86 = h.StrPtr(*x86 + str)
-		return x86, x64, nil
+func AppendToURL(str string, f c.DownloadExtractor) c.DescribedDownloads {
+	return c.DescribedDownloads{
+		Desc: c.NewDescriptor("w.AppendToURL", "suffix", str, "of", f),
+		F: func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+			dls, err := f.Structured()(ctx, version)
+			if err != nil {
+				return nil, err
+			}
+			// the downloads may be shared with the inner extractor (e.g. if it caches them)
+			res := make(c.Downloads, len(dls))
+			for arch, dl := range dls {
+				cp := *dl
+				cp.URL += str
+				res[arch] = &cp
+			}
+			return res, nil
+		},
 	}
 }
 
-// SplitDownload runs a different helper for each architecture.
-func SplitDownload(x86, x64 c.DownloadExtractorFunc) c.DownloadExtractorFunc {
-	return func(version string) (*string, *string, error) {
-		dx86, _, err := x86(version)
-		if err != nil {
-			return nil, nil, err
-		}
-		_, dx64, err := x64(version)
-		if err != nil {
-			return nil, nil, err
-		}
-		return dx86, dx64, nil
+// SplitDownload runs a different helper for each architecture. The helper for arm64
+// can be passed as the last argument.
+func SplitDownload(x86, x64 c.DownloadExtractor, arm64 ...c.DownloadExtractor) c.DescribedDownloads {
+	var arm64f c.DownloadExtractor
+	if len(arm64) != 0 {
+		arm64f = arm64[0]
+	}
+	return c.DescribedDownloads{
+		Desc: c.NewDescriptor("w.SplitDownload", "x86", x86, "x64", x64, "arm64", arm64f),
+		F: func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+			dls := c.Downloads{}
+			for _, l := range []struct {
+				arch c.Arch
+				f    c.DownloadExtractor
+			}{{c.ArchX86, x86}, {c.ArchX86_64, x64}, {c.ArchARM64, arm64f}} {
+				if l.f == nil {
+					continue
+				}
+				d, err := l.f.Structured()(ctx, version)
+				if err != nil {
+					return nil, err
+				}
+				if dl, ok := d[l.arch]; ok {
+					dls[l.arch] = dl
+				}
+			}
+			return dls, nil
+		},
 	}
 }
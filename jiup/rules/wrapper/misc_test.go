@@ -0,0 +1,29 @@
+package w
+
+import (
+	"context"
+	"testing"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendToURL(t *testing.T) {
+	inner := c.Downloads{
+		c.ArchX86:    {URL: "https://example.com/app.exe", FileName: "app.exe"},
+		c.ArchX86_64: {URL: "https://example.com/app-x64.exe"},
+	}
+	f := AppendToURL("/download", c.DownloadsExtractorFunc(func(context.Context, c.VersionInfo) (c.Downloads, error) {
+		return inner, nil
+	}))
+
+	for i := 0; i < 2; i++ {
+		dls, err := f.Structured()(context.Background(), c.VersionInfo{Version: "1.0"})
+		assert.NoError(t, err)
+		assert.Equal(t, c.Downloads{
+			c.ArchX86:    {URL: "https://example.com/app.exe/download", FileName: "app.exe"},
+			c.ArchX86_64: {URL: "https://example.com/app-x64.exe/download"},
+		}, dls, "should only append once (run %d)", i)
+	}
+	assert.Equal(t, "https://example.com/app.exe", inner[c.ArchX86].URL, "should not modify the downloads from the inner extractor")
+}
@@ -0,0 +1,34 @@
+package w
+
+import (
+	"context"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+)
+
+// Timeout wraps a version extractor to cancel it if it takes longer than the
+// timeout. It overrides any longer deadline set by the updater.
+func Timeout(timeout time.Duration, f c.VersionExtractor) c.DescribedVersion {
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("w.Timeout", "timeout", timeout, "of", f),
+		F: func(ctx context.Context) (c.VersionInfo, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return f.Structured()(ctx)
+		},
+	}
+}
+
+// TimeoutDownloads wraps a download extractor to cancel it if it takes longer than
+// the timeout. It overrides any longer deadline set by the updater.
+func TimeoutDownloads(timeout time.Duration, f c.DownloadExtractor) c.DescribedDownloads {
+	return c.DescribedDownloads{
+		Desc: c.NewDescriptor("w.TimeoutDownloads", "timeout", timeout, "of", f),
+		F: func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return f.Structured()(ctx, version)
+		},
+	}
+}
@@ -0,0 +1,37 @@
+package w
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	slowV := c.VersionInfoExtractorFunc(func(ctx context.Context) (c.VersionInfo, error) {
+		select {
+		case <-ctx.Done():
+			return c.VersionInfo{}, ctx.Err()
+		case <-time.After(time.Second):
+			return c.VersionInfo{Version: "1.0"}, nil
+		}
+	})
+	slowD := c.DownloadsExtractorFunc(func(ctx context.Context, version c.VersionInfo) (c.Downloads, error) {
+		if _, err := slowV(ctx); err != nil {
+			return nil, err
+		}
+		return c.Downloads{c.ArchX86_64: {URL: "https://example.com/" + version.Version}}, nil
+	})
+
+	_, err := Timeout(time.Millisecond*10, slowV).Structured()(context.Background())
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	_, err = TimeoutDownloads(time.Millisecond*10, slowD).Structured()(context.Background(), c.VersionInfo{Version: "1.0"})
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	version, err := Timeout(time.Second*5, slowV).Structured()(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0", version.Version)
+}
@@ -0,0 +1,149 @@
+package w
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+)
+
+// VersionTransform transforms a version string. It is created by the transform
+// constructors (e.g. Replace) along with its descriptor.
+type VersionTransform struct {
+	Desc *c.Descriptor
+	F    func(version string) (string, error)
+}
+
+// Descriptor returns the descriptor.
+func (t VersionTransform) Descriptor() *c.Descriptor {
+	return t.Desc
+}
+
+// Transform wraps a version extractor and applies each transform to the version in order.
+// The fields are left as-is.
+func Transform(f c.VersionExtractor, transforms ...VersionTransform) c.DescribedVersion {
+	ds := make([]c.Described, len(transforms))
+	for i, t := range transforms {
+		ds[i] = t
+	}
+	return c.DescribedVersion{
+		Desc: c.NewDescriptor("w.Transform", "of", f, "transforms", ds),
+		F: func(ctx context.Context) (c.VersionInfo, error) {
+			version, err := f.Structured()(ctx)
+			if err != nil {
+				return c.VersionInfo{}, err
+			}
+			for _, t := range transforms {
+				if version.Version, err = t.F(version.Version); err != nil {
+					return c.VersionInfo{}, err
+				}
+			}
+			return version, nil
+		},
+	}
+}
+
+// Replace returns a transform which replaces all occurrences of old with new.
+func Replace(old, new string) VersionTransform {
+	return VersionTransform{
+		Desc: c.NewDescriptor("w.Replace", "old", old, "new", new),
+		F: func(version string) (string, error) {
+			return strings.Replace(version, old, new, -1), nil
+		},
+	}
+}
+
+// ReplaceRe returns a transform which replaces all matches of a regexp with repl,
+// which can reference capture groups (see regexp.Regexp.ReplaceAllString).
+func ReplaceRe(re *regexp.Regexp, repl string) VersionTransform {
+	return VersionTransform{
+		Desc: c.NewDescriptor("w.ReplaceRe", "regexp", re, "repl", repl),
+		F: func(version string) (string, error) {
+			return re.ReplaceAllString(version, repl), nil
+		},
+	}
+}
+
+// TrimPrefix returns a transform which removes a prefix if present.
+func TrimPrefix(prefix string) VersionTransform {
+	return VersionTransform{
+		Desc: c.NewDescriptor("w.TrimPrefix", "prefix", prefix),
+		F: func(version string) (string, error) {
+			return strings.TrimPrefix(version, prefix), nil
+		},
+	}
+}
+
+// TrimSuffix returns a transform which removes a suffix if present.
+func TrimSuffix(suffix string) VersionTransform {
+	return VersionTransform{
+		Desc: c.NewDescriptor("w.TrimSuffix", "suffix", suffix),
+		F: func(version string) (string, error) {
+			return strings.TrimSuffix(version, suffix), nil
+		},
+	}
+}
+
+// FirstN returns a transform which keeps the first n dot-separated components.
+func FirstN(n int) VersionTransform {
+	return VersionTransform{
+		Desc: c.NewDescriptor("w.FirstN", "n", n),
+		F: func(version string) (string, error) {
+			spl := strings.Split(version, ".")
+			if n < len(spl) {
+				spl = spl[:n]
+			}
+			return strings.Join(spl, "."), nil
+		},
+	}
+}
+
+// PadN returns a transform which appends zero components until there are at least n.
+func PadN(n int) VersionTransform {
+	return VersionTransform{
+		Desc: c.NewDescriptor("w.PadN", "n", n),
+		F: func(version string) (string, error) {
+			spl := strings.Split(version, ".")
+			for len(spl) < n {
+				spl = append(spl, "0")
+			}
+			return strings.Join(spl, "."), nil
+		},
+	}
+}
+
+// Lower returns a transform which converts the version to lowercase.
+func Lower() VersionTransform {
+	return VersionTransform{
+		Desc: c.NewDescriptor("w.Lower"),
+		F: func(version string) (string, error) {
+			return strings.ToLower(version), nil
+		},
+	}
+}
+
+// Map returns a transform which looks up the version in a map (e.g. to convert a
+// marketing name to a version number). It is an error if the version is not in the map.
+func Map(m map[string]string) VersionTransform {
+	return VersionTransform{
+		Desc: c.NewDescriptor("w.Map", "map", mapParam(m)),
+		F: func(version string) (string, error) {
+			if mapped, ok := m[version]; ok {
+				return mapped, nil
+			}
+			return "", c.Errorf(c.CategoryValidation, "", "no mapping for version %#v", version)
+		},
+	}
+}
+
+// mapParam formats a map for a descriptor (e.g. 2019=16.0,2022=17.0).
+func mapParam(m map[string]string) []string {
+	ps := []string{}
+	for k, v := range m {
+		ps = append(ps, k+"="+v)
+	}
+	sort.Strings(ps)
+	return ps
+}
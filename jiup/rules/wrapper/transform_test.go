@@ -0,0 +1,78 @@
+package w
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionTransforms(t *testing.T) {
+	for _, tc := range []struct {
+		Name      string
+		Transform VersionTransform
+		In        string
+		Out       string
+		HasError  bool
+	}{
+		{"Replace", Replace("_", "."), "1_2_3", "1.2.3", false},
+		{"Replace none", Replace("_", "."), "1.2.3", "1.2.3", false},
+		{"ReplaceRe", ReplaceRe(h.Re("^([0-9])([0-9]+)$"), "$1.$2"), "582", "5.82", false},
+		{"ReplaceRe none", ReplaceRe(h.Re("^([0-9])([0-9]+)$"), "$1.$2"), "5.82", "5.82", false},
+		{"TrimPrefix", TrimPrefix("v"), "v1.2.3", "1.2.3", false},
+		{"TrimPrefix none", TrimPrefix("v"), "1.2.3", "1.2.3", false},
+		{"TrimSuffix", TrimSuffix("-stable"), "1.2.3-stable", "1.2.3", false},
+		{"TrimSuffix none", TrimSuffix("-stable"), "1.2.3", "1.2.3", false},
+		{"FirstN", FirstN(2), "1.2.3.4", "1.2", false},
+		{"FirstN short", FirstN(3), "1.2", "1.2", false},
+		{"PadN", PadN(4), "1.2", "1.2.0.0", false},
+		{"PadN long", PadN(2), "1.2.3", "1.2.3", false},
+		{"Lower", Lower(), "1.0-RC1", "1.0-rc1", false},
+		{"Map", Map(map[string]string{"2019": "16.0"}), "2019", "16.0", false},
+		{"Map missing", Map(map[string]string{"2019": "16.0"}), "2022", "", true},
+	} {
+		out, err := tc.Transform.F(tc.In)
+		if tc.HasError {
+			assert.Error(t, err, tc.Name)
+			continue
+		}
+		assert.NoError(t, err, tc.Name)
+		assert.Equal(t, tc.Out, out, tc.Name)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	f := c.VersionInfoExtractorFunc(func(context.Context) (c.VersionInfo, error) {
+		return c.VersionInfo{Version: "v1_2", Fields: map[string]string{"Build": "123"}}, nil
+	})
+
+	version, err := Transform(f, TrimPrefix("v"), Replace("_", "."), PadN(3)).Structured()(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, c.VersionInfo{Version: "1.2.0", Fields: map[string]string{"Build": "123"}}, version)
+
+	version, err = Transform(f).Structured()(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "v1_2", version.Version)
+
+	_, err = Transform(f, Map(map[string]string{})).Structured()(context.Background())
+	assert.Error(t, err)
+
+	_, err = Transform(c.VersionExtractorFunc(func() (string, error) {
+		return "", errors.New("test")
+	}), Lower()).Structured()(context.Background())
+	assert.EqualError(t, err, "test")
+}
+
+func TestTransformDescriptor(t *testing.T) {
+	f := Transform(c.VersionExtractorFunc(func() (string, error) {
+		return "", nil
+	}), TrimPrefix("v"), Map(map[string]string{"b": "2", "a": "1"}), FirstN(2))
+	assert.Equal(t, `w.Transform(of: custom(), transforms: w.TrimPrefix(prefix="v"), transforms: w.Map(map="a=1,b=2"), transforms: w.FirstN(n="2"))`, f.Descriptor().String())
+
+	assert.Equal(t, `w.Transform(of: custom(), transforms: w.Replace(new=".", old="_"))`, UnderscoreToDot(c.VersionExtractorFunc(func() (string, error) {
+		return "", nil
+	})).Descriptor().String())
+}
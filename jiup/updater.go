@@ -1,12 +1,19 @@
 package jiup
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
+	"time"
 
+	"github.com/just-install/just-install-updater-go/jiup/history"
 	"github.com/just-install/just-install-updater-go/jiup/rules"
 	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+	w "github.com/just-install/just-install-updater-go/jiup/rules/wrapper"
 
 	"github.com/just-install/just-install-updater-go/jiup/registry"
 )
@@ -14,23 +21,69 @@ import (
 // Updater represents an instance of the updater.
 type Updater struct {
 	Registry *registry.Registry
+
+	// Rules is the set of rules used to update the packages (e.g. rules.Default).
+	Rules *rules.RuleSet
+
+	// CheckVersions controls whether the download links are checked to contain the
+	// version (see rules.RuleSet.CheckVersion). Mismatches are returned as a
+	// *c.VersionMismatchError.
+	CheckVersions bool
+
+	// CheckZips controls whether the downloads of packages which are or contain a zip
+	// are checked to contain the container installer and the shims (see zipPaths).
+	CheckZips bool
+
+	// ClientOptions are the default options for the HTTP clients used by the rules, which
+	// can be overridden by each rule.
+	ClientOptions h.ClientOptions
+
+	// ClientFactory creates the HTTP clients used by the rules. If nil, clients are
+	// created with h.NewClient and reused for identical options.
+	ClientFactory h.ClientFactory
+
+	// Cache is an optional persistent HTTP cache used for the clients.
+	Cache *h.DiskCache
+
+	// RecordHAR is an optional directory to record the HTTP requests for each package
+	// into (as pkg.har).
+	RecordHAR string
+
+	// ReplayHAR is an optional directory to replay the HTTP requests for each package
+	// from (see RecordHAR) instead of making them. Requests which were not recorded fail.
+	ReplayHAR string
+
+	// History is an optional store to record the outcome and latency of each rule
+	// which was run into (see the health command).
+	History *history.Store
+
+	// Observations are the versions and download links found by the last Update. They
+	// should only be recorded into History (see history.Store.AppendObservations) once
+	// the updated registry has been written.
+	Observations []history.Observation
+
+	// RuleTimeout is the maximum time to spend on each extractor of a package, or zero
+	// for no limit.
+	RuleTimeout time.Duration
+
 	packages []string
 }
 
 // ErrNoSuchPackage is returned if one or more specified packages does not exist.
 var ErrNoSuchPackage = errors.New("one or more of the specified packages does not exist")
 
-// New returns a new instance of Updater.
-func New(registry *registry.Registry) *Updater {
+// New returns a new instance of Updater which uses a set of rules.
+func New(registry *registry.Registry, ruleSet *rules.RuleSet) *Updater {
 	return &Updater{
 		Registry: registry,
+		Rules:    ruleSet,
 		packages: []string{},
 	}
 }
 
 // NewForPackages returns a new instance of Updater which only updates a selected set of packages.
-func NewForPackages(registry *registry.Registry, packages []string) (*Updater, error) {
-	u := New(registry)
+func NewForPackages(registry *registry.Registry, ruleSet *rules.RuleSet, packages []string) (*Updater, error) {
+	u := New(registry, ruleSet)
 	u.packages = packages
 	for _, pkgName := range u.packages {
 		if _, ok := u.Registry.Packages[pkgName]; !ok {
@@ -40,8 +93,11 @@ func NewForPackages(registry *registry.Registry, packages []string) (*Updater, e
 	return u, nil
 }
 
-// Update updates the registry.
-func (u *Updater) Update(progress, verbose, force bool, broken map[string]error) (updated map[string]string, unchanged []string, norule []string, rolling []string, skipped []string, errored map[string]error) {
+// Update updates the registry. If the context is cancelled, it stops and returns the
+// results for the packages checked so far (the context error can be checked afterwards).
+// Packages in broken (e.g. from ledger.Ledger.Skipped) are not checked, and are returned
+// as errored with the error from the map.
+func (u *Updater) Update(ctx context.Context, progress, verbose, force bool, broken map[string]error) (updated map[string]string, unchanged []string, norule []string, rolling []string, skipped []string, errored map[string]error) {
 	updated = map[string]string{}
 	unchanged = []string{}
 	norule = []string{}
@@ -50,6 +106,30 @@ func (u *Updater) Update(progress, verbose, force bool, broken map[string]error)
 	errored = map[string]error{}
 	// TODO: multithreaded for loop.
 	c.Verbose = verbose
+	factory := u.ClientFactory
+	if u.Cache != nil {
+		if factory == nil {
+			factory = h.NewClient
+		}
+		factory = h.CachedClientFactory(u.Cache.ClientFactory(factory))
+	}
+	ctx = h.WithClientFactory(ctx, factory, u.ClientOptions)
+
+	start := time.Now()
+	run := history.RunID("updater", start)
+	latency := map[string]time.Duration{}
+	u.Observations = []history.Observation{}
+	if u.History != nil {
+		defer func() {
+			results := []history.Result{}
+			for pkgName, l := range latency {
+				results = append(results, history.NewResult(run, pkgName, start, l, errored[pkgName]))
+			}
+			if err := u.History.AppendResults(results...); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to record rule health: %v\n", err)
+			}
+		}()
+	}
 
 	allpkgs := []string{}
 	for pkgName := range u.Registry.Packages {
@@ -59,6 +139,13 @@ func (u *Updater) Update(progress, verbose, force bool, broken map[string]error)
 
 	i := 0
 	for _, pkgName := range allpkgs {
+		if ctx.Err() != nil {
+			if verbose {
+				fmt.Printf("  Stopping: %v\n", ctx.Err())
+			}
+			break
+		}
+
 		i++
 		if progress {
 			fmt.Printf("[%d/%d] Checking %s\n", i, len(u.Registry.Packages), pkgName)
@@ -74,15 +161,15 @@ func (u *Updater) Update(progress, verbose, force bool, broken map[string]error)
 
 		if broken != nil {
 			if err, ok := broken[pkgName]; ok {
-				errored[pkgName] = fmt.Errorf("broken: %v", err)
+				errored[pkgName] = err
 				if verbose {
-					fmt.Printf("  Found saved error for %s: %v\n", pkgName, err)
+					fmt.Printf("  Skipped %s because it is marked as broken: %v\n", pkgName, err)
 				}
 				continue
 			}
 		}
 
-		v, d, ok := rules.GetRule(pkgName)
+		rule, ok := u.Rules.Get(pkgName)
 		if !ok {
 			if u.Registry.Packages[pkgName].Version == "latest" {
 				rolling = append(rolling, pkgName)
@@ -95,10 +182,42 @@ func (u *Updater) Update(progress, verbose, force bool, broken map[string]error)
 			continue
 		}
 
+		v, d := rule.V, rule.D
+		if u.RuleTimeout > 0 {
+			v, d = w.Timeout(u.RuleTimeout, v).Structured(), w.TimeoutDownloads(u.RuleTimeout, d).Structured()
+		}
+
+		pctx, save := ctx, func() {}
+		if u.ReplayHAR != "" {
+			r, err := h.LoadHARReplayer(filepath.Join(u.ReplayHAR, pkgName+".har"))
+			if err != nil {
+				errored[pkgName] = err
+				if verbose {
+					fmt.Printf("  Error loading recorded requests for %s: %v\n", pkgName, err)
+				}
+				continue
+			}
+			pctx = h.WithHARReplayer(pctx, r)
+		} else if u.RecordHAR != "" {
+			r, fn := h.NewHARRecorder(nil), filepath.Join(u.RecordHAR, pkgName+".har")
+			pctx = h.WithHARRecorder(pctx, r)
+			save = func() {
+				if err := h.SaveHAR(r, fn); err != nil && verbose {
+					fmt.Printf("  Error saving recorded requests for %s: %v\n", pkgName, err)
+				}
+			}
+		}
+
 		if verbose {
 			fmt.Printf("  Getting version for %s\n", pkgName)
 		}
-		version, err := v()
+		rstart := time.Now()
+		vi, err := v(pctx)
+		save()
+		if ctx.Err() != nil {
+			break
+		}
+		latency[pkgName] = time.Since(rstart)
 		if err != nil {
 			errored[pkgName] = err
 			if verbose {
@@ -106,8 +225,12 @@ func (u *Updater) Update(progress, verbose, force bool, broken map[string]error)
 			}
 			continue
 		}
+		version := vi.Version
 		if verbose {
 			fmt.Printf("  Version for %s: %s -> %s\n", pkgName, u.Registry.Packages[pkgName].Version, version)
+			for k, f := range vi.Fields {
+				fmt.Printf("  %s: %s: %s\n", pkgName, k, f)
+			}
 		}
 
 		if !force && u.Registry.Packages[pkgName].Version != "latest" && u.Registry.Packages[pkgName].Version == version {
@@ -121,7 +244,13 @@ func (u *Updater) Update(progress, verbose, force bool, broken map[string]error)
 		if verbose {
 			fmt.Printf("  Getting links for %s\n", pkgName)
 		}
-		x86dl, x86_64dl, err := d(version)
+		dstart := time.Now()
+		dls, err := d(pctx, vi)
+		save()
+		if ctx.Err() != nil {
+			break
+		}
+		latency[pkgName] += time.Since(dstart)
 		if err != nil {
 			errored[pkgName] = err
 			if verbose {
@@ -130,28 +259,64 @@ func (u *Updater) Update(progress, verbose, force bool, broken map[string]error)
 			continue
 		}
 		if verbose {
-			if x86dl != nil {
-				fmt.Printf("  %s: x86: %s\n", pkgName, *x86dl)
-			}
-			if x86_64dl != nil {
-				fmt.Printf("  %s: x86_64: %s\n", pkgName, *x86_64dl)
-			} else {
-				fmt.Printf("  %s: x86_64: <nil>\n", pkgName)
+			for _, arch := range c.Archs {
+				if dl, ok := dls[arch]; ok {
+					fmt.Printf("  %s: %s: %s\n", pkgName, arch, dl.URL)
+					if dl.FileName != "" || dl.Size > 0 || dl.Checksum != "" || !dl.ReleaseDate.IsZero() {
+						fmt.Printf("  %s: %s: filename=%q size=%d checksum=%q date=%s\n", pkgName, arch, dl.FileName, dl.Size, dl.Checksum, dl.ReleaseDate.Format("2006-01-02"))
+					}
+				} else {
+					fmt.Printf("  %s: %s: <nil>\n", pkgName, arch)
+				}
 			}
 		}
 
-		if x86dl == nil && x86_64dl == nil {
-			errored[pkgName] = errors.New("x86 and x86_64 urls are both empty")
+		if len(dls) == 0 {
+			errored[pkgName] = c.WithRule(pkgName, c.Errorf(c.CategoryValidation, "", "x86, x86_64 and arm64 urls are all empty"))
 			if verbose {
-				fmt.Printf("  Error parsing links for %s: %v\n", pkgName, err)
+				fmt.Printf("  Error parsing links for %s: %v\n", pkgName, errored[pkgName])
 			}
 			continue
 		}
 
+		if u.CheckVersions {
+			if err := u.Rules.CheckVersion(pkgName, vi, dls); err != nil {
+				errored[pkgName] = err
+				if verbose {
+					fmt.Printf("  Error checking links for %s: %v\n", pkgName, err)
+				}
+				continue
+			}
+		}
+
+		if u.CheckZips {
+			pkg := u.Registry.Packages[pkgName]
+			err := checkZips(pctx, pkgName, &pkg, dls)
+			save()
+			if ctx.Err() != nil {
+				break
+			}
+			if err != nil {
+				errored[pkgName] = err
+				if verbose {
+					fmt.Printf("  Error checking zips for %s: %v\n", pkgName, err)
+				}
+				continue
+			}
+		}
+
+		u.Observations = append(u.Observations, history.NewObservation(run, pkgName, rule.Source, time.Now(), version, dls))
+
 		tmp := u.Registry.Packages[pkgName]
 		if tmp.Version == "latest" {
 			rolling = append(rolling, pkgName)
-			if !((x86dl != nil && tmp.Installer.X86 != nil && *tmp.Installer.X86 != *x86dl) || x86_64dl != nil && tmp.Installer.X86_64 != nil && *tmp.Installer.X86_64 != *x86_64dl) {
+			changed := false
+			for arch, dl := range dls {
+				if cur := *installerURL(&tmp, arch); cur != nil && *cur != dl.URL {
+					changed = true
+				}
+			}
+			if !changed {
 				// Not updated a package with no version
 				if verbose {
 					fmt.Printf("  Version for %s is latest, and download links have not changed\n", pkgName)
@@ -160,8 +325,9 @@ func (u *Updater) Update(progress, verbose, force bool, broken map[string]error)
 				continue
 			}
 		}
-		tmp.Installer.X86 = x86dl
-		tmp.Installer.X86_64 = x86_64dl
+		for _, arch := range c.Archs {
+			*installerURL(&tmp, arch) = dls.URL(arch)
+		}
 		tmp.Version = version
 		u.Registry.Packages[pkgName] = tmp
 
@@ -0,0 +1,196 @@
+package jiup
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/just-install/just-install-updater-go/jiup/registry"
+	"github.com/just-install/just-install-updater-go/jiup/rules"
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	d "github.com/just-install/just-install-updater-go/jiup/rules/download"
+	v "github.com/just-install/just-install-updater-go/jiup/rules/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPackage(version, x86 string) registry.Package {
+	var pkg registry.Package
+	pkg.Version = version
+	pkg.Installer.Kind = registry.InstallerKindAsIs
+	pkg.Installer.X86 = &x86
+	return pkg
+}
+
+func fixedVersion(version string, err error, calls *int) c.VersionInfoExtractorFunc {
+	return func(context.Context) (c.VersionInfo, error) {
+		if calls != nil {
+			*calls++
+		}
+		if err != nil {
+			return c.VersionInfo{}, err
+		}
+		return c.VersionInfo{Version: version}, nil
+	}
+}
+
+func testRules(t *testing.T, brokenCalls *int) *rules.RuleSet {
+	s := rules.NewRuleSet()
+	for _, r := range []struct {
+		pkg string
+		v   c.VersionExtractor
+		d   c.DownloadExtractor
+	}{
+		{"new", fixedVersion("2.0", nil, nil), d.Template("https://example.com/new-{{.Version}}.exe", "https://example.com/new-{{.Version}}-x64.exe")},
+		{"same", fixedVersion("2.0", nil, nil), d.Template("https://example.com/same-{{.Version}}.exe", "")},
+		{"rolling-same", v.Latest(), d.Template("https://example.com/rolling-same.exe", "")},
+		{"rolling-changed", v.Latest(), d.Template("https://example.com/rolling-changed-2.exe", "")},
+		{"error", fixedVersion("", errors.New("test"), nil), d.Template("https://example.com/error-{{.Version}}.exe", "")},
+		{"mismatch", fixedVersion("2.0", nil, nil), d.Template("https://example.com/mismatch-{{.Version0}}.exe", "")},
+		{"broken", fixedVersion("2.0", nil, brokenCalls), d.Template("https://example.com/broken-{{.Version}}.exe", "")},
+	} {
+		assert.NoError(t, s.Register(r.pkg, r.v, r.d))
+	}
+	return s
+}
+
+func testRegistry() *registry.Registry {
+	return &registry.Registry{
+		Version: registry.RegistryVersion,
+		Packages: map[string]registry.Package{
+			"new":             testPackage("1.0", "https://example.com/new-1.0.exe"),
+			"same":            testPackage("2.0", "https://example.com/same-2.0.exe"),
+			"rolling-same":    testPackage("latest", "https://example.com/rolling-same.exe"),
+			"rolling-changed": testPackage("latest", "https://example.com/rolling-changed-1.exe"),
+			"rolling-norule":  testPackage("latest", "https://example.com/rolling-norule.exe"),
+			"norule":          testPackage("1.0", "https://example.com/norule-1.0.exe"),
+			"error":           testPackage("1.0", "https://example.com/error-1.0.exe"),
+			"mismatch":        testPackage("1.0", "https://example.com/mismatch-1.exe"),
+			"broken":          testPackage("1.0", "https://example.com/broken-1.0.exe"),
+		},
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	brokenCalls := 0
+	u := New(testRegistry(), testRules(t, &brokenCalls))
+	u.CheckVersions = true
+
+	updated, unchanged, norule, rolling, skipped, errored := u.Update(context.Background(), false, false, false, map[string]error{
+		"broken": errors.New("marked as broken"),
+	})
+	sort.Strings(unchanged)
+	sort.Strings(norule)
+	sort.Strings(rolling)
+
+	assert.Equal(t, map[string]string{"new": "2.0", "rolling-changed": "latest"}, updated)
+	assert.Equal(t, []string{"rolling-same", "same"}, unchanged)
+	assert.Equal(t, []string{"norule"}, norule)
+	assert.Equal(t, []string{"rolling-changed", "rolling-norule", "rolling-same"}, rolling)
+	assert.Empty(t, skipped)
+	if assert.Len(t, errored, 3) {
+		assert.EqualError(t, errored["broken"], "marked as broken")
+		assert.EqualError(t, errored["error"], "test")
+		var mismatch *c.VersionMismatchError
+		assert.True(t, errors.As(errored["mismatch"], &mismatch), "links should be checked to contain the version")
+	}
+	assert.Zero(t, brokenCalls, "broken rules should not be run")
+
+	pkg := u.Registry.Packages["new"]
+	assert.Equal(t, "2.0", pkg.Version)
+	if assert.NotNil(t, pkg.Installer.X86) && assert.NotNil(t, pkg.Installer.X86_64) {
+		assert.Equal(t, "https://example.com/new-2.0.exe", *pkg.Installer.X86)
+		assert.Equal(t, "https://example.com/new-2.0-x64.exe", *pkg.Installer.X86_64)
+	}
+	assert.Nil(t, pkg.Installer.ARM64)
+	assert.Equal(t, "https://example.com/rolling-changed-2.exe", *u.Registry.Packages["rolling-changed"].Installer.X86)
+	assert.Equal(t, "1.0", u.Registry.Packages["error"].Version, "errored packages should not be changed")
+	assert.Equal(t, "1.0", u.Registry.Packages["mismatch"].Version, "errored packages should not be changed")
+
+	observed := map[string]string{}
+	for _, o := range u.Observations {
+		assert.Equal(t, rules.SourceBuiltin, o.Rule)
+		observed[o.Package] = o.URLs[c.ArchX86]
+	}
+	assert.Equal(t, map[string]string{
+		"new":             "https://example.com/new-2.0.exe",
+		"rolling-same":    "https://example.com/rolling-same.exe",
+		"rolling-changed": "https://example.com/rolling-changed-2.exe",
+	}, observed, "only the links which are in the registry should be observed")
+}
+
+func TestUpdateForce(t *testing.T) {
+	u := New(testRegistry(), testRules(t, nil))
+	updated, unchanged, _, _, _, _ := u.Update(context.Background(), false, false, true, nil)
+	assert.Equal(t, "2.0", updated["same"], "unchanged packages should be updated with force")
+	assert.Equal(t, "2.0", updated["mismatch"], "versions should not be checked unless enabled")
+	assert.Equal(t, "2.0", updated["broken"], "packages should only be skipped if marked as broken")
+	assert.Equal(t, []string{"rolling-same"}, unchanged, "rolling packages should only be updated if the links changed")
+}
+
+func TestUpdateCheckZips(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	zw := zip.NewWriter(buf)
+	_, err := zw.Create("setup.exe")
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "test.zip", time.Time{}, bytes.NewReader(buf.Bytes()))
+	}))
+	defer s.Close()
+
+	r := &registry.Registry{Version: registry.RegistryVersion, Packages: map[string]registry.Package{}}
+	set := rules.NewRuleSet()
+	for pkgName, installer := range map[string]string{"found": "setup.exe", "missing": "other.exe"} {
+		var pkg registry.Package
+		assert.NoError(t, json.Unmarshal([]byte(`{"installer": {"kind": "as-is", "options": {"container": {"installer": "`+installer+`", "kind": "zip"}}, "x86": "x"}, "version": "1.0"}`), &pkg))
+		r.Packages[pkgName] = pkg
+		assert.NoError(t, set.Register(pkgName, fixedVersion("2.0", nil, nil), d.Template(s.URL+"/"+pkgName+"-{{.Version}}.zip", "")))
+	}
+
+	u := New(r, set)
+	u.CheckZips = true
+	updated, _, _, _, _, errored := u.Update(context.Background(), false, false, false, nil)
+	assert.Equal(t, map[string]string{"found": "2.0"}, updated)
+	if assert.Len(t, errored, 1) {
+		assert.EqualError(t, errored["missing"], "missing from zip: other.exe ("+s.URL+"/missing-2.0.zip)")
+		assert.Equal(t, c.CategoryValidation, c.Category(errored["missing"]))
+	}
+}
+
+func TestUpdateForPackages(t *testing.T) {
+	_, err := NewForPackages(testRegistry(), testRules(t, nil), []string{"new", "missing"})
+	assert.Equal(t, ErrNoSuchPackage, err)
+
+	u, err := NewForPackages(testRegistry(), testRules(t, nil), []string{"new", "norule"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	updated, unchanged, norule, rolling, skipped, errored := u.Update(context.Background(), false, false, false, nil)
+	assert.Equal(t, map[string]string{"new": "2.0"}, updated)
+	assert.Empty(t, unchanged)
+	assert.Equal(t, []string{"norule"}, norule)
+	assert.Empty(t, rolling)
+	assert.Len(t, skipped, len(u.Registry.Packages)-2)
+	assert.Empty(t, errored)
+}
+
+func TestUpdateCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	u := New(testRegistry(), testRules(t, nil))
+	updated, unchanged, norule, rolling, skipped, errored := u.Update(ctx, false, false, false, nil)
+	assert.Empty(t, updated)
+	assert.Empty(t, unchanged)
+	assert.Empty(t, norule)
+	assert.Empty(t, rolling)
+	assert.Empty(t, skipped)
+	assert.Empty(t, errored)
+	assert.Equal(t, "1.0", u.Registry.Packages["new"].Version)
+}
@@ -1,5 +1,14 @@
 package jiup
 
+import (
+	"context"
+	"strings"
+
+	"github.com/just-install/just-install-updater-go/jiup/registry"
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
+)
+
 func includes(arr []string, val string) bool {
 	for i := range arr {
 		if arr[i] == val {
@@ -8,3 +17,83 @@ func includes(arr []string, val string) bool {
 	}
 	return false
 }
+
+// installerURL returns a pointer to the installer url field for an architecture.
+func installerURL(pkg *registry.Package, arch c.Arch) **string {
+	switch arch {
+	case c.ArchX86:
+		return &pkg.Installer.X86
+	case c.ArchX86_64:
+		return &pkg.Installer.X86_64
+	case c.ArchARM64:
+		return &pkg.Installer.ARM64
+	}
+	panic("unknown arch " + string(arch))
+}
+
+// installerOptions returns the options for an architecture, which are either the
+// options for all architectures, or the ones for the architecture if x86 is set.
+func installerOptions(pkg *registry.Package, arch c.Arch) *registry.Options {
+	opts := pkg.Installer.Options
+	if opts == nil {
+		return nil
+	}
+	if opts.X86 == nil {
+		return opts.Options
+	}
+	switch arch {
+	case c.ArchX86:
+		return opts.X86
+	case c.ArchX86_64:
+		return opts.X86_64
+	case c.ArchARM64:
+		return opts.ARM64
+	}
+	return nil
+}
+
+// zipPaths returns the paths which should exist in the download for an architecture:
+// the installer for a zip container, and the shims for a zip package which are in
+// the destination (the other shims can't be checked).
+func zipPaths(pkg *registry.Package, arch c.Arch) []string {
+	opts := installerOptions(pkg, arch)
+	if opts == nil {
+		return nil
+	}
+	paths := []string{}
+	if opts.Container != nil && opts.Container.ContainerKind == registry.ContainerKindZip {
+		paths = append(paths, opts.Container.Installer)
+	}
+	if pkg.Installer.Kind == registry.InstallerKindZip && opts.Shims != nil && opts.Destination != nil {
+		prefix := strings.ToLower(strings.TrimRight(*opts.Destination, "\\/")) + "\\"
+		for _, shim := range *opts.Shims {
+			if strings.HasPrefix(strings.ToLower(shim), prefix) {
+				paths = append(paths, shim[len(prefix):])
+			}
+		}
+	}
+	return paths
+}
+
+// checkZips checks that the paths from zipPaths exist in the downloads. Only the
+// central directory of each zip is downloaded if the server supports range requests.
+func checkZips(ctx context.Context, pkgName string, pkg *registry.Package, dls c.Downloads) error {
+	for _, arch := range c.Archs {
+		dl, ok := dls[arch]
+		if !ok {
+			continue
+		}
+		paths := zipPaths(pkg, arch)
+		if len(paths) == 0 {
+			continue
+		}
+		missing, err := h.MissingZipEntries(ctx, nil, dl.URL, paths)
+		if err != nil {
+			return c.WithRule(pkgName, err)
+		}
+		if len(missing) > 0 {
+			return c.WithRule(pkgName, c.Errorf(c.CategoryValidation, dl.URL, "missing from zip: %s", strings.Join(missing, ", ")).WithArch(arch))
+		}
+	}
+	return nil
+}
@@ -1,8 +1,11 @@
 package jiup
 
 import (
+	"encoding/json"
 	"testing"
 
+	"github.com/just-install/just-install-updater-go/jiup/registry"
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,3 +27,36 @@ func TestIncludes(t *testing.T) {
 		assert.False(t, includes(c.Arr, c.Val))
 	}
 }
+
+func TestZipPaths(t *testing.T) {
+	for _, tc := range []struct {
+		JSON  string
+		Paths map[c.Arch][]string
+	}{
+		{`{"installer": {"kind": "as-is", "x86": "x"}}`, map[c.Arch][]string{c.ArchX86: nil}},
+		{`{"installer": {"kind": "as-is", "options": {"container": {"installer": "setup.exe", "kind": "zip"}}, "x86": "x"}}`, map[c.Arch][]string{
+			c.ArchX86:    {"setup.exe"},
+			c.ArchX86_64: {"setup.exe"},
+		}},
+		{`{"installer": {"kind": "zip", "options": {"destination": "{{.PROGRAMFILES}}\\hugo\\", "shims": ["{{.PROGRAMFILES}}\\Hugo\\bin\\hugo.exe", "{{.SYSTEMROOT}}\\other.exe"]}, "x86": "x"}}`, map[c.Arch][]string{
+			c.ArchX86: {"bin\\hugo.exe"},
+		}},
+		{`{"installer": {"kind": "zip", "options": {"x86": {"destination": "a", "shims": ["a\\x86.exe"]}, "x86_64": {"destination": "a", "shims": ["a\\x64.exe"]}}, "x86": "x"}}`, map[c.Arch][]string{
+			c.ArchX86:    {"x86.exe"},
+			c.ArchX86_64: {"x64.exe"},
+			c.ArchARM64:  nil,
+		}},
+	} {
+		var pkg registry.Package
+		if !assert.NoError(t, json.Unmarshal([]byte(tc.JSON), &pkg)) {
+			continue
+		}
+		for arch, paths := range tc.Paths {
+			if paths == nil {
+				assert.Empty(t, zipPaths(&pkg, arch), tc.JSON)
+			} else {
+				assert.Equal(t, paths, zipPaths(&pkg, arch), tc.JSON)
+			}
+		}
+	}
+}
@@ -1,15 +1,22 @@
 package main
 
 import (
-	"bufio"
-	"errors"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/signal"
 	"strings"
+	"time"
 
 	"github.com/just-install/just-install-updater-go/jiup"
+	"github.com/just-install/just-install-updater-go/jiup/history"
+	"github.com/just-install/just-install-updater-go/jiup/ledger"
 	"github.com/just-install/just-install-updater-go/jiup/registry"
+	"github.com/just-install/just-install-updater-go/jiup/rules"
+	c "github.com/just-install/just-install-updater-go/jiup/rules/common"
+	"github.com/just-install/just-install-updater-go/jiup/rules/declarative"
+	h "github.com/just-install/just-install-updater-go/jiup/rules/helper"
 	"github.com/spf13/pflag"
 )
 
@@ -18,7 +25,19 @@ func main() {
 	dryRun := pflag.BoolP("dry-run", "d", false, "Do not actually write the changes")
 	force := pflag.BoolP("force", "f", false, "Update all entries including ones with a matching version")
 	commitMessageFile := pflag.StringP("commit-message-file", "c", "", "If set, jiup-go will save a commit message describing the changes to a file.")
-	readBroken := pflag.StringP("read-broken", "b", "", "If set, jiup-go will ignore rules listed in the specified file")
+	ledgerFile := pflag.StringP("ledger", "b", "", "If set, jiup-go will skip rules manually marked as broken in the specified ledger, and record broken rules into it")
+	checkVersions := pflag.Bool("check-versions", false, "Check that the download links contain the version")
+	checkZips := pflag.Bool("check-zips", false, "Check that the zip downloads contain the container installer and shims (only the zip index is downloaded if possible)")
+	timeout := pflag.Duration("timeout", 0, "The maximum time to spend on each extractor of a package (0 for no limit)")
+	proxy := pflag.String("proxy", "", "The proxy to use for HTTP requests (default is from the environment)")
+	userAgent := pflag.String("user-agent", "", "The User-Agent to use for HTTP requests")
+	cacheDir := pflag.String("cache-dir", "", "If set, HTTP responses will be cached in the specified directory across runs and revalidated (e.g. ~/.cache/just-install-updater)")
+	noCache := pflag.Bool("no-cache", false, "Do not cache HTTP responses across runs, even if --cache-dir is set")
+	recordHAR := pflag.String("record-har", "", "If set, the HTTP requests for each package will be recorded into HAR files in the specified directory")
+	replayHAR := pflag.String("replay-har", "", "If set, the HTTP requests for each package will be replayed from HAR files in the specified directory")
+	stateDir := pflag.String("state-dir", history.DefaultDir(), "The directory to record the health of the rules and the versions found in across runs (see the health and versions commands)")
+	noHistory := pflag.Bool("no-history", false, "Do not record the health of the rules or the versions found")
+	rulesDir := pflag.String("rules-dir", "", "If set, rules will also be loaded from the JSON files in the specified directory, replacing the built-in rules for the same packages")
 	quiet := pflag.BoolP("quiet", "q", false, "Do not output progress info")
 	help := pflag.Bool("help", false, "Show this help text")
 	pflag.Parse()
@@ -27,6 +46,18 @@ func main() {
 		helpExit()
 	}
 
+	if *rulesDir != "" {
+		set, err := declarative.Load(*rulesDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading rules:\n%v\n", err)
+			os.Exit(1)
+		}
+		rules.Default.Merge(set)
+		if *verbose {
+			fmt.Printf("Loaded %d rules from %s\n", len(set.List()), *rulesDir)
+		}
+	}
+
 	registryPath := pflag.Arg(0)
 	buf, err := ioutil.ReadFile(registryPath)
 	if err != nil {
@@ -39,52 +70,91 @@ func main() {
 		os.Exit(1)
 	}
 
-	u := jiup.New(r)
+	u := jiup.New(r, rules.Default)
 	if pflag.NArg() > 1 {
-		u, err = jiup.NewForPackages(r, pflag.Args()[1:])
+		u, err = jiup.NewForPackages(r, rules.Default, pflag.Args()[1:])
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 	}
 
-	var broken map[string]error
-	if *readBroken != "" {
-		broken = map[string]error{}
+	u.CheckVersions = *checkVersions
+	u.CheckZips = *checkZips
+	u.RuleTimeout = *timeout
+	u.ClientOptions = h.ClientOptions{Proxy: *proxy, UserAgent: *userAgent}
+	u.RecordHAR = *recordHAR
+	u.ReplayHAR = *replayHAR
 
-		f, err := os.Open(*readBroken)
+	if !*noCache && *cacheDir != "" {
+		u.Cache, err = h.NewDiskCache(*cacheDir, 0, 0)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: failed to open %q: %v\n", *readBroken, err)
+			fmt.Fprintf(os.Stderr, "Error opening cache: %v\n", err)
 			os.Exit(1)
 		}
+	}
 
-		sc := bufio.NewScanner(f)
-		for sc.Scan() {
-			spl := strings.SplitN(sc.Text(), ":", 2)
-			if len(spl) != 2 || spl[0] == "" {
-				continue
-			}
+	if !*noHistory && *stateDir != "" && *replayHAR == "" {
+		u.History, err = history.Open(*stateDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening state dir: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
-			var err error
-			if spl[1] != "" {
-				err = errors.New(spl[1])
-			} else {
-				err = errors.New("error")
-			}
+	var l *ledger.Ledger
+	var broken map[string]error
+	if *ledgerFile != "" {
+		l, err = ledger.Load(*ledgerFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to read ledger %q: %v\n", *ledgerFile, err)
+			os.Exit(1)
+		}
+		broken = l.Skipped(time.Now())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	updated, unchanged, norule, rolling, skipped, errored := u.Update(ctx, !*quiet, *verbose, *force, broken)
+	interrupted := ctx.Err() != nil
+	stop()
 
-			broken[spl[0]] = err
+	if u.Cache != nil {
+		if err := u.Cache.Prune(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to prune cache: %v\n", err)
 		}
+	}
 
-		f.Close()
+	if u.History != nil {
+		if err := u.History.PruneResults(time.Now().Add(-history.MaxAge)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to prune rule health: %v\n", err)
+		}
+	}
+
+	if interrupted {
+		// Don't write partial results.
+		*dryRun = true
+		*commitMessageFile = ""
+	}
 
-		if sc.Err() != nil {
-			fmt.Fprintf(os.Stderr, "Error: failed to read %q: %v\n", *readBroken, err)
+	if l != nil && !*dryRun {
+		now := time.Now()
+		for pkgName, err := range errored {
+			if _, ok := broken[pkgName]; !ok {
+				l.Record(pkgName, err, now)
+			}
+		}
+		for pkgName := range updated {
+			l.Record(pkgName, nil, now)
+		}
+		for _, pkgName := range append(unchanged, rolling...) {
+			l.Record(pkgName, nil, now)
+		}
+		if err := l.Save(*ledgerFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing ledger: %v\n", err)
 			os.Exit(1)
 		}
 	}
 
-	updated, unchanged, norule, rolling, skipped, errored := u.Update(!*quiet, *verbose, *force, broken)
-
 	if commitMessageFile != nil && *commitMessageFile != "" {
 		pkgs := []string{}
 		pkgvs := []string{}
@@ -106,7 +176,7 @@ func main() {
 		if len(errored) > 0 {
 			errs := []string{}
 			for pkg, err := range errored {
-				errs = append(errs, "  - "+pkg+" ("+err.Error()+")")
+				errs = append(errs, "  - "+pkg+" ("+string(c.Category(err))+": "+err.Error()+")")
 			}
 			cMessage = cMessage + "\nErrors:\n" + strings.Join(errs, "\n") + "\n"
 		}
@@ -130,6 +200,12 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error writing new registry: %v\n", err)
 			os.Exit(1)
 		}
+
+		if u.History != nil {
+			if err := u.History.AppendObservations(u.Observations...); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to record versions: %v\n", err)
+			}
+		}
 	}
 
 	fmt.Printf("\n===== RESULTS =====\n")
@@ -161,16 +237,27 @@ func main() {
 		}
 		fmt.Printf("\n")
 	}
-	if len(errored) > 0 {
-		fmt.Printf("Errors:\n")
-		for pkgName, err := range errored {
-			fmt.Fprintf(os.Stderr, "  %s: %v\n", pkgName, err)
+	groups := c.GroupByCategory(errored)
+	for _, cat := range c.ErrorCategories {
+		if len(groups[cat]) == 0 {
+			continue
+		}
+		fmt.Printf("Errors (%s):\n", cat)
+		for _, pkgName := range groups[cat] {
+			fmt.Fprintf(os.Stderr, "  %s: %v\n", pkgName, errored[pkgName])
 		}
 		fmt.Printf("\n")
 	}
-	fmt.Printf("Summary: %d updated, %d unchanged, %d norule (%.0f%%), %d rolling, %d skipped, %d errored\n", len(updated), len(unchanged), len(norule), float32(len(norule))/float32(len(u.Registry.Packages))*100.0, len(rolling), len(skipped), len(errored))
+	fmt.Printf("Summary: %d updated, %d unchanged, %d norule (%.0f%%), %d rolling, %d skipped, %d errored", len(updated), len(unchanged), len(norule), float32(len(norule))/float32(len(u.Registry.Packages))*100.0, len(rolling), len(skipped), len(errored))
+	if len(errored) > 0 {
+		fmt.Printf(" (%s)", c.FormatCategoryCounts(groups))
+	}
+	fmt.Printf("\n")
 
-	if *dryRun {
+	if interrupted {
+		fmt.Printf("\nINTERRUPTED. NO CHANGES WERE MADE.\n")
+		os.Exit(130)
+	} else if *dryRun {
 		fmt.Printf("\nDRY RUN. NO CHANGES WERE MADE.\n")
 	}
 